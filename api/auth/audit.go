@@ -0,0 +1,67 @@
+package auth
+
+import (
+	"bytes"
+	"github.com/ditsuke/youtube-focus/store"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/rs/zerolog"
+	"io"
+	"net/http"
+)
+
+// auditDetailMax is the maximum size of a request payload recorded in the audit log.
+const auditDetailMax = 4 << 10
+
+// Auditor provides middleware to record changes made through the API in the audit log.
+type Auditor struct {
+	Logger zerolog.Logger
+	Store  store.AuditStore
+}
+
+// Audit is middleware that records successful requests with unsafe methods (ie: changes) to
+// the audit log, along with the client that made them.
+func (a *Auditor) Audit(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		var detail []byte
+		if r.Body != nil {
+			detail, _ = io.ReadAll(io.LimitReader(r.Body, auditDetailMax))
+			r.Body = io.NopCloser(io.MultiReader(bytes.NewReader(detail), r.Body))
+		}
+
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+		next.ServeHTTP(ww, r)
+		status := ww.Status()
+		if status == 0 {
+			status = http.StatusOK
+		}
+		if status >= http.StatusBadRequest {
+			return
+		}
+
+		entry := &store.AuditEntry{
+			Actor:  "anonymous",
+			Action: r.Method + " " + chi.RouteContext(r.Context()).RoutePattern(),
+			Target: r.URL.Path,
+			Detail: string(detail),
+			Status: status,
+		}
+		if key, ok := Client(r.Context()); ok {
+			entry.Actor = key.Name
+			if key.ID != 0 {
+				entry.ActorKeyID = &key.ID
+				entry.Actor += " (" + key.Prefix + "...)"
+			}
+		}
+
+		if err := a.Store.Record(entry); err != nil {
+			a.Logger.Error().Err(err).Str("action", entry.Action).Msg("audit log")
+		}
+	})
+}
@@ -0,0 +1,157 @@
+// Package auth provides middleware to authenticate, authorize, rate-limit and audit clients of
+// the REST API. Checks of API keys are shared with the gRPC API.
+package auth
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"github.com/ditsuke/youtube-focus/api/response"
+	"github.com/ditsuke/youtube-focus/store"
+	"github.com/go-chi/render"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+const (
+	// HeaderAPIKey is the header clients pass their API key in. Alternatively, keys can be
+	// passed as bearer tokens in the Authorization header.
+	HeaderAPIKey = "X-API-Key"
+
+	HeaderRateLimitLimit     = "X-RateLimit-Limit"
+	HeaderRateLimitRemaining = "X-RateLimit-Remaining"
+	HeaderRateLimitReset     = "X-RateLimit-Reset"
+)
+
+// adminTokenClient stands in for the API key of clients authenticated with the admin token.
+var adminTokenClient = store.APIKey{Name: "admin token", Role: store.RoleAdmin}
+
+type clientCtxKey struct{}
+
+// Authenticator provides middleware to authenticate requests by API key, rate-limiting each
+// key.
+type Authenticator struct {
+	Keys    store.APIKeyStore
+	Limiter *Limiter
+	// RateLimit is the number of requests per minute allowed for keys without a RateLimit.
+	RateLimit int
+	// AdminToken authenticates administrators without an API key, eg: to issue the first
+	// admin key. It is not rate-limited, and is disabled if empty.
+	AdminToken string
+}
+
+// Client returns the API key a request was authenticated with, if any.
+func Client(ctx context.Context) (store.APIKey, bool) {
+	key, ok := ctx.Value(clientCtxKey{}).(store.APIKey)
+	return key, ok
+}
+
+// Errors authenticating clients.
+var (
+	ErrMissingKey  = errors.New("missing api key")
+	ErrInvalidKey  = errors.New("invalid api key")
+	ErrRateLimited = errors.New("rate limit exceeded")
+)
+
+// Check authenticates a client by the secret it passed, taking a request from its key's rate
+// limit. The returned Quota is zero-valued for clients that are not rate-limited.
+func (a *Authenticator) Check(secret string) (store.APIKey, Quota, error) {
+	if secret == "" {
+		return store.APIKey{}, Quota{}, ErrMissingKey
+	}
+	if a.isAdminToken(secret) {
+		return adminTokenClient, Quota{}, nil
+	}
+
+	key, err := a.Keys.Authenticate(secret)
+	if errors.Is(err, store.ErrNotFound) {
+		return key, Quota{}, ErrInvalidKey
+	}
+	if err != nil {
+		return key, Quota{}, err
+	}
+
+	limit := key.RateLimit
+	if limit <= 0 {
+		limit = a.RateLimit
+	}
+	quota, ok := a.Limiter.Allow(key.ID, limit)
+	if !ok {
+		return key, quota, ErrRateLimited
+	}
+	return key, quota, nil
+}
+
+// WithClient returns a copy of ctx carrying the API key a client was authenticated with.
+func WithClient(ctx context.Context, key store.APIKey) context.Context {
+	return context.WithValue(ctx, clientCtxKey{}, key)
+}
+
+// Authenticate is middleware that only lets through requests with a valid API key, within the
+// key's rate limit. Rate limit headers are set on every response to a key.
+func (a *Authenticator) Authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key, quota, err := a.Check(secretFromRequest(r))
+		if quota.Limit > 0 {
+			w.Header().Set(HeaderRateLimitLimit, strconv.Itoa(quota.Limit))
+			w.Header().Set(HeaderRateLimitRemaining, strconv.Itoa(quota.Remaining))
+			w.Header().Set(HeaderRateLimitReset, strconv.FormatInt(quota.Reset.Unix(), 10))
+		}
+
+		switch {
+		case errors.Is(err, ErrMissingKey), errors.Is(err, ErrInvalidKey):
+			_ = render.Render(w, r, response.ErrUnauthorized(err))
+			return
+		case errors.Is(err, ErrRateLimited):
+			retryAfter := int(quota.RetryAfter.Seconds()) + 1
+			w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+			_ = render.Render(w, r, response.ErrTooManyRequests(
+				fmt.Errorf("rate limit of %d requests per minute exceeded", quota.Limit)))
+			return
+		case err != nil:
+			_ = render.Render(w, r, response.ErrStore(err))
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(WithClient(r.Context(), key)))
+	})
+}
+
+// RequireRole returns middleware that only lets through clients with (at least) the passed
+// role. It must be used after Authenticate.
+func RequireRole(role store.Role) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key, ok := Client(r.Context())
+			if !ok || !key.Role.Includes(role) {
+				_ = render.Render(w, r, response.ErrForbidden(
+					fmt.Errorf("requires the %s role", role)))
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func (a *Authenticator) isAdminToken(secret string) bool {
+	return a.AdminToken != "" &&
+		subtle.ConstantTimeCompare([]byte(secret), []byte(a.AdminToken)) == 1
+}
+
+// secretFromRequest returns the API key or token passed with a request, if any.
+func secretFromRequest(r *http.Request) string {
+	if key := r.Header.Get(HeaderAPIKey); key != "" {
+		return key
+	}
+
+	const bearer = "Bearer "
+	authz := r.Header.Get("Authorization")
+	if len(authz) > len(bearer) && strings.EqualFold(authz[:len(bearer)], bearer) {
+		return authz[len(bearer):]
+	}
+
+	return ""
+}
@@ -0,0 +1,143 @@
+package auth
+
+import (
+	"github.com/ditsuke/youtube-focus/config"
+	"github.com/ditsuke/youtube-focus/store"
+	"github.com/ditsuke/youtube-focus/store/migrations"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+const testAdminToken = "test-admin-token"
+
+// newTestAuthenticator returns an Authenticator with a key allowing 2 requests per minute,
+// and the key's secret.
+func newTestAuthenticator(t *testing.T) (*Authenticator, *fakeClock, string) {
+	t.Helper()
+	db, err := config.Config{DBDriver: config.DriverSQLite, SQLitePath: ":memory:"}.GetDB()
+	if err != nil {
+		t.Fatalf("open the database: %v", err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatalf("open the database: %v", err)
+	}
+	// The in-memory database is dropped with its last connection, once the test is over
+	t.Cleanup(func() { _ = sqlDB.Close() })
+	if _, err := migrations.Up(db); err != nil {
+		t.Fatalf("migrate the database: %v", err)
+	}
+
+	keys := store.APIKeyStore{DB: db}
+	secret, err := keys.Issue(&store.APIKey{Name: "reader", Role: store.RoleReader, RateLimit: 2})
+	if err != nil {
+		t.Fatalf("Issue: %v", err)
+	}
+	limiter, clock := newTestLimiter()
+	a := &Authenticator{Keys: keys, Limiter: limiter, RateLimit: 60, AdminToken: testAdminToken}
+	return a, clock, secret
+}
+
+func TestAuthenticate(t *testing.T) {
+	a, clock, secret := newTestAuthenticator(t)
+	h := a.Authenticate(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key, _ := Client(r.Context())
+		_, _ = w.Write([]byte(key.Name))
+	}))
+	serve := func(header, value string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		if header != "" {
+			r.Header.Set(header, value)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, r)
+		return rec
+	}
+
+	for _, tc := range []struct {
+		name, header, value string
+	}{
+		{"no key", "", ""},
+		{"bad key", HeaderAPIKey, "yf_bad"},
+		{"bad bearer token", "Authorization", "Bearer yf_bad"},
+	} {
+		rec := serve(tc.header, tc.value)
+		if rec.Code != http.StatusUnauthorized || rec.Header().Get(HeaderRateLimitLimit) != "" {
+			t.Errorf("request with %s = %d, rate limit %q, want %d and no rate limit",
+				tc.name, rec.Code, rec.Header().Get(HeaderRateLimitLimit),
+				http.StatusUnauthorized)
+		}
+	}
+
+	// The key's own limit of 2 applies, rather than the default
+	reset := strconv.FormatInt(clock.t.Add(30*time.Second).Unix(), 10)
+	for i, header := range []string{HeaderAPIKey, "Authorization"} {
+		value := secret
+		if header == "Authorization" {
+			value = "Bearer " + secret
+		}
+		rec := serve(header, value)
+		remaining := strconv.Itoa(1 - i)
+		if rec.Code != http.StatusOK || rec.Body.String() != "reader" ||
+			rec.Header().Get(HeaderRateLimitLimit) != "2" ||
+			rec.Header().Get(HeaderRateLimitRemaining) != remaining ||
+			rec.Header().Get(HeaderRateLimitReset) == "" {
+			t.Errorf("request %d with the key in %s = %d %q, headers %v, want %d as reader, "+
+				"with %s remaining", i+1, header, rec.Code, rec.Body.String(), rec.Header(),
+				http.StatusOK, remaining)
+		}
+		if i == 0 && rec.Header().Get(HeaderRateLimitReset) != reset {
+			t.Errorf("%s = %s, want %s", HeaderRateLimitReset,
+				rec.Header().Get(HeaderRateLimitReset), reset)
+		}
+	}
+
+	rec := serve(HeaderAPIKey, secret)
+	if rec.Code != http.StatusTooManyRequests || rec.Header().Get("Retry-After") != "31" ||
+		rec.Header().Get(HeaderRateLimitRemaining) != "0" {
+		t.Errorf("request past the limit = %d, headers %v, want %d, retrying after 31s",
+			rec.Code, rec.Header(), http.StatusTooManyRequests)
+	}
+
+	clock.advance(30 * time.Second)
+	if rec := serve(HeaderAPIKey, secret); rec.Code != http.StatusOK {
+		t.Errorf("request once refilled = %d, want %d", rec.Code, http.StatusOK)
+	}
+
+	// The admin token is not rate-limited
+	for i := 0; i < 3; i++ {
+		rec := serve(HeaderAPIKey, testAdminToken)
+		if rec.Code != http.StatusOK || rec.Body.String() != adminTokenClient.Name ||
+			rec.Header().Get(HeaderRateLimitLimit) != "" {
+			t.Errorf("request with the admin token = %d %q, headers %v, want %d as %s",
+				rec.Code, rec.Body.String(), rec.Header(), http.StatusOK, adminTokenClient.Name)
+		}
+	}
+}
+
+func TestRequireRole(t *testing.T) {
+	noop := http.HandlerFunc(func(http.ResponseWriter, *http.Request) {})
+	h := RequireRole(store.RoleEditor)(noop)
+	for _, tc := range []struct {
+		key  *store.APIKey
+		want int
+	}{
+		{nil, http.StatusForbidden},
+		{&store.APIKey{Role: store.RoleReader}, http.StatusForbidden},
+		{&store.APIKey{Role: store.RoleEditor}, http.StatusOK},
+		{&store.APIKey{Role: store.RoleAdmin}, http.StatusOK},
+	} {
+		r := httptest.NewRequest(http.MethodPost, "/", nil)
+		if tc.key != nil {
+			r = r.WithContext(WithClient(r.Context(), *tc.key))
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, r)
+		if rec.Code != tc.want {
+			t.Errorf("RequireRole(editor) with %+v = %d, want %d", tc.key, rec.Code, tc.want)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package auth
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// Limiter rate-limits clients with a token bucket each. Buckets hold up to a minute's worth of
+// requests, and refill continuously.
+type Limiter struct {
+	mu      sync.Mutex
+	buckets map[uint]*bucket
+	// now is the clock buckets are refilled by.
+	now func() time.Time
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// Quota is the state of a client's rate limit after a request.
+type Quota struct {
+	// Limit is the number of requests allowed per minute.
+	Limit int
+	// Remaining is the number of requests that can be made right away.
+	Remaining int
+	// Reset is the time at which the full Limit is available again.
+	Reset time.Time
+	// RetryAfter is the time to wait for the next request to be allowed, zero if it is.
+	RetryAfter time.Duration
+}
+
+func NewLimiter() *Limiter {
+	return &Limiter{buckets: map[uint]*bucket{}, now: time.Now}
+}
+
+// Allow takes a token from the bucket of the client with the passed ID, allowing up to
+// perMinute requests per minute. The returned bool reports whether the request is allowed.
+func (l *Limiter) Allow(client uint, perMinute int) (Quota, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	capacity := float64(perMinute)
+	rate := capacity / time.Minute.Seconds() // tokens per second
+
+	b, ok := l.buckets[client]
+	if !ok {
+		b = &bucket{tokens: capacity, last: now}
+		l.buckets[client] = b
+	}
+	b.tokens = math.Min(capacity, b.tokens+now.Sub(b.last).Seconds()*rate)
+	b.last = now
+
+	allowed := b.tokens >= 1
+	if allowed {
+		b.tokens--
+	}
+
+	q := Quota{
+		Limit:     perMinute,
+		Remaining: int(b.tokens),
+		Reset:     now.Add(seconds((capacity - b.tokens) / rate)),
+	}
+	if !allowed {
+		q.RetryAfter = seconds((1 - b.tokens) / rate)
+	}
+
+	return q, allowed
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
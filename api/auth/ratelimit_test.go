@@ -0,0 +1,79 @@
+package auth
+
+import (
+	"testing"
+	"time"
+)
+
+// fakeClock is a clock that only moves when told to.
+type fakeClock struct {
+	t time.Time
+}
+
+func (c *fakeClock) now() time.Time { return c.t }
+
+func (c *fakeClock) advance(d time.Duration) { c.t = c.t.Add(d) }
+
+func newTestLimiter() (*Limiter, *fakeClock) {
+	clock := &fakeClock{t: time.Date(2022, time.September, 1, 12, 0, 0, 0, time.UTC)}
+	l := NewLimiter()
+	l.now = clock.now
+	return l, clock
+}
+
+func TestLimiterDrainsBucket(t *testing.T) {
+	l, clock := newTestLimiter()
+
+	for want := 2; want >= 0; want-- {
+		q, ok := l.Allow(1, 3)
+		if !ok || q.Limit != 3 || q.Remaining != want || q.RetryAfter != 0 {
+			t.Fatalf("Allow = %+v, %v, want allowed with %d remaining", q, ok, want)
+		}
+	}
+	// Each of the 3 requests takes 20s to refill
+	q, ok := l.Allow(1, 3)
+	if ok || q.Remaining != 0 || q.RetryAfter != 20*time.Second ||
+		!q.Reset.Equal(clock.t.Add(time.Minute)) {
+		t.Errorf("Allow on an empty bucket = %+v, %v, want denied, retrying after 20s, and "+
+			"reset in a minute", q, ok)
+	}
+}
+
+func TestLimiterRefills(t *testing.T) {
+	l, clock := newTestLimiter()
+	for i := 0; i < 3; i++ {
+		l.Allow(1, 3)
+	}
+
+	clock.advance(10 * time.Second)
+	if q, ok := l.Allow(1, 3); ok || q.RetryAfter != 10*time.Second {
+		t.Errorf("Allow 10s into a refill = %+v, %v, want denied, retrying after 10s", q, ok)
+	}
+
+	clock.advance(10 * time.Second)
+	q, ok := l.Allow(1, 3)
+	if !ok || q.Remaining != 0 || !q.Reset.Equal(clock.t.Add(time.Minute)) {
+		t.Errorf("Allow 20s into a refill = %+v, %v, want allowed, with none remaining", q, ok)
+	}
+
+	// Buckets hold no more than a minute's worth of requests
+	clock.advance(time.Hour)
+	q, ok = l.Allow(1, 3)
+	if !ok || q.Remaining != 2 || !q.Reset.Equal(clock.t.Add(20*time.Second)) {
+		t.Errorf("Allow after an hour = %+v, %v, want allowed with 2 remaining, reset in 20s",
+			q, ok)
+	}
+}
+
+func TestLimiterBucketPerClient(t *testing.T) {
+	l, _ := newTestLimiter()
+	if _, ok := l.Allow(1, 1); !ok {
+		t.Fatalf("first request of client 1 denied")
+	}
+	if _, ok := l.Allow(1, 1); ok {
+		t.Errorf("second request of client 1 allowed past its limit")
+	}
+	if _, ok := l.Allow(2, 1); !ok {
+		t.Errorf("first request of client 2 denied for the requests of client 1")
+	}
+}
@@ -0,0 +1,207 @@
+// Package cache provides an in-process cache of API responses, served with ETags and
+// Cache-Control headers so that clients can make conditional requests.
+package cache
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Cache holds responses to GET requests, keyed on their normalized URL. It must be purged
+// whenever the data behind the responses changes.
+type Cache struct {
+	// MaxAge is the duration clients may reuse a response for without revalidating it.
+	MaxAge time.Duration
+	// TTL is the duration responses are cached for, should no purge evict them sooner. Zero
+	// or less keeps them until purged.
+	TTL time.Duration
+
+	responses *lru[*response]
+	// now is the clock responses expire by.
+	now func() time.Time
+}
+
+type response struct {
+	status  int
+	header  http.Header
+	body    []byte
+	etag    string
+	expires time.Time
+}
+
+// New returns a Cache holding up to size responses, for ttl each. A size of zero or less
+// disables caching, though responses are still served with ETags.
+func New(size int, maxAge, ttl time.Duration) *Cache {
+	c := &Cache{MaxAge: maxAge, TTL: ttl, now: time.Now}
+	if size > 0 {
+		c.responses = newLRU[*response](size)
+	}
+	return c
+}
+
+// Enabled reports whether responses are cached, rather than only served with ETags. A nil
+// Cache caches none.
+func (c *Cache) Enabled() bool {
+	return c != nil && c.responses != nil
+}
+
+// Purge all cached responses.
+func (c *Cache) Purge() {
+	if c.responses != nil {
+		c.responses.purge()
+	}
+}
+
+// Middleware serves GET requests from the cache, caching successful responses on misses.
+// Responses carry an ETag, and requests with a matching If-None-Match header are answered
+// with 304 Not Modified.
+func (c *Cache) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		k := key(r)
+		resp, ok := c.get(k)
+		if !ok {
+			// Responses computed across a purge may be stale already, and are not cached
+			gen := c.generation()
+			rec := &recorder{header: http.Header{}}
+			next.ServeHTTP(rec, r)
+			resp = rec.response()
+			if resp.status == http.StatusOK {
+				c.add(k, resp, gen)
+			}
+		}
+
+		c.serve(w, r, resp)
+	})
+}
+
+func (c *Cache) get(key string) (*response, bool) {
+	if c.responses == nil {
+		return nil, false
+	}
+	resp, ok := c.responses.get(key)
+	if ok && !resp.expires.IsZero() && !c.now().Before(resp.expires) {
+		return nil, false
+	}
+	return resp, ok
+}
+
+func (c *Cache) generation() uint64 {
+	if c.responses == nil {
+		return 0
+	}
+	return c.responses.generation()
+}
+
+func (c *Cache) add(key string, resp *response, gen uint64) {
+	if c.responses == nil {
+		return
+	}
+	if c.TTL > 0 {
+		resp.expires = c.now().Add(c.TTL)
+	}
+	c.responses.add(key, resp, gen)
+}
+
+func (c *Cache) serve(w http.ResponseWriter, r *http.Request, resp *response) {
+	for name, values := range resp.header {
+		w.Header()[name] = values
+	}
+	if resp.status != http.StatusOK {
+		w.WriteHeader(resp.status)
+		_, _ = w.Write(resp.body)
+		return
+	}
+
+	// Responses may depend on the client's credentials, eg: through rate limits
+	visibility := "public"
+	if r.Header.Get("Authorization") != "" || r.Header.Get("X-API-Key") != "" {
+		visibility = "private"
+	}
+	w.Header().Set("ETag", resp.etag)
+	w.Header().Set("Cache-Control",
+		visibility+", max-age="+strconv.Itoa(int(c.MaxAge.Seconds())))
+
+	if etagMatches(r.Header.Get("If-None-Match"), resp.etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	w.WriteHeader(resp.status)
+	_, _ = w.Write(resp.body)
+}
+
+// key normalizes the URL of a request, along with the representation it accepts, so that
+// equivalent requests share cached responses.
+func key(r *http.Request) string {
+	query := r.URL.Query()
+	normalized := make(url.Values, len(query))
+	for param, values := range query {
+		values = append([]string(nil), values...)
+		sort.Strings(values)
+		normalized[param] = values
+	}
+	// Encode sorts by parameter
+	return r.URL.Path + "?" + normalized.Encode() + "\n" + r.Header.Get("Accept")
+}
+
+// etagMatches reports whether an If-None-Match header matches an ETag.
+func etagMatches(ifNoneMatch, etag string) bool {
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == etag || candidate == "*" {
+			return true
+		}
+	}
+	return false
+}
+
+// recorder is an http.ResponseWriter buffering a response in memory.
+type recorder struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (rec *recorder) Header() http.Header {
+	return rec.header
+}
+
+func (rec *recorder) Write(b []byte) (int, error) {
+	if rec.status == 0 {
+		rec.status = http.StatusOK
+	}
+	return rec.body.Write(b)
+}
+
+func (rec *recorder) WriteHeader(status int) {
+	if rec.status == 0 {
+		rec.status = status
+	}
+}
+
+func (rec *recorder) response() *response {
+	status := rec.status
+	if status == 0 {
+		status = http.StatusOK
+	}
+
+	sum := sha256.Sum256(rec.body.Bytes())
+	return &response{
+		status: status,
+		header: rec.header,
+		body:   rec.body.Bytes(),
+		etag:   `"` + hex.EncodeToString(sum[:16]) + `"`,
+	}
+}
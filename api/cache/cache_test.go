@@ -0,0 +1,188 @@
+package cache
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// testHandler answers with the number of requests it served so far, which tells responses
+// served from the cache apart.
+type testHandler struct {
+	served int
+	status int
+	// onServe, if set, is called as each request is served.
+	onServe func()
+}
+
+func (h *testHandler) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	h.served++
+	if h.onServe != nil {
+		h.onServe()
+	}
+	if h.status != 0 {
+		w.WriteHeader(h.status)
+	}
+	_, _ = w.Write([]byte(strconv.Itoa(h.served)))
+}
+
+func serve(h http.Handler, method, target string, header http.Header) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, target, nil)
+	for name, values := range header {
+		r.Header[name] = values
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, r)
+	return rec
+}
+
+func TestCacheServesHits(t *testing.T) {
+	c := New(10, 5*time.Second, time.Minute)
+	next := &testHandler{}
+	h := c.Middleware(next)
+
+	first := serve(h, http.MethodGet, "/videos?b=2&a=1", nil)
+	if first.Code != http.StatusOK || first.Body.String() != "1" ||
+		first.Header().Get("Cache-Control") != "public, max-age=5" {
+		t.Fatalf("first GET = %d %q, headers %v", first.Code, first.Body.String(),
+			first.Header())
+	}
+
+	// Requests for the same parameters, in any order, are served from the cache
+	second := serve(h, http.MethodGet, "/videos?a=1&b=2", nil)
+	if second.Body.String() != "1" || second.Header().Get("ETag") != first.Header().Get("ETag") {
+		t.Errorf("second GET = %q, ETag %s, want the cached response", second.Body.String(),
+			second.Header().Get("ETag"))
+	}
+	if other := serve(h, http.MethodGet, "/videos?a=2", nil); other.Body.String() != "2" {
+		t.Errorf("GET of other parameters = %q, want a response of its own", other.Body.String())
+	}
+
+	// Only GETs are cached
+	if post := serve(h, http.MethodPost, "/videos?a=2", nil); post.Body.String() != "3" ||
+		post.Header().Get("ETag") != "" {
+		t.Errorf("POST = %q, headers %v, want it passed through", post.Body.String(),
+			post.Header())
+	}
+
+	// Responses to clients with a key are private to them
+	keyed := serve(h, http.MethodGet, "/videos?a=1&b=2", http.Header{"X-Api-Key": {"key"}})
+	if keyed.Header().Get("Cache-Control") != "private, max-age=5" {
+		t.Errorf("Cache-Control with an API key = %q, want it private",
+			keyed.Header().Get("Cache-Control"))
+	}
+}
+
+func TestCacheETag(t *testing.T) {
+	for _, size := range []int{10, 0} {
+		h := New(size, 5*time.Second, time.Minute).Middleware(&testHandler{})
+		etag := serve(h, http.MethodGet, "/videos", nil).Header().Get("ETag")
+		if etag == "" {
+			t.Fatalf("response of a cache of size %d has no ETag", size)
+		}
+
+		for _, ifNoneMatch := range []string{etag, "W/" + etag, `"other", ` + etag, "*"} {
+			if size == 0 && ifNoneMatch != "*" {
+				// An uncached response is computed anew, with the ETag of its new body
+				continue
+			}
+			rec := serve(h, http.MethodGet, "/videos", http.Header{"If-None-Match": {ifNoneMatch}})
+			if rec.Code != http.StatusNotModified || rec.Body.Len() != 0 {
+				t.Errorf("GET with If-None-Match %s = %d %q, want %d", ifNoneMatch, rec.Code,
+					rec.Body.String(), http.StatusNotModified)
+			}
+		}
+		rec := serve(h, http.MethodGet, "/videos", http.Header{"If-None-Match": {`"other"`}})
+		if rec.Code != http.StatusOK {
+			t.Errorf("GET with a stale If-None-Match = %d, want %d", rec.Code, http.StatusOK)
+		}
+	}
+}
+
+func TestCacheExpires(t *testing.T) {
+	now := time.Date(2022, time.September, 1, 12, 0, 0, 0, time.UTC)
+	c := New(10, 5*time.Second, time.Minute)
+	c.now = func() time.Time { return now }
+	h := c.Middleware(&testHandler{})
+
+	serve(h, http.MethodGet, "/videos", nil)
+	now = now.Add(time.Minute - time.Second)
+	if rec := serve(h, http.MethodGet, "/videos", nil); rec.Body.String() != "1" {
+		t.Errorf("GET within the TTL = %q, want the cached response", rec.Body.String())
+	}
+	now = now.Add(time.Second)
+	if rec := serve(h, http.MethodGet, "/videos", nil); rec.Body.String() != "2" {
+		t.Errorf("GET past the TTL = %q, want a new response", rec.Body.String())
+	}
+
+	// Without a TTL, responses are kept until purged
+	c = New(10, 5*time.Second, 0)
+	c.now = func() time.Time { return now }
+	h = c.Middleware(&testHandler{})
+	serve(h, http.MethodGet, "/videos", nil)
+	now = now.Add(24 * time.Hour)
+	if rec := serve(h, http.MethodGet, "/videos", nil); rec.Body.String() != "1" {
+		t.Errorf("GET a day later without a TTL = %q, want the cached response",
+			rec.Body.String())
+	}
+	c.Purge()
+	if rec := serve(h, http.MethodGet, "/videos", nil); rec.Body.String() != "2" {
+		t.Errorf("GET after a purge = %q, want a new response", rec.Body.String())
+	}
+}
+
+func TestCacheEvicts(t *testing.T) {
+	h := New(2, 5*time.Second, time.Minute).Middleware(&testHandler{})
+	serve(h, http.MethodGet, "/videos/a", nil)
+	serve(h, http.MethodGet, "/videos/b", nil)
+	serve(h, http.MethodGet, "/videos/a", nil)
+	serve(h, http.MethodGet, "/videos/c", nil)
+
+	// b was the least recently used when c was cached
+	for _, tc := range []struct{ target, want string }{
+		{"/videos/a", "1"}, {"/videos/c", "3"}, {"/videos/b", "4"},
+	} {
+		if rec := serve(h, http.MethodGet, tc.target, nil); rec.Body.String() != tc.want {
+			t.Errorf("GET %s = %q, want %q", tc.target, rec.Body.String(), tc.want)
+		}
+	}
+}
+
+func TestCacheSkipsResponsesAcrossPurge(t *testing.T) {
+	c := New(10, 5*time.Second, time.Minute)
+	next := &testHandler{}
+	// New videos come in while the first response is computed
+	next.onServe = func() {
+		if next.served == 1 {
+			c.Purge()
+		}
+	}
+	h := c.Middleware(next)
+
+	if rec := serve(h, http.MethodGet, "/videos", nil); rec.Body.String() != "1" {
+		t.Fatalf("first GET = %q, want 1", rec.Body.String())
+	}
+	if rec := serve(h, http.MethodGet, "/videos", nil); rec.Body.String() != "2" {
+		t.Errorf("GET after a response computed across a purge = %q, want a new response",
+			rec.Body.String())
+	}
+	if rec := serve(h, http.MethodGet, "/videos", nil); rec.Body.String() != "2" {
+		t.Errorf("GET after a response computed with no purge = %q, want the cached one",
+			rec.Body.String())
+	}
+}
+
+func TestCacheSkipsErrors(t *testing.T) {
+	next := &testHandler{status: http.StatusInternalServerError}
+	h := New(10, 5*time.Second, time.Minute).Middleware(next)
+
+	serve(h, http.MethodGet, "/videos", nil)
+	rec := serve(h, http.MethodGet, "/videos", nil)
+	if rec.Code != http.StatusInternalServerError || rec.Body.String() != "2" ||
+		rec.Header().Get("ETag") != "" {
+		t.Errorf("second GET of an error = %d %q, want the error computed anew, without an ETag",
+			rec.Code, rec.Body.String())
+	}
+}
@@ -0,0 +1,81 @@
+package cache
+
+import (
+	"container/list"
+	"sync"
+)
+
+// lru is a size-bounded map, evicting its least recently used entries first.
+// It is safe for concurrent use.
+type lru[V any] struct {
+	mu    sync.Mutex
+	size  int
+	order *list.List // front is the most recently used
+	items map[string]*list.Element
+	// gen counts purges, so that values computed before one are not added after it
+	gen uint64
+}
+
+type lruEntry[V any] struct {
+	key   string
+	value V
+}
+
+func newLRU[V any](size int) *lru[V] {
+	return &lru[V]{
+		size:  size,
+		order: list.New(),
+		items: make(map[string]*list.Element, size),
+	}
+}
+
+func (l *lru[V]) get(key string) (V, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	el, ok := l.items[key]
+	if !ok {
+		var zero V
+		return zero, false
+	}
+	l.order.MoveToFront(el)
+	return el.Value.(*lruEntry[V]).value, true
+}
+
+// generation returns the number of purges so far.
+func (l *lru[V]) generation() uint64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.gen
+}
+
+// add adds a value, unless the lru was purged since generation gen.
+func (l *lru[V]) add(key string, value V, gen uint64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if gen != l.gen {
+		return
+	}
+	if el, ok := l.items[key]; ok {
+		el.Value.(*lruEntry[V]).value = value
+		l.order.MoveToFront(el)
+		return
+	}
+
+	l.items[key] = l.order.PushFront(&lruEntry[V]{key: key, value: value})
+	if l.order.Len() > l.size {
+		oldest := l.order.Back()
+		l.order.Remove(oldest)
+		delete(l.items, oldest.Value.(*lruEntry[V]).key)
+	}
+}
+
+func (l *lru[V]) purge() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.order.Init()
+	l.items = make(map[string]*list.Element, l.size)
+	l.gen++
+}
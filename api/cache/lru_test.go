@@ -0,0 +1,61 @@
+package cache
+
+import (
+	"testing"
+)
+
+func TestLRUEvictsLeastRecentlyUsed(t *testing.T) {
+	l := newLRU[int](2)
+	l.add("a", 1, 0)
+	l.add("b", 2, 0)
+	// Using a makes b the least recently used
+	if v, ok := l.get("a"); !ok || v != 1 {
+		t.Fatalf("get(a) = %d, %v, want 1", v, ok)
+	}
+	l.add("c", 3, 0)
+
+	if _, ok := l.get("b"); ok {
+		t.Errorf("b was kept past the capacity, rather than evicted")
+	}
+	for key, want := range map[string]int{"a": 1, "c": 3} {
+		if v, ok := l.get(key); !ok || v != want {
+			t.Errorf("get(%s) = %d, %v, want %d", key, v, ok, want)
+		}
+	}
+}
+
+func TestLRUReplaces(t *testing.T) {
+	l := newLRU[int](2)
+	l.add("a", 1, 0)
+	l.add("b", 2, 0)
+	// Replacing a takes no more room, and makes b the least recently used
+	l.add("a", 10, 0)
+	l.add("c", 3, 0)
+
+	if v, ok := l.get("a"); !ok || v != 10 {
+		t.Errorf("get(a) = %d, %v, want 10", v, ok)
+	}
+	if _, ok := l.get("b"); ok {
+		t.Errorf("b was kept past the capacity, rather than evicted")
+	}
+}
+
+func TestLRUPurge(t *testing.T) {
+	l := newLRU[int](2)
+	gen := l.generation()
+	l.add("a", 1, gen)
+	l.purge()
+
+	if _, ok := l.get("a"); ok {
+		t.Errorf("a was kept past a purge")
+	}
+	// Values computed before the purge are stale
+	l.add("b", 2, gen)
+	if _, ok := l.get("b"); ok {
+		t.Errorf("b was added after a purge, with the generation from before it")
+	}
+	l.add("b", 2, l.generation())
+	if v, ok := l.get("b"); !ok || v != 2 {
+		t.Errorf("get(b) = %d, %v, want 2", v, ok)
+	}
+}
@@ -0,0 +1,172 @@
+// Package graphql serves a GraphQL API over the video store, alongside the REST API.
+package graphql
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/ditsuke/youtube-focus/api/response"
+	"github.com/ditsuke/youtube-focus/internal/services"
+	"github.com/ditsuke/youtube-focus/internal/yt"
+	"github.com/ditsuke/youtube-focus/store"
+	"github.com/go-chi/render"
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/graphql-go/graphql/language/parser"
+	"github.com/rs/zerolog"
+	"net/http"
+)
+
+// Request is a GraphQL request, POSTed as JSON or passed as the query parameters of a GET.
+type Request struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName"`
+	Variables     map[string]interface{} `json:"variables"`
+}
+
+// Bind validates the request.
+func (req *Request) Bind(r *http.Request) error {
+	if req.Query == "" {
+		return fmt.Errorf("no `query`")
+	}
+	return nil
+}
+
+// Handler serves GraphQL requests: queries over GET or POST, and subscriptions as streams of
+// server-sent events. Requests over MaxDepth or MaxComplexity are rejected unresolved.
+type Handler struct {
+	logger zerolog.Logger
+	store  store.VideoStore
+	schema graphql.Schema
+}
+
+// New returns a Handler resolving requests with the store, and subscriptions to new videos
+// with newVideos.
+func New(logger zerolog.Logger, st store.VideoStore,
+	newVideos *services.Broadcaster[[]yt.Video],
+) (*Handler, error) {
+	schema, err := NewSchema(st, newVideos)
+	if err != nil {
+		return nil, err
+	}
+	return &Handler{logger: logger, store: st, schema: schema}, nil
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	req := &Request{}
+	if err := bindRequest(r, req); err != nil {
+		_ = render.Render(w, r, response.ErrInvalidRequest(err))
+		return
+	}
+
+	doc, op := operation(req)
+	if op != nil {
+		if err := checkLimits(doc, op, req.Variables); err != nil {
+			_ = render.Render(w, r, response.ErrInvalidRequest(err))
+			return
+		}
+	}
+
+	l := newLoaders(h.store, func(err error) {
+		h.logger.Error().Err(err).Msg("graphql store query")
+	})
+	params := graphql.Params{
+		Schema:         h.schema,
+		RequestString:  req.Query,
+		OperationName:  req.OperationName,
+		VariableValues: req.Variables,
+		Context:        withLoaders(r.Context(), l),
+	}
+
+	if op != nil && op.Operation == ast.OperationTypeSubscription {
+		h.stream(w, r, params)
+		return
+	}
+	render.JSON(w, r, graphql.Do(params))
+}
+
+// stream writes the results of a subscription as server-sent events, until either end hangs
+// up.
+func (h *Handler) stream(w http.ResponseWriter, r *http.Request, params graphql.Params) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		_ = render.Render(w, r, response.ErrInternal(fmt.Errorf("streaming unsupported")))
+		return
+	}
+
+	results := graphql.Subscribe(params)
+	// Results pending once the client is gone are discarded, freeing the subscription
+	defer func() {
+		go func() {
+			for range results {
+			}
+		}()
+	}()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	for {
+		select {
+		case result, ok := <-results:
+			if !ok {
+				_, _ = fmt.Fprint(w, "event: complete\ndata:\n\n")
+				flusher.Flush()
+				return
+			}
+			data, err := json.Marshal(result)
+			if err != nil {
+				h.logger.Error().Err(err).Msg("encode subscription result")
+				return
+			}
+			if _, err := fmt.Fprintf(w, "event: next\ndata: %s\n\n", data); err != nil {
+				return
+			}
+			flusher.Flush()
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+// bindRequest decodes a Request from the JSON body of a POST, or the query of a GET.
+func bindRequest(r *http.Request, req *Request) error {
+	if r.Method == http.MethodPost {
+		return render.Bind(r, req)
+	}
+
+	query := r.URL.Query()
+	req.Query = query.Get("query")
+	req.OperationName = query.Get("operationName")
+	if variables := query.Get("variables"); variables != "" {
+		if err := json.Unmarshal([]byte(variables), &req.Variables); err != nil {
+			return fmt.Errorf("invalid `variables`")
+		}
+	}
+	return req.Bind(r)
+}
+
+// operation parses a request, returning its document and the operation it runs. Requests
+// that do not parse, or name no operation of theirs, have none, and are left to fail as
+// queries.
+func operation(req *Request) (*ast.Document, *ast.OperationDefinition) {
+	doc, err := parser.Parse(parser.ParseParams{Source: req.Query})
+	if err != nil {
+		return nil, nil
+	}
+
+	var operations []*ast.OperationDefinition
+	for _, def := range doc.Definitions {
+		if op, ok := def.(*ast.OperationDefinition); ok {
+			operations = append(operations, op)
+		}
+	}
+	for _, op := range operations {
+		named := op.Name != nil && op.Name.Value == req.OperationName
+		if named || (req.OperationName == "" && len(operations) == 1) {
+			return doc, op
+		}
+	}
+	return doc, nil
+}
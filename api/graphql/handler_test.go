@@ -0,0 +1,151 @@
+package graphql
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/ditsuke/youtube-focus/internal/services"
+	"github.com/ditsuke/youtube-focus/internal/yt"
+	"github.com/rs/zerolog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTestServer serves a Handler over a store of n testVideos, with new videos published on
+// newVideos.
+func newTestServer(t *testing.T, n int, newVideos *services.Broadcaster[[]yt.Video],
+) *httptest.Server {
+	t.Helper()
+	h, err := New(zerolog.Nop(), newTestStore(t, n), newVideos)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	srv := httptest.NewServer(h)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// get GETs a query from srv.
+func get(t *testing.T, ctx context.Context, srv *httptest.Server, q string) *http.Response {
+	t.Helper()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet,
+		srv.URL+"?query="+url.QueryEscape(q), nil)
+	if err != nil {
+		t.Fatalf("NewRequest: %v", err)
+	}
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("GET %s: %v", q, err)
+	}
+	return res
+}
+
+func TestSubscription(t *testing.T) {
+	newVideos := services.NewBroadcaster[[]yt.Video]()
+	srv := newTestServer(t, 0, newVideos)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	res := get(t, ctx, srv, `subscription { newVideos { id title } }`)
+	defer res.Body.Close()
+	if got := res.Header.Get("Content-Type"); got != "text/event-stream" {
+		t.Fatalf("Content-Type = %q, want text/event-stream", got)
+	}
+
+	// The subscription is made some time after the response starts, so videos are published
+	// until one comes through
+	published := testVideos(2)
+	go func() {
+		ticker := time.NewTicker(10 * time.Millisecond)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				newVideos.Publish(published)
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	var got []string
+	lines := bufio.NewScanner(res.Body)
+	for len(got) < 2 && lines.Scan() {
+		if !strings.HasPrefix(lines.Text(), "data: ") {
+			continue
+		}
+		data := strings.TrimPrefix(lines.Text(), "data: ")
+		var result struct {
+			Data struct {
+				NewVideos struct {
+					ID    string `json:"id"`
+					Title string `json:"title"`
+				} `json:"newVideos"`
+			} `json:"data"`
+			Errors []interface{} `json:"errors"`
+		}
+		if err := json.Unmarshal([]byte(data), &result); err != nil || len(result.Errors) > 0 {
+			t.Fatalf("event data %s: %v", data, err)
+		}
+		got = append(got, result.Data.NewVideos.ID+" "+result.Data.NewVideos.Title)
+	}
+	want := []string{"video0 Cats, video 0", "video1 Dogs, video 1"}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("streamed %q, want %q (%v)", got, want, lines.Err())
+	}
+}
+
+func TestLimits(t *testing.T) {
+	srv := newTestServer(t, 3, nil)
+
+	// nest nests connections paging through first videos n deep, selecting fields at the end.
+	nest := func(n int, first string, fields string) string {
+		q := fields
+		for i := 0; i < n; i++ {
+			q = fmt.Sprintf("channel { videos(first: %s) { edges { node { %s } } } }", first, q)
+		}
+		return fmt.Sprintf("videos(first: %s) { edges { node { %s } } }", first, q)
+	}
+	// fields are 13 fields of a video: a page of 20 videos under another takes 5200.
+	fields := "id title description publishedAt firstSeenAt thumbnailUrl categoryId viewCount " +
+		"likeCount commentCount durationSeconds defaultLanguage removedAt"
+	for _, tt := range []struct {
+		name  string
+		query string
+		want  int
+	}{
+		{"shallow", "{" + nest(1, "20", "id title") + "}", http.StatusOK},
+		{"within limits", "{" + nest(1, "20", "id title description publishedAt") + "}",
+			http.StatusOK},
+		{"deep", "{" + nest(2, "1", "id") + "}", http.StatusBadRequest},
+		{"complex", "{" + nest(1, "20", fields) + "}", http.StatusBadRequest},
+		{"complex past the cap", "{" + nest(1, "1000", fields) + "}", http.StatusBadRequest},
+		{"complex with a variable unset",
+			"query($n: Int) {" + nest(1, "$n", fields) + "}", http.StatusBadRequest},
+		{"complex through fragments",
+			"{" + nest(1, "20", "...f") + "} fragment f on Video { " + fields + " }",
+			http.StatusBadRequest},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			res := get(t, context.Background(), srv, tt.query)
+			defer res.Body.Close()
+			var body struct {
+				Error  string        `json:"error"`
+				Errors []interface{} `json:"errors"`
+			}
+			_ = json.NewDecoder(res.Body).Decode(&body)
+			if res.StatusCode != tt.want || len(body.Errors) > 0 {
+				t.Errorf("status = %d, %s%v, want %d", res.StatusCode, body.Error, body.Errors,
+					tt.want)
+			}
+			if tt.want != http.StatusOK && !strings.Contains(body.Error, "the maximum is") {
+				t.Errorf("error = %q, want a limit exceeded", body.Error)
+			}
+		})
+	}
+}
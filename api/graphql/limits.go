@@ -0,0 +1,122 @@
+package graphql
+
+import (
+	"fmt"
+	"github.com/ditsuke/youtube-focus/api/handlers"
+	"github.com/graphql-go/graphql/language/ast"
+	"strconv"
+)
+
+// Limits on the cost of requests, as a single request nesting connections could otherwise
+// have the store load pages of videos for every video of a page, several times over.
+const (
+	// MaxDepth is the deepest fields a request may select, counting from the operation's.
+	MaxDepth = 10
+	// MaxComplexity is the most fields a request may resolve: each selected field counts once
+	// per video of the pages of the connections it is selected under.
+	MaxComplexity = 5000
+)
+
+// checkLimits fails if an operation of a document selects fields deeper than MaxDepth, or
+// resolves more fields than MaxComplexity. Connections count as paging through as many videos
+// as their first argument asks for, or the most they can when it is a variable left unset.
+func checkLimits(doc *ast.Document, op *ast.OperationDefinition,
+	variables map[string]interface{},
+) error {
+	fragments := map[string]*ast.FragmentDefinition{}
+	for _, def := range doc.Definitions {
+		if f, ok := def.(*ast.FragmentDefinition); ok && f.Name != nil {
+			fragments[f.Name.Value] = f
+		}
+	}
+
+	c := &costs{fragments: fragments, variables: variables, spread: map[string]bool{}}
+	c.add(op.SelectionSet, 1, 1, 1)
+	switch {
+	case c.depth > MaxDepth:
+		return fmt.Errorf("query is %d fields deep, the maximum is %d", c.depth, MaxDepth)
+	case c.complexity > MaxComplexity:
+		return fmt.Errorf("query resolves %d fields, the maximum is %d",
+			c.complexity, MaxComplexity)
+	}
+	return nil
+}
+
+// connections are the fields resolving to a VideoConnection, paging through videos.
+var connections = map[string]bool{"videos": true}
+
+// costs adds up the depth and complexity of a request.
+type costs struct {
+	fragments map[string]*ast.FragmentDefinition
+	variables map[string]interface{}
+	// spread are the fragments being spread, whose spreads within themselves are left out:
+	// they would fail validation anyway.
+	spread map[string]bool
+
+	depth      int
+	complexity int
+}
+
+// add adds the costs of the fields of a selection set at some depth, each resolved times
+// times, within a connection paging through page videos at a time, if any.
+func (c *costs) add(set *ast.SelectionSet, depth, times, page int) {
+	if set == nil || c.complexity > MaxComplexity {
+		return
+	}
+	for _, selection := range set.Selections {
+		switch s := selection.(type) {
+		case *ast.Field:
+			if depth > c.depth {
+				c.depth = depth
+			}
+			c.complexity += times
+			switch {
+			case connections[s.Name.Value]:
+				c.add(s.SelectionSet, depth+1, times, c.pageSize(s))
+			case s.Name.Value == "edges":
+				// The fields of edges are resolved for each video of the page
+				c.add(s.SelectionSet, depth+1, times*page, 1)
+			default:
+				c.add(s.SelectionSet, depth+1, times, 1)
+			}
+		case *ast.InlineFragment:
+			c.add(s.SelectionSet, depth, times, page)
+		case *ast.FragmentSpread:
+			f, ok := c.fragments[s.Name.Value]
+			if !ok || c.spread[s.Name.Value] {
+				continue
+			}
+			c.spread[s.Name.Value] = true
+			c.add(f.SelectionSet, depth, times, page)
+			delete(c.spread, s.Name.Value)
+		}
+	}
+}
+
+// pageSize returns the number of videos in the pages of a connection, as capped when
+// resolved.
+func (c *costs) pageSize(connection *ast.Field) int {
+	first := handlers.LimitDefault
+	for _, arg := range connection.Arguments {
+		if arg.Name.Value != "first" {
+			continue
+		}
+		switch v := arg.Value.(type) {
+		case *ast.IntValue:
+			first, _ = strconv.Atoi(v.Value)
+		case *ast.Variable:
+			first = handlers.LimitMax
+			if n, ok := c.variables[v.Name.Value].(float64); ok {
+				first = int(n)
+			}
+		}
+	}
+	if first > handlers.LimitMax {
+		return handlers.LimitMax
+	}
+	if first < 1 {
+		// The connection fails to resolve
+		return 1
+	}
+	return first
+}
@@ -0,0 +1,81 @@
+package graphql
+
+import (
+	"sync"
+)
+
+// loader batches the loads of values by key made while a query is resolved. Loads return
+// thunks, which graphql-go only calls once every field at their depth has been resolved; the
+// first thunk called fetches the values of every pending key at once, so that resolving a
+// list of objects costs one query rather than one per object.
+type loader[K comparable, V any] struct {
+	fetch func(keys []K) (map[K]V, error)
+
+	mu      sync.Mutex
+	pending []K
+	values  map[K]V
+	errs    map[K]error
+}
+
+func newLoader[K comparable, V any](fetch func(keys []K) (map[K]V, error)) *loader[K, V] {
+	return &loader[K, V]{
+		fetch:  fetch,
+		values: map[K]V{},
+		errs:   map[K]error{},
+	}
+}
+
+// load queues a key to be fetched, returning a thunk resolving to its value, or nil if there
+// is no value for the key.
+func (l *loader[K, V]) load(key K) func() (interface{}, error) {
+	l.mu.Lock()
+	if _, ok := l.values[key]; !ok {
+		l.pending = append(l.pending, key)
+	}
+	l.mu.Unlock()
+
+	return func() (interface{}, error) {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+
+		if len(l.pending) > 0 {
+			l.dispatch()
+		}
+		if err := l.errs[key]; err != nil {
+			return nil, err
+		}
+		if v, ok := l.values[key]; ok {
+			return v, nil
+		}
+		return nil, nil
+	}
+}
+
+// dispatch fetches the values of pending keys. It must be called with the lock held.
+func (l *loader[K, V]) dispatch() {
+	keys := dedupe(l.pending)
+	l.pending = nil
+
+	values, err := l.fetch(keys)
+	for _, key := range keys {
+		if err != nil {
+			l.errs[key] = err
+			continue
+		}
+		if v, ok := values[key]; ok {
+			l.values[key] = v
+		}
+	}
+}
+
+func dedupe[K comparable](keys []K) []K {
+	seen := make(map[K]bool, len(keys))
+	unique := keys[:0]
+	for _, key := range keys {
+		if !seen[key] {
+			seen[key] = true
+			unique = append(unique, key)
+		}
+	}
+	return unique
+}
@@ -0,0 +1,54 @@
+package graphql
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestLoaderBatches(t *testing.T) {
+	var fetched [][]string
+	l := newLoader(func(keys []string) (map[string]int, error) {
+		fetched = append(fetched, append([]string(nil), keys...))
+		return map[string]int{"a": 1, "b": 2}, nil
+	})
+
+	a, b, again, missing := l.load("a"), l.load("b"), l.load("a"), l.load("z")
+	if len(fetched) != 0 {
+		t.Fatalf("fetched %v before any thunk was called", fetched)
+	}
+	for _, tt := range []struct {
+		thunk func() (interface{}, error)
+		want  interface{}
+	}{{a, 1}, {b, 2}, {again, 1}, {missing, nil}} {
+		if got, err := tt.thunk(); err != nil || got != tt.want {
+			t.Errorf("thunk = %v, %v, want %v", got, err, tt.want)
+		}
+	}
+	if want := [][]string{{"a", "b", "z"}}; !reflect.DeepEqual(fetched, want) {
+		t.Errorf("fetched %v, want %v", fetched, want)
+	}
+
+	// Loaded values are not fetched again
+	if got, err := l.load("b")(); err != nil || got != 2 {
+		t.Errorf("thunk of a loaded key = %v, %v, want 2", got, err)
+	}
+	l.load("c")()
+	if want := [][]string{{"a", "b", "z"}, {"c"}}; !reflect.DeepEqual(fetched, want) {
+		t.Errorf("fetched %v, want %v", fetched, want)
+	}
+}
+
+func TestLoaderErrors(t *testing.T) {
+	failed := errors.New("failed")
+	l := newLoader(func(keys []string) (map[string]int, error) {
+		return nil, failed
+	})
+
+	a, b := l.load("a"), l.load("b")
+	for _, thunk := range []func() (interface{}, error){a, b} {
+		if got, err := thunk(); !errors.Is(err, failed) {
+			t.Errorf("thunk = %v, %v, want the error of the fetch", got, err)
+		}
+	}
+}
@@ -0,0 +1,593 @@
+package graphql
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"github.com/ditsuke/youtube-focus/api/handlers"
+	"github.com/ditsuke/youtube-focus/internal/services"
+	"github.com/ditsuke/youtube-focus/internal/yt"
+	"github.com/ditsuke/youtube-focus/store"
+	"github.com/graphql-go/graphql"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// subscriptionBuffer is the number of batches of new videos buffered for each subscriber.
+const subscriptionBuffer = 8
+
+// cursorPrefix marks the publish time encoded in connection cursors.
+const cursorPrefix = "published:"
+
+// filterParams maps the fields of the VideoFilter input to the query parameters they are
+// validated as, shared with the REST API.
+var filterParams = map[string]string{
+	"publishedAfter":  handlers.ParamAfter,
+	"publishedBefore": handlers.ParamBefore,
+	"channel":         handlers.ParamChannel,
+	"minDuration":     handlers.ParamMinDuration,
+	"maxDuration":     handlers.ParamMaxDuration,
+	"minViews":        handlers.ParamMinViews,
+	"maxViews":        handlers.ParamMaxViews,
+	"type":            handlers.ParamType,
+	"lang":            handlers.ParamLanguage,
+	"category":        handlers.ParamCategory,
+	"removed":         handlers.ParamRemoved,
+}
+
+// connection is a page of videos, in the style of Relay connections.
+type connection struct {
+	videos      []yt.Video
+	hasNextPage bool
+}
+
+// newConnection builds a connection from videos fetched with a limit of one more than the
+// page size, that extra video signalling a next page.
+func newConnection(videos []yt.Video, first int) connection {
+	if len(videos) > first {
+		return connection{videos: videos[:first], hasNextPage: true}
+	}
+	return connection{videos: videos}
+}
+
+// resolver resolves the fields of the schema with the store.
+type resolver struct {
+	store     store.VideoStore
+	newVideos *services.Broadcaster[[]yt.Video]
+}
+
+// NewSchema returns the GraphQL schema over videos in the store. Subscriptions to new videos
+// are fed by newVideos.
+func NewSchema(st store.VideoStore,
+	newVideos *services.Broadcaster[[]yt.Video],
+) (graphql.Schema, error) {
+	res := &resolver{store: st, newVideos: newVideos}
+
+	stats := graphql.NewObject(graphql.ObjectConfig{
+		Name:        "Stats",
+		Description: "Statistics of a video. Counts are Floats as they can exceed an Int.",
+		Fields: graphql.Fields{
+			"viewCount": videoField(graphql.Float, func(v yt.Video) any { return v.ViewCount }),
+			"likeCount": videoField(graphql.Float, func(v yt.Video) any { return v.LikeCount }),
+			"commentCount": videoField(graphql.Float,
+				func(v yt.Video) any { return v.CommentCount }),
+		},
+	})
+
+	statsRecord := graphql.NewObject(graphql.ObjectConfig{
+		Name:        "StatsRecord",
+		Description: "Statistics of a video as they were at some time.",
+		Fields: graphql.Fields{
+			"recordedAt": statsField(graphql.NewNonNull(graphql.DateTime),
+				func(s store.VideoStats) any { return s.RecordedAt }),
+			"viewCount": statsField(graphql.Float,
+				func(s store.VideoStats) any { return s.ViewCount }),
+			"likeCount": statsField(graphql.Float,
+				func(s store.VideoStats) any { return s.LikeCount }),
+			"commentCount": statsField(graphql.Float,
+				func(s store.VideoStats) any { return s.CommentCount }),
+		},
+	})
+
+	watch := graphql.NewObject(graphql.ObjectConfig{
+		Name:        "Watch",
+		Description: "A saved search.",
+		Fields: graphql.Fields{
+			"id": watchField(graphql.NewNonNull(graphql.ID),
+				func(s store.SavedSearch) any { return strconv.FormatUint(uint64(s.ID), 10) }),
+			"name":   watchField(graphql.String, func(s store.SavedSearch) any { return s.Name }),
+			"search": watchField(graphql.String, func(s store.SavedSearch) any { return s.Query }),
+			"mode": watchField(graphql.String,
+				func(s store.SavedSearch) any { return string(s.Mode) }),
+			"createdAt": watchField(graphql.DateTime,
+				func(s store.SavedSearch) any { return s.CreatedAt }),
+		},
+	})
+
+	pageInfo := graphql.NewObject(graphql.ObjectConfig{
+		Name: "PageInfo",
+		Fields: graphql.Fields{
+			"hasNextPage": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.Boolean),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return p.Source.(connection).hasNextPage, nil
+				},
+			},
+			"endCursor": &graphql.Field{
+				Type: graphql.String,
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					videos := p.Source.(connection).videos
+					if len(videos) == 0 {
+						return nil, nil
+					}
+					return encodeCursor(videos[len(videos)-1].PublishedAt), nil
+				},
+			},
+		},
+	})
+
+	// Videos and channels refer to each other, so their fields are declared after both
+	channel := graphql.NewObject(graphql.ObjectConfig{
+		Name:   "Channel",
+		Fields: graphql.Fields{},
+	})
+	video := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Video",
+		Fields: graphql.Fields{
+			"id": videoField(graphql.NewNonNull(graphql.ID),
+				func(v yt.Video) any { return v.VideoId }),
+			"title": videoField(graphql.String, func(v yt.Video) any { return v.Title }),
+			"description": videoField(graphql.String,
+				func(v yt.Video) any { return v.Description }),
+			"publishedAt": videoField(graphql.DateTime,
+				func(v yt.Video) any { return v.PublishedAt }),
+			"firstSeenAt": videoField(graphql.DateTime,
+				func(v yt.Video) any { return v.CreatedAt }),
+			"thumbnailUrl": videoField(graphql.String,
+				func(v yt.Video) any { return v.ThumbnailUrl }),
+			"liveBroadcastContent": videoField(graphql.String,
+				func(v yt.Video) any { return v.LiveBroadcastContent }),
+			"durationSeconds": videoField(graphql.Int,
+				func(v yt.Video) any { return v.DurationSeconds }),
+			"defaultLanguage": videoField(graphql.String,
+				func(v yt.Video) any { return v.DefaultLanguage }),
+			"categoryId": videoField(graphql.String, func(v yt.Video) any { return v.CategoryId }),
+			"removedAt": videoField(graphql.DateTime, func(v yt.Video) any {
+				if !v.DeletedAt.Valid {
+					return nil
+				}
+				return v.DeletedAt.Time
+			}),
+			"removalStatus": videoField(graphql.String, func(v yt.Video) any {
+				if v.RemovalStatus == "" {
+					return nil
+				}
+				return v.RemovalStatus
+			}),
+			"channel": videoField(graphql.NewNonNull(channel), func(v yt.Video) any {
+				return store.Channel{ID: v.ChannelId, Title: v.ChannelTitle}
+			}),
+			"stats": videoField(graphql.NewNonNull(stats), func(v yt.Video) any { return v }),
+			"statsHistory": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(statsRecord))),
+				Description: "Statistics as first stored, and every change since, oldest first.",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					history := loadersFrom(p.Context).statsHistory.load(p.Source.(yt.Video).VideoId)
+					return orEmpty[store.VideoStats](history), nil
+				},
+			},
+			"watches": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(watch))),
+				Description: "The saved searches matching the video, whenever it was first seen.",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					watches := loadersFrom(p.Context).watches.load(p.Source.(yt.Video).ID)
+					return orEmpty[store.SavedSearch](watches), nil
+				},
+			},
+		},
+	})
+
+	edge := graphql.NewObject(graphql.ObjectConfig{
+		Name: "VideoEdge",
+		Fields: graphql.Fields{
+			"cursor": videoField(graphql.NewNonNull(graphql.String),
+				func(v yt.Video) any { return encodeCursor(v.PublishedAt) }),
+			"node": videoField(graphql.NewNonNull(video), func(v yt.Video) any { return v }),
+		},
+	})
+	videoConnection := graphql.NewObject(graphql.ObjectConfig{
+		Name: "VideoConnection",
+		Fields: graphql.Fields{
+			"edges": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(edge))),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return p.Source.(connection).videos, nil
+				},
+			},
+			"pageInfo": &graphql.Field{
+				Type: graphql.NewNonNull(pageInfo),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return p.Source, nil
+				},
+			},
+		},
+	})
+
+	channel.AddFieldConfig("id", &graphql.Field{
+		Type: graphql.NewNonNull(graphql.ID),
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			return p.Source.(store.Channel).ID, nil
+		},
+	})
+	channel.AddFieldConfig("title", &graphql.Field{
+		Type: graphql.String,
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			return p.Source.(store.Channel).Title, nil
+		},
+	})
+	channel.AddFieldConfig("videos", &graphql.Field{
+		Type: graphql.NewNonNull(videoConnection),
+		Args: pageArgs(),
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			first, after, err := parsePageArgs(p.Context, p.Args)
+			if err != nil {
+				return nil, err
+			}
+			videos := loadersFrom(p.Context).channelVideos.load(channelVideosKey{
+				channelID: p.Source.(store.Channel).ID,
+				before:    after,
+				limit:     first + 1,
+			})
+			return func() (interface{}, error) {
+				v, err := videos()
+				if err != nil {
+					return nil, err
+				}
+				page, _ := v.([]yt.Video)
+				return newConnection(page, first), nil
+			}, nil
+		},
+	})
+
+	filter := graphql.NewInputObject(graphql.InputObjectConfig{
+		Name:        "VideoFilter",
+		Description: "Filters on videos, validated as the REST API's query parameters.",
+		Fields: graphql.InputObjectConfigFieldMap{
+			"publishedAfter":  {Type: graphql.DateTime},
+			"publishedBefore": {Type: graphql.DateTime},
+			"channel":         {Type: graphql.ID},
+			"minDuration":     {Type: graphql.Int, Description: "In seconds."},
+			"maxDuration":     {Type: graphql.Int, Description: "In seconds."},
+			"minViews":        {Type: graphql.Int},
+			"maxViews":        {Type: graphql.Int},
+			"type":            {Type: graphql.String, Description: "upload, live or upcoming."},
+			"lang":            {Type: graphql.String},
+			"category":        {Type: graphql.String},
+			"removed": {Type: graphql.String,
+				Description: "Videos removed from YouTube: exclude (the default), include or only."},
+		},
+	})
+
+	videosArgs := pageArgs()
+	videosArgs["search"] = &graphql.ArgumentConfig{Type: graphql.String}
+	videosArgs["fuzzy"] = &graphql.ArgumentConfig{Type: graphql.Boolean, DefaultValue: false}
+	videosArgs["filter"] = &graphql.ArgumentConfig{Type: filter}
+
+	query := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Query",
+		Fields: graphql.Fields{
+			"video": &graphql.Field{
+				Type: video,
+				Args: graphql.FieldConfigArgument{
+					"id": {Type: graphql.NewNonNull(graphql.ID)},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return loadersFrom(p.Context).videos.load(p.Args["id"].(string)), nil
+				},
+			},
+			"videos": &graphql.Field{
+				Type:    graphql.NewNonNull(videoConnection),
+				Args:    videosArgs,
+				Resolve: res.videos,
+			},
+			"channel": &graphql.Field{
+				Type: channel,
+				Args: graphql.FieldConfigArgument{
+					"id": {Type: graphql.NewNonNull(graphql.ID)},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return loadersFrom(p.Context).channels.load(p.Args["id"].(string)), nil
+				},
+			},
+		},
+	})
+
+	subscription := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Subscription",
+		Fields: graphql.Fields{
+			"newVideos": &graphql.Field{
+				Type:        graphql.NewNonNull(video),
+				Description: "Videos as they are first stored.",
+				Subscribe:   res.subscribeNewVideos,
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return p.Source, nil
+				},
+			},
+		},
+	})
+
+	return graphql.NewSchema(graphql.SchemaConfig{
+		Query:        query,
+		Subscription: subscription,
+	})
+}
+
+// videos resolves a page of videos, searched for or not.
+func (res *resolver) videos(p graphql.ResolveParams) (interface{}, error) {
+	first, after, err := parsePageArgs(p.Context, p.Args)
+	if err != nil {
+		return nil, err
+	}
+	filter, err := parseFilterArg(p.Args["filter"])
+	if err != nil {
+		return nil, err
+	}
+
+	st := res.store.WithFilter(filter)
+	search, _ := p.Args["search"].(string)
+	var videos []yt.Video
+	switch {
+	case search == "":
+		videos, err = st.Retrieve(after, first+1)
+	case p.Args["fuzzy"].(bool):
+		videos, err = st.FuzzySearch(search, after, first+1)
+	default:
+		videos, err = st.Search(search, after, first+1)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return newConnection(videos, first), nil
+}
+
+// subscribeNewVideos subscribes to videos as they are stored, one at a time, until the
+// subscription's context expires.
+func (res *resolver) subscribeNewVideos(p graphql.ResolveParams) (interface{}, error) {
+	if res.newVideos == nil {
+		return nil, fmt.Errorf("subscriptions are unavailable")
+	}
+
+	batches := res.newVideos.Subscribe(p.Context, subscriptionBuffer)
+	videos := make(chan interface{})
+	go func() {
+		defer close(videos)
+		for batch := range batches {
+			for _, v := range batch {
+				select {
+				case videos <- v:
+				case <-p.Context.Done():
+					return
+				}
+			}
+		}
+	}()
+
+	return videos, nil
+}
+
+// videoField is a field of a video, resolved with fn.
+func videoField(t graphql.Output, fn func(v yt.Video) any) *graphql.Field {
+	return &graphql.Field{
+		Type: t,
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			return fn(p.Source.(yt.Video)), nil
+		},
+	}
+}
+
+// statsField is a field of a record of statistics, resolved with fn.
+func statsField(t graphql.Output, fn func(s store.VideoStats) any) *graphql.Field {
+	return &graphql.Field{
+		Type: t,
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			return fn(p.Source.(store.VideoStats)), nil
+		},
+	}
+}
+
+// watchField is a field of a saved search, resolved with fn.
+func watchField(t graphql.Output, fn func(s store.SavedSearch) any) *graphql.Field {
+	return &graphql.Field{
+		Type: t,
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			return fn(p.Source.(store.SavedSearch)), nil
+		},
+	}
+}
+
+// orEmpty wraps the thunk of a loaded list, resolving to an empty list where there is none.
+func orEmpty[T any](thunk func() (interface{}, error)) func() (interface{}, error) {
+	return func() (interface{}, error) {
+		v, err := thunk()
+		if err != nil || v != nil {
+			return v, err
+		}
+		return []T{}, nil
+	}
+}
+
+// pageArgs are the arguments of a connection.
+func pageArgs() graphql.FieldConfigArgument {
+	return graphql.FieldConfigArgument{
+		"first": {
+			Type:         graphql.Int,
+			DefaultValue: handlers.LimitDefault,
+			Description:  fmt.Sprintf("Capped at %d.", handlers.LimitMax),
+		},
+		"after": {Type: graphql.String},
+	}
+}
+
+// parsePageArgs returns the page size of a connection, and the time before which its videos
+// were published: the time of the request, by default.
+func parsePageArgs(ctx context.Context, args map[string]interface{}) (int, time.Time, error) {
+	first, _ := args["first"].(int)
+	if first <= 0 {
+		return 0, time.Time{}, fmt.Errorf("first must be positive")
+	}
+	if first > handlers.LimitMax {
+		first = handlers.LimitMax
+	}
+
+	after, ok := args["after"].(string)
+	if !ok {
+		return first, loadersFrom(ctx).now, nil
+	}
+	before, err := decodeCursor(after)
+	return first, before, err
+}
+
+// parseFilterArg parses a VideoFilter input.
+func parseFilterArg(arg interface{}) (store.Filter, error) {
+	fields, _ := arg.(map[string]interface{})
+	query := url.Values{}
+	for field, value := range fields {
+		switch value := value.(type) {
+		case time.Time:
+			query.Set(filterParams[field], value.Format(handlers.QueryTimeFmt))
+		case int:
+			query.Set(filterParams[field], strconv.Itoa(value))
+		case string:
+			query.Set(filterParams[field], value)
+		}
+	}
+	return handlers.ParseFilterParams(query)
+}
+
+func encodeCursor(publishedAt time.Time) string {
+	return base64.URLEncoding.EncodeToString(
+		[]byte(cursorPrefix + publishedAt.Format(time.RFC3339Nano)))
+}
+
+func decodeCursor(cursor string) (time.Time, error) {
+	invalid := fmt.Errorf("invalid cursor")
+	raw, err := base64.URLEncoding.DecodeString(cursor)
+	if err != nil {
+		return time.Time{}, invalid
+	}
+	if !strings.HasPrefix(string(raw), cursorPrefix) {
+		return time.Time{}, invalid
+	}
+	before, err := time.Parse(time.RFC3339Nano, strings.TrimPrefix(string(raw), cursorPrefix))
+	if err != nil {
+		return time.Time{}, invalid
+	}
+	return before, nil
+}
+
+// loadersKey keys the loaders of a request in its context.
+type loadersKey struct{}
+
+// channelVideosKey identifies the page of a channel's videos to load.
+type channelVideosKey struct {
+	channelID string
+	before    time.Time
+	limit     int
+}
+
+// loaders batch the store queries made to resolve a request.
+type loaders struct {
+	// now is the time of the request, so that pages of videos alike batch together.
+	now time.Time
+
+	videos        *loader[string, yt.Video]
+	channels      *loader[string, store.Channel]
+	channelVideos *loader[channelVideosKey, []yt.Video]
+	statsHistory  *loader[string, []store.VideoStats]
+	// watches are loaded by the IDs of videos in the store
+	watches *loader[uint, []store.SavedSearch]
+}
+
+func newLoaders(st store.VideoStore, onErr func(error)) *loaders {
+	// Store errors are not exposed to clients
+	internal := func(err error) error {
+		onErr(err)
+		return fmt.Errorf("internal error")
+	}
+
+	return &loaders{
+		now: time.Now(),
+		videos: newLoader(func(ids []string) (map[string]yt.Video, error) {
+			found, err := st.Lookup(ids)
+			if err != nil {
+				return nil, internal(err)
+			}
+			videos := make(map[string]yt.Video, len(found))
+			for _, v := range found {
+				videos[v.VideoId] = v
+			}
+			return videos, nil
+		}),
+		channels: newLoader(func(ids []string) (map[string]store.Channel, error) {
+			found, err := st.Channels(ids)
+			if err != nil {
+				return nil, internal(err)
+			}
+			channels := make(map[string]store.Channel, len(found))
+			for _, c := range found {
+				channels[c.ID] = c
+			}
+			return channels, nil
+		}),
+		channelVideos: newLoader(func(keys []channelVideosKey,
+		) (map[channelVideosKey][]yt.Video, error) {
+			// Channels paged alike are loaded together
+			type page struct {
+				before time.Time
+				limit  int
+			}
+			pages := map[page][]string{}
+			for _, key := range keys {
+				pg := page{key.before, key.limit}
+				pages[pg] = append(pages[pg], key.channelID)
+			}
+
+			videos := make(map[channelVideosKey][]yt.Video, len(keys))
+			for pg, channelIDs := range pages {
+				byChannel, err := st.ChannelVideos(channelIDs, pg.before, pg.limit)
+				if err != nil {
+					return nil, internal(err)
+				}
+				for channelID, v := range byChannel {
+					videos[channelVideosKey{channelID, pg.before, pg.limit}] = v
+				}
+			}
+			return videos, nil
+		}),
+		statsHistory: newLoader(func(ids []string) (map[string][]store.VideoStats, error) {
+			history, err := st.StatsHistory(ids)
+			if err != nil {
+				return nil, internal(err)
+			}
+			return history, nil
+		}),
+		watches: newLoader(func(ids []uint) (map[uint][]store.SavedSearch, error) {
+			watches, err := st.MatchingSearches(ids)
+			if err != nil {
+				return nil, internal(err)
+			}
+			return watches, nil
+		}),
+	}
+}
+
+func withLoaders(ctx context.Context, l *loaders) context.Context {
+	return context.WithValue(ctx, loadersKey{}, l)
+}
+
+func loadersFrom(ctx context.Context) *loaders {
+	return ctx.Value(loadersKey{}).(*loaders)
+}
@@ -0,0 +1,301 @@
+package graphql
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/ditsuke/youtube-focus/internal/services"
+	"github.com/ditsuke/youtube-focus/internal/yt"
+	"github.com/ditsuke/youtube-focus/store"
+	"github.com/graphql-go/graphql"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+// epoch is when the latest of the videos made by testVideos was published.
+var epoch = time.Date(2022, time.September, 1, 12, 0, 0, 0, time.UTC)
+
+// testVideos returns n videos, video0 to video<n-1>, published a minute apart from epoch,
+// latest first. Their channels, channel0 to channel2, take turns, and they are about cats and
+// dogs in turn.
+func testVideos(n int) []yt.Video {
+	videos := make([]yt.Video, n)
+	for i := range videos {
+		pet := "Cats"
+		if i%2 == 1 {
+			pet = "Dogs"
+		}
+		videos[i] = yt.Video{
+			VideoId:      fmt.Sprintf("video%d", i),
+			Title:        fmt.Sprintf("%s, video %d", pet, i),
+			PublishedAt:  epoch.Add(-time.Duration(i) * time.Minute),
+			ChannelId:    fmt.Sprintf("channel%d", i%3),
+			ChannelTitle: fmt.Sprintf("Channel %d", i%3),
+			ViewCount:    int64(100 * (i + 1)),
+		}
+	}
+	return videos
+}
+
+// savedSearches lists its saved searches.
+type savedSearches []store.SavedSearch
+
+func (s savedSearches) List() ([]store.SavedSearch, error) {
+	return s, nil
+}
+
+// countingStore counts the calls to the methods of a store that loaders batch.
+type countingStore struct {
+	store.VideoStore
+
+	mu    sync.Mutex
+	calls map[string]int
+}
+
+func (s *countingStore) count(method string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.calls[method]++
+}
+
+func (s *countingStore) Lookup(ids []string) ([]yt.Video, error) {
+	s.count("Lookup")
+	return s.VideoStore.Lookup(ids)
+}
+
+func (s *countingStore) Channels(ids []string) ([]store.Channel, error) {
+	s.count("Channels")
+	return s.VideoStore.Channels(ids)
+}
+
+func (s *countingStore) ChannelVideos(ids []string, publishedBefore time.Time, limit int,
+) (map[string][]yt.Video, error) {
+	s.count("ChannelVideos")
+	return s.VideoStore.ChannelVideos(ids, publishedBefore, limit)
+}
+
+func (s *countingStore) StatsHistory(ids []string) (map[string][]store.VideoStats, error) {
+	s.count("StatsHistory")
+	return s.VideoStore.StatsHistory(ids)
+}
+
+func (s *countingStore) MatchingSearches(ids []uint) (map[uint][]store.SavedSearch, error) {
+	s.count("MatchingSearches")
+	return s.VideoStore.MatchingSearches(ids)
+}
+
+// newTestStore returns a counting store holding n testVideos, with a saved search for cats.
+func newTestStore(t *testing.T, n int) *countingStore {
+	t.Helper()
+	m := store.NewMemoryStore()
+	m.Searches = savedSearches{{Name: "cats", Query: "cats", Mode: store.ModeLike}}
+	if _, err := m.Save(testVideos(n)); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	return &countingStore{VideoStore: m, calls: map[string]int{}}
+}
+
+// query runs a query on the schema over st, failing the test if it fails, and decodes its
+// data into v.
+func query(t *testing.T, st store.VideoStore, q string, v interface{}) {
+	t.Helper()
+	result := run(t, st, q)
+	if result.HasErrors() {
+		t.Fatalf("query %s: %v", q, result.Errors)
+	}
+	data, err := json.Marshal(result.Data)
+	if err != nil {
+		t.Fatalf("encode the data: %v", err)
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		t.Fatalf("decode the data: %v", err)
+	}
+}
+
+// run runs a query on the schema over st.
+func run(t *testing.T, st store.VideoStore, q string) *graphql.Result {
+	t.Helper()
+	schema, err := NewSchema(st, services.NewBroadcaster[[]yt.Video]())
+	if err != nil {
+		t.Fatalf("NewSchema: %v", err)
+	}
+	l := newLoaders(st, func(err error) { t.Errorf("store query: %v", err) })
+	return graphql.Do(graphql.Params{
+		Schema:        schema,
+		RequestString: q,
+		Context:       withLoaders(context.Background(), l),
+	})
+}
+
+// page is a page of a connection.
+type page struct {
+	Edges []struct {
+		Cursor string `json:"cursor"`
+		Node   struct {
+			ID string `json:"id"`
+		} `json:"node"`
+	} `json:"edges"`
+	PageInfo struct {
+		HasNextPage bool   `json:"hasNextPage"`
+		EndCursor   string `json:"endCursor"`
+	} `json:"pageInfo"`
+}
+
+func (p page) ids() []string {
+	ids := []string{}
+	for _, e := range p.Edges {
+		ids = append(ids, e.Node.ID)
+	}
+	return ids
+}
+
+func TestLoadsAreBatched(t *testing.T) {
+	st := newTestStore(t, 6)
+	var data struct {
+		Videos struct {
+			Edges []struct {
+				Node struct {
+					ID      string `json:"id"`
+					Channel struct {
+						ID     string `json:"id"`
+						Videos page   `json:"videos"`
+					} `json:"channel"`
+					StatsHistory []struct {
+						ViewCount float64 `json:"viewCount"`
+					} `json:"statsHistory"`
+					Watches []struct {
+						Name string `json:"name"`
+					} `json:"watches"`
+				} `json:"node"`
+			} `json:"edges"`
+		} `json:"videos"`
+	}
+	query(t, st, `{
+		videos(first: 6) {
+			edges { node {
+				id
+				channel { id videos(first: 2) { edges { node { id } } } }
+				statsHistory { viewCount }
+				watches { name }
+			} }
+		}
+	}`, &data)
+
+	want := map[string]int{"ChannelVideos": 1, "StatsHistory": 1, "MatchingSearches": 1}
+	if !reflect.DeepEqual(st.calls, want) {
+		t.Errorf("store calls = %v, want %v", st.calls, want)
+	}
+	if n := len(data.Videos.Edges); n != 6 {
+		t.Fatalf("got %d videos, want 6", n)
+	}
+	for i, e := range data.Videos.Edges {
+		node := e.Node
+		channelVideos := node.Channel.Videos.ids()
+		if len(channelVideos) != 2 || node.Channel.ID != fmt.Sprintf("channel%d", i%3) {
+			t.Errorf("channel of %s = %s with %v, want channel%d with 2 videos",
+				node.ID, node.Channel.ID, channelVideos, i%3)
+		}
+		if len(node.StatsHistory) != 1 || node.StatsHistory[0].ViewCount != float64(100*(i+1)) {
+			t.Errorf("statsHistory of %s = %v, want its views", node.ID, node.StatsHistory)
+		}
+		if cats := i%2 == 0; cats != (len(node.Watches) == 1) {
+			t.Errorf("watches of %s = %v", node.ID, node.Watches)
+		}
+	}
+
+	// Top-level lookups batch together too
+	st.calls = map[string]int{}
+	var lookups struct {
+		A, B struct {
+			ID string `json:"id"`
+		}
+		C, D struct {
+			Title string `json:"title"`
+		}
+	}
+	query(t, st, `{
+		a: video(id: "video0") { id }
+		b: video(id: "video1") { id }
+		c: channel(id: "channel0") { title }
+		d: channel(id: "channel1") { title }
+	}`, &lookups)
+	if want := map[string]int{"Lookup": 1, "Channels": 1}; !reflect.DeepEqual(st.calls, want) {
+		t.Errorf("store calls = %v, want %v", st.calls, want)
+	}
+	if lookups.A.ID != "video0" || lookups.B.ID != "video1" || lookups.C.Title != "Channel 0" ||
+		lookups.D.Title != "Channel 1" {
+		t.Errorf("lookups = %+v", lookups)
+	}
+}
+
+func TestConnectionPaging(t *testing.T) {
+	st := newTestStore(t, 5)
+
+	var ids []string
+	after := ""
+	for pages := 0; ; pages++ {
+		if pages == 5 {
+			t.Fatalf("paged past the end, through %v", ids)
+		}
+		var data struct {
+			Videos page `json:"videos"`
+		}
+		query(t, st, fmt.Sprintf(`{
+			videos(first: 2%s) {
+				edges { cursor node { id } }
+				pageInfo { hasNextPage endCursor }
+			}
+		}`, after), &data)
+		ids = append(ids, data.Videos.ids()...)
+
+		edges := data.Videos.Edges
+		if len(edges) > 0 && edges[len(edges)-1].Cursor != data.Videos.PageInfo.EndCursor {
+			t.Errorf("endCursor = %q, want the cursor of the last edge",
+				data.Videos.PageInfo.EndCursor)
+		}
+		if !data.Videos.PageInfo.HasNextPage {
+			break
+		}
+		after = fmt.Sprintf(", after: %q", data.Videos.PageInfo.EndCursor)
+	}
+	want := []string{"video0", "video1", "video2", "video3", "video4"}
+	if !reflect.DeepEqual(ids, want) {
+		t.Errorf("paged through %v, want %v", ids, want)
+	}
+
+	// Channels page through their own videos
+	var data struct {
+		Channel struct {
+			Videos page `json:"videos"`
+		} `json:"channel"`
+	}
+	query(t, st, `{ channel(id: "channel0") {
+		videos(first: 1) { edges { cursor node { id } } pageInfo { hasNextPage endCursor } }
+	} }`, &data)
+	if got := data.Channel.Videos.ids(); !reflect.DeepEqual(got, []string{"video0"}) ||
+		!data.Channel.Videos.PageInfo.HasNextPage {
+		t.Errorf("first page of channel0 = %v, %+v", got, data.Channel.Videos.PageInfo)
+	}
+	query(t, st, fmt.Sprintf(`{ channel(id: "channel0") {
+		videos(first: 1, after: %q) { edges { node { id } } pageInfo { hasNextPage } }
+	} }`, data.Channel.Videos.PageInfo.EndCursor), &data)
+	if got := data.Channel.Videos.ids(); !reflect.DeepEqual(got, []string{"video3"}) ||
+		data.Channel.Videos.PageInfo.HasNextPage {
+		t.Errorf("last page of channel0 = %v, %+v", got, data.Channel.Videos.PageInfo)
+	}
+}
+
+func TestConnectionArgs(t *testing.T) {
+	st := newTestStore(t, 1)
+	for _, q := range []string{
+		`{ videos(first: 0) { edges { node { id } } } }`,
+		`{ videos(after: "nonsense") { edges { node { id } } } }`,
+		`{ videos(filter: {type: "short"}) { edges { node { id } } } }`,
+	} {
+		if result := run(t, st, q); !result.HasErrors() {
+			t.Errorf("query %s succeeded", q)
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"github.com/ditsuke/youtube-focus/api/response"
+	"github.com/ditsuke/youtube-focus/store"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"net/http"
+	"strconv"
+)
+
+// ParamAPIKeyID is the URL parameter identifying an API key.
+const ParamAPIKeyID = "id"
+
+// APIKeyHandler provides HTTP handlers to administer client API keys.
+type APIKeyHandler struct {
+	keys store.APIKeyStore
+}
+
+// NewAPIKeyHandler returns an APIKeyHandler managing keys in the passed store.APIKeyStore.
+func NewAPIKeyHandler(keys store.APIKeyStore) *APIKeyHandler {
+	return &APIKeyHandler{keys: keys}
+}
+
+// APIKeyRequest is the payload to issue an API key with.
+type APIKeyRequest struct {
+	Name string `json:"name"`
+	// RateLimit is the number of requests per minute to allow with the key, zero for the
+	// server default.
+	RateLimit int `json:"rate_limit"`
+	// Role is the role granted to the key, store.RoleReader by default.
+	Role store.Role `json:"role"`
+}
+
+// Bind validates the request.
+func (k *APIKeyRequest) Bind(r *http.Request) error {
+	if k.Name == "" {
+		return fmt.Errorf("no `name` for api key")
+	}
+	if k.RateLimit < 0 {
+		return fmt.Errorf("invalid `rate_limit`")
+	}
+	if k.Role == "" {
+		k.Role = store.RoleReader
+	}
+	if !k.Role.IsValid() {
+		return fmt.Errorf("invalid role %q", k.Role)
+	}
+	return nil
+}
+
+// Issue a new API key. The response is the only time the secret key is revealed.
+func (c *APIKeyHandler) Issue(w http.ResponseWriter, r *http.Request) {
+	req := &APIKeyRequest{}
+	if err := render.Bind(r, req); err != nil {
+		_ = render.Render(w, r, response.ErrInvalidRequest(err))
+		return
+	}
+
+	key := store.APIKey{Name: req.Name, RateLimit: req.RateLimit, Role: req.Role}
+	secret, err := c.keys.Issue(&key)
+	if err != nil {
+		_ = render.Render(w, r, response.ErrStore(err))
+		return
+	}
+
+	resp := response.NewAPIKeyResponse(key)
+	resp.Key = secret
+	_ = render.Render(w, r, resp)
+}
+
+// List all API keys, including revoked ones.
+func (c *APIKeyHandler) List(w http.ResponseWriter, r *http.Request) {
+	keys, err := c.keys.List()
+	if err != nil {
+		_ = render.Render(w, r, response.ErrStore(err))
+		return
+	}
+
+	resp := &response.APIKeysResponse{Keys: make([]*response.APIKeyResponse, len(keys))}
+	for i := range keys {
+		resp.Keys[i] = response.NewAPIKeyResponse(keys[i])
+	}
+	_ = render.Render(w, r, resp)
+}
+
+// Revoke an API key.
+func (c *APIKeyHandler) Revoke(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseUint(chi.URLParam(r, ParamAPIKeyID), 10, 0)
+	if err != nil {
+		_ = render.Render(w, r, response.ErrInvalidRequest(fmt.Errorf("invalid api key id")))
+		return
+	}
+
+	key, err := c.keys.Revoke(uint(id))
+	if errors.Is(err, store.ErrNotFound) {
+		_ = render.Render(w, r, response.ErrNotFound(fmt.Errorf("no such unrevoked api key")))
+		return
+	}
+	if err != nil {
+		_ = render.Render(w, r, response.ErrStore(err))
+		return
+	}
+
+	_ = render.Render(w, r, response.NewAPIKeyResponse(key))
+}
@@ -0,0 +1,44 @@
+package handlers
+
+import (
+	"fmt"
+	"github.com/ditsuke/youtube-focus/api/response"
+	"github.com/ditsuke/youtube-focus/store"
+	"github.com/go-chi/render"
+	"net/http"
+)
+
+// AuditHandler provides HTTP handlers to read the audit log.
+type AuditHandler struct {
+	audit store.AuditStore
+}
+
+// NewAuditHandler returns an AuditHandler reading from the passed store.AuditStore.
+func NewAuditHandler(audit store.AuditStore) *AuditHandler {
+	return &AuditHandler{audit: audit}
+}
+
+// List entries in the audit log, latest first. Unlike video listings, ParamFrom takes the ID
+// of an entry.
+func (c *AuditHandler) List(w http.ResponseWriter, r *http.Request) {
+	qParams := r.URL.Query()
+	from, err := parseParam(qParams, ParamFrom, int64(0))
+	if err != nil || from < 0 {
+		_ = render.Render(w, r, response.ErrInvalidRequest(
+			fmt.Errorf("invalid %s param", ParamFrom)))
+		return
+	}
+	_, limit, err := getPaginationParams(qParams)
+	if err != nil {
+		_ = render.Render(w, r, response.ErrInvalidRequest(err))
+		return
+	}
+
+	entries, err := c.audit.Retrieve(uint(from), limit)
+	if err != nil {
+		_ = render.Render(w, r, response.ErrStore(err))
+		return
+	}
+
+	_ = render.Render(w, r, response.NewAuditLogResponse(entries))
+}
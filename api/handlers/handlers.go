@@ -1,9 +1,12 @@
 package handlers
 
 import (
+	"errors"
 	"fmt"
 	"github.com/ditsuke/youtube-focus/api/response"
+	"github.com/ditsuke/youtube-focus/internal/yt"
 	"github.com/ditsuke/youtube-focus/store"
+	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/render"
 	"net/http"
 	"time"
@@ -21,23 +24,83 @@ const (
 	// The param is used in both the /videos and /videos_search endpoints.
 	ParamSearch = "search"
 
+	// ParamAfter and ParamBefore filter results to those published in a time range, in the
+	// QueryTimeFmt format.
+	ParamAfter  = "after"
+	ParamBefore = "before"
+
+	// ParamChannel filters results to those from the channel with a given ID.
+	ParamChannel = "channel"
+
+	// ParamMinDuration and ParamMaxDuration filter results by video duration, in seconds.
+	ParamMinDuration = "min_duration"
+	ParamMaxDuration = "max_duration"
+
+	// ParamMinViews and ParamMaxViews filter results by view count.
+	ParamMinViews = "min_views"
+	ParamMaxViews = "max_views"
+
+	// ParamType filters results by the type of video, one of TypeUpload, TypeLive or
+	// TypeUpcoming.
+	ParamType = "type"
+
+	// ParamLanguage filters results by video language, eg: "en" or "en-US".
+	ParamLanguage = "lang"
+
+	// ParamCategory filters results by YouTube video category ID.
+	ParamCategory = "category"
+
+	// ParamRemoved includes videos removed from YouTube in results, or restricts results to
+	// them, with one of store.RemovedExclude (the default), RemovedInclude or RemovedOnly.
+	ParamRemoved = "removed"
+
+	// ParamVideoID is the URL parameter identifying a video by its YouTube ID.
+	ParamVideoID = "videoId"
+
+	// LookupMax is the maximum number of videos that can be looked up in one request.
+	LookupMax = 100
+
+	// ExportFlushEvery is the number of videos streamed between flushes of an export.
+	ExportFlushEvery = 500
+
+	// ParamFacets is a comma-separated list of facets (see store.Facet) to count the results
+	// of a /videos_search query by.
+	ParamFacets = "facets"
+
+	// FacetValuesMax is the maximum number of values counted per facet.
+	FacetValuesMax = 10
+
+	// ParamFuzzy switches /videos searches to typo-tolerant trigram matching when true.
+	ParamFuzzy = "fuzzy"
+
 	// LimitMax is the maximum value of ParamLimit, beyond which is it capped
 	LimitMax = 20
 
 	// LimitDefault is the default limit of results in a response.
 	LimitDefault = 10
 
+	// SuggestionsMax is the maximum number of "did you mean" suggestions offered when a search
+	// yields no results.
+	SuggestionsMax = 3
+
 	// QueryTimeFmt is the accepted format of time values in URL queries.
 	QueryTimeFmt = time.RFC3339
 )
 
+// Values of ParamType.
+const (
+	TypeUpload   = "upload"
+	TypeLive     = "live"
+	TypeUpcoming = "upcoming"
+)
+
 // VideoHandler provides HTTP handlers for the video API.
 type VideoHandler struct {
-	store store.VideoMetaStore
+	store store.VideoStore
 }
 
-// New returns a VideoHandler configured with the passed store.VideoMetaStore
-func New(svc store.VideoMetaStore) *VideoHandler {
+// New returns a VideoHandler configured with the passed store.VideoStore
+func New(svc store.VideoStore) *VideoHandler {
 	return &VideoHandler{
 		store: svc,
 	}
@@ -52,15 +115,47 @@ func (c *VideoHandler) Search(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	filter, err := ParseFilterParams(qParams)
+	if err != nil {
+		_ = render.Render(w, r, response.ErrInvalidRequest(err))
+		return
+	}
+
+	fuzzy, err := parseParam(qParams, ParamFuzzy, false)
+	if err != nil {
+		_ = render.Render(w, r, response.ErrInvalidRequest(
+			fmt.Errorf("invalid %s param", ParamFuzzy)))
+		return
+	}
+
+	st := c.store.WithFilter(filter)
 	s, _ := parseParam(qParams, ParamSearch, "")
 	if s == "" {
-		videos := c.store.Retrieve(from, limit)
-		_ = render.Render(w, r, response.NewVideosResponse(videos))
+		videos, err := st.Retrieve(from, limit)
+		if err != nil {
+			_ = render.Render(w, r, response.ErrStore(err))
+			return
+		}
+		response.RenderVideos(w, r, response.NewVideosResponse(videos))
 		return
 	}
 
-	videos := c.store.Search(s, from, limit)
-	_ = render.Render(w, r, response.NewVideosResponse(videos))
+	var videos []yt.Video
+	if fuzzy {
+		videos, err = st.FuzzySearch(s, from, limit)
+	} else {
+		videos, err = st.Search(s, from, limit)
+	}
+	if err != nil {
+		_ = render.Render(w, r, response.ErrStore(err))
+		return
+	}
+	resp, err := searchResponse(st, s, videos, !qParams.Has(ParamFrom))
+	if err != nil {
+		_ = render.Render(w, r, response.ErrStore(err))
+		return
+	}
+	response.RenderVideos(w, r, resp)
 }
 
 // AdvancedSearch handles natural-language search queries
@@ -69,6 +164,19 @@ func (c *VideoHandler) AdvancedSearch(w http.ResponseWriter, r *http.Request) {
 	_, limit, err := getPaginationParams(qParams)
 	if err != nil {
 		_ = render.Render(w, r, response.ErrInvalidRequest(err))
+		return
+	}
+
+	filter, err := ParseFilterParams(qParams)
+	if err != nil {
+		_ = render.Render(w, r, response.ErrInvalidRequest(err))
+		return
+	}
+
+	facets, err := getFacetParams(qParams)
+	if err != nil {
+		_ = render.Render(w, r, response.ErrInvalidRequest(err))
+		return
 	}
 
 	s, _ := parseParam(qParams, ParamSearch, "")
@@ -78,6 +186,176 @@ func (c *VideoHandler) AdvancedSearch(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	videos := c.store.NaturalSearch(s, limit)
-	_ = render.Render(w, r, response.NewVideosResponse(videos))
+	st := c.store.WithFilter(filter)
+	videos, err := st.NaturalSearch(s, limit)
+	if err != nil {
+		_ = render.Render(w, r, response.ErrStore(err))
+		return
+	}
+	// Natural-language searches yield a single page
+	resp, err := searchResponse(st, s, videos, true)
+	if err == nil && len(facets) > 0 {
+		resp.Facets, err = st.NaturalSearchFacets(s, facets, FacetValuesMax)
+	}
+	if err != nil {
+		_ = render.Render(w, r, response.ErrStore(err))
+		return
+	}
+	response.RenderVideos(w, r, resp)
+}
+
+// searchResponse builds the response to a search query, with "did you mean" suggestions
+// attached when nothing matched. Empty pages past the first only mark the end of the results,
+// and get no suggestions.
+func searchResponse(st store.VideoStore, query string, videos []yt.Video,
+	firstPage bool,
+) (*response.VideosResponse, error) {
+	resp := response.NewVideosResponse(videos)
+	if len(videos) == 0 && firstPage {
+		suggestions, err := st.Suggest(query, SuggestionsMax)
+		if err != nil {
+			return nil, err
+		}
+		resp.Suggestions = suggestions
+	}
+	return resp, nil
+}
+
+// LookupRequest is the payload to look up videos with.
+type LookupRequest struct {
+	IDs []string `json:"ids"`
+}
+
+// Bind validates the request.
+func (l *LookupRequest) Bind(r *http.Request) error {
+	if len(l.IDs) == 0 {
+		return fmt.Errorf("no `ids` to look up")
+	}
+	if len(l.IDs) > LookupMax {
+		return fmt.Errorf("too many `ids`, the maximum is %d", LookupMax)
+	}
+	return nil
+}
+
+// Get handles requests for a single video by its ID.
+func (c *VideoHandler) Get(w http.ResponseWriter, r *http.Request) {
+	shape, err := response.ParseShape(r)
+	if err != nil {
+		_ = render.Render(w, r, response.ErrInvalidRequest(err))
+		return
+	}
+
+	video, err := c.store.Get(chi.URLParam(r, ParamVideoID))
+	if errors.Is(err, store.ErrNotFound) {
+		_ = render.Render(w, r, response.ErrNotFound(fmt.Errorf("no such video")))
+		return
+	}
+	if err != nil {
+		_ = render.Render(w, r, response.ErrStore(err))
+		return
+	}
+
+	_ = render.Render(w, r, response.NewVideoResponse(video, shape))
+}
+
+// History handles requests for the history of the content of a video, by its ID.
+func (c *VideoHandler) History(w http.ResponseWriter, r *http.Request) {
+	video, err := c.store.Get(chi.URLParam(r, ParamVideoID))
+	if errors.Is(err, store.ErrNotFound) {
+		_ = render.Render(w, r, response.ErrNotFound(fmt.Errorf("no such video")))
+		return
+	}
+	if err != nil {
+		_ = render.Render(w, r, response.ErrStore(err))
+		return
+	}
+
+	revisions, err := c.store.Revisions(video.VideoId)
+	if err != nil {
+		_ = render.Render(w, r, response.ErrStore(err))
+		return
+	}
+
+	_ = render.Render(w, r, response.NewHistoryResponse(video, revisions))
+}
+
+// Lookup handles requests for videos in bulk by their IDs.
+func (c *VideoHandler) Lookup(w http.ResponseWriter, r *http.Request) {
+	shape, err := response.ParseShape(r)
+	if err != nil {
+		_ = render.Render(w, r, response.ErrInvalidRequest(err))
+		return
+	}
+
+	req := &LookupRequest{}
+	if err := render.Bind(r, req); err != nil {
+		_ = render.Render(w, r, response.ErrInvalidRequest(err))
+		return
+	}
+
+	videos, err := c.store.Lookup(req.IDs)
+	if err != nil {
+		_ = render.Render(w, r, response.ErrStore(err))
+		return
+	}
+
+	_ = render.Render(w, r, response.NewLookupResponse(req.IDs, videos, shape))
+}
+
+// Export handles requests to stream every video matching some filters, oldest first, in one
+// of the response.FormatNDJSON (the default) or response.FormatCSV formats. Exported videos
+// embed every related object unless shaped otherwise.
+func (c *VideoHandler) Export(w http.ResponseWriter, r *http.Request) {
+	format, err := response.NegotiateFormat(r, response.FormatNDJSON, response.FormatCSV)
+	if err != nil {
+		_ = render.Render(w, r, response.ErrInvalidRequest(err))
+		return
+	}
+	if format == response.FormatJSON {
+		format = response.FormatNDJSON
+	}
+
+	filter, err := ParseFilterParams(r.URL.Query())
+	if err != nil {
+		_ = render.Render(w, r, response.ErrInvalidRequest(err))
+		return
+	}
+
+	shape, err := response.ParseShape(r)
+	if err != nil {
+		_ = render.Render(w, r, response.ErrInvalidRequest(err))
+		return
+	}
+	if shape.IsZero() {
+		shape = response.FullShape
+	}
+
+	w.Header().Set("Content-Type", response.ContentType(format))
+	w.Header().Set("Content-Disposition", `attachment; filename="videos.`+format+`"`)
+	flusher, _ := w.(http.Flusher)
+	enc := response.NewVideoEncoder(format, w, shape)
+
+	written := 0
+	err = c.store.WithFilter(filter).Walk(func(v *yt.Video) error {
+		if err := enc.Encode(v); err != nil {
+			return err
+		}
+		written++
+		if written%ExportFlushEvery == 0 && flusher != nil {
+			if err := enc.Flush(); err != nil {
+				return err
+			}
+			flusher.Flush()
+		}
+		return nil
+	})
+	if err != nil {
+		// Too late for an error response once streaming has begun
+		if written == 0 {
+			_ = render.Render(w, r, response.ErrStore(err))
+		}
+		return
+	}
+
+	_ = enc.Flush()
 }
@@ -0,0 +1,345 @@
+package handlers
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"github.com/ditsuke/youtube-focus/config"
+	"github.com/ditsuke/youtube-focus/internal/yt"
+	"github.com/ditsuke/youtube-focus/store"
+	"github.com/ditsuke/youtube-focus/store/migrations"
+	"github.com/go-chi/chi/v5"
+	"gorm.io/gorm"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTestDB returns a migrated in-memory SQLite database, dropped at the end of the test.
+func newTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := config.Config{DBDriver: config.DriverSQLite, SQLitePath: ":memory:"}.GetDB()
+	if err != nil {
+		t.Fatalf("open the database: %v", err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatalf("open the database: %v", err)
+	}
+	t.Cleanup(func() { _ = sqlDB.Close() })
+	if _, err := migrations.Up(db); err != nil {
+		t.Fatalf("migrate the database: %v", err)
+	}
+	return db
+}
+
+// testStores are the video stores the handlers are tested on. Saved searches are kept in the
+// database either way, as with the memory driver.
+var testStores = []struct {
+	name     string
+	newStore func(db *gorm.DB, searches *store.SavedSearchStore) store.ManagedVideoStore
+}{
+	{"VideoMetaStore", func(db *gorm.DB, _ *store.SavedSearchStore) store.ManagedVideoStore {
+		return &store.VideoMetaStore{DB: db}
+	}},
+	{"MemoryStore", func(_ *gorm.DB, searches *store.SavedSearchStore) store.ManagedVideoStore {
+		m := store.NewMemoryStore()
+		m.Searches = searches
+		return m
+	}},
+}
+
+// testServer routes requests to the video and saved search handlers over a store of videos.
+type testServer struct {
+	t        *testing.T
+	router   *chi.Mux
+	videos   store.ManagedVideoStore
+	searches *store.SavedSearchStore
+}
+
+// forEachStore runs test on a testServer over each of testStores.
+func forEachStore(t *testing.T, test func(t *testing.T, s *testServer)) {
+	for _, tt := range testStores {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			db := newTestDB(t)
+			searches := &store.SavedSearchStore{DB: db}
+			s := &testServer{t: t, searches: searches, videos: tt.newStore(db, searches)}
+
+			videoSvc := New(s.videos)
+			savedSearchSvc := NewSavedSearchHandler(*searches, s.videos)
+			s.router = chi.NewRouter()
+			s.router.Get("/videos", videoSvc.Search)
+			s.router.Get("/videos/{"+ParamVideoID+"}", videoSvc.Get)
+			s.router.Get("/videos/{"+ParamVideoID+"}/history", videoSvc.History)
+			s.router.Get("/videos_search", videoSvc.AdvancedSearch)
+			s.router.Post("/videos/lookup", videoSvc.Lookup)
+			s.router.Get("/export", videoSvc.Export)
+			s.router.Post("/saved_searches", savedSearchSvc.Create)
+			s.router.Get("/saved_searches/{"+ParamSavedSearchID+"}/new", savedSearchSvc.New)
+			test(t, s)
+		})
+	}
+}
+
+// save saves videos, failing the test if that fails.
+func (s *testServer) save(videos ...yt.Video) {
+	s.t.Helper()
+	if _, err := s.videos.Save(videos); err != nil {
+		s.t.Fatalf("Save: %v", err)
+	}
+}
+
+// do serves a request, and returns the recorded response.
+func (s *testServer) do(method, target, body string) *httptest.ResponseRecorder {
+	s.t.Helper()
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	if body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	rec := httptest.NewRecorder()
+	s.router.ServeHTTP(rec, req)
+	return rec
+}
+
+// get serves a GET request, failing the test unless it succeeds, and decodes its response
+// into v.
+func (s *testServer) get(target string, v interface{}) {
+	s.t.Helper()
+	s.decode(http.MethodGet, target, "", v)
+}
+
+// decode is like get, for requests of any method.
+func (s *testServer) decode(method, target, body string, v interface{}) {
+	s.t.Helper()
+	rec := s.do(method, target, body)
+	if rec.Code != http.StatusOK && rec.Code != http.StatusCreated {
+		s.t.Fatalf("%s %s = %d %s, want success", method, target, rec.Code, rec.Body)
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), v); err != nil {
+		s.t.Fatalf("decode the response to %s %s: %v", method, target, err)
+	}
+}
+
+// epoch is when the latest of the videos made by testVideos was published.
+var epoch = time.Date(2022, time.September, 1, 12, 0, 0, 0, time.UTC)
+
+// testVideos returns videos titled as passed, published a minute apart from epoch, latest
+// first.
+func testVideos(titles ...string) []yt.Video {
+	videos := make([]yt.Video, len(titles))
+	for i, title := range titles {
+		videos[i] = yt.Video{
+			VideoId:     fmt.Sprintf("video%d", i),
+			Title:       title,
+			PublishedAt: epoch.Add(-time.Duration(i) * time.Minute),
+			ChannelId:   "channel",
+		}
+	}
+	return videos
+}
+
+type videosBody struct {
+	Videos      []yt.Video `json:"videos"`
+	Suggestions []string   `json:"suggestions"`
+	Facets      map[string][]struct {
+		Value string `json:"value"`
+		Count int64  `json:"count"`
+	} `json:"facets"`
+}
+
+func expectIDs(t *testing.T, what string, got []yt.Video, want ...string) {
+	t.Helper()
+	ids := []string{}
+	for _, v := range got {
+		ids = append(ids, v.VideoId)
+	}
+	if want == nil {
+		want = []string{}
+	}
+	if !reflect.DeepEqual(ids, want) {
+		t.Errorf("%s = %v, want %v", what, ids, want)
+	}
+}
+
+func TestSearch(t *testing.T) {
+	forEachStore(t, func(t *testing.T, s *testServer) {
+		s.save(testVideos("Cats at play", "Dogs at play", "Cats asleep")...)
+
+		var body videosBody
+		s.get("/videos?limit=2", &body)
+		expectIDs(t, "GET /videos?limit=2", body.Videos, "video0", "video1")
+
+		body = videosBody{}
+		s.get("/videos?search=cats", &body)
+		expectIDs(t, "GET /videos?search=cats", body.Videos, "video0", "video2")
+
+		body = videosBody{}
+		s.get("/videos?search=play+cats", &body)
+		expectIDs(t, "GET /videos?search=play+cats", body.Videos)
+
+		body = videosBody{}
+		s.get("/videos?search=play+cats&fuzzy=true", &body)
+		expectIDs(t, "GET /videos?search=play+cats&fuzzy=true", body.Videos, "video0")
+
+		rec := s.do(http.MethodGet, "/videos?limit=many", "")
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("GET /videos?limit=many = %d, want %d", rec.Code, http.StatusBadRequest)
+		}
+	})
+}
+
+func TestSearchSuggestions(t *testing.T) {
+	forEachStore(t, func(t *testing.T, s *testServer) {
+		s.save(testVideos("Cats at play")...)
+
+		var body videosBody
+		s.get("/videos?search=cat+naps", &body)
+		expectIDs(t, "GET /videos?search=cat+naps", body.Videos)
+		if want := []string{"Cats at play"}; !reflect.DeepEqual(body.Suggestions, want) {
+			t.Errorf("GET /videos?search=cat+naps suggested %q, want %q", body.Suggestions, want)
+		}
+	})
+}
+
+func TestAdvancedSearch(t *testing.T) {
+	forEachStore(t, func(t *testing.T, s *testServer) {
+		s.save(testVideos("Cats at play", "Dogs at play", "Birds in flight")...)
+
+		var body videosBody
+		s.get("/videos_search?search=cats+dogs&facets=channel", &body)
+		ids := []string{}
+		for _, v := range body.Videos {
+			ids = append(ids, v.VideoId)
+		}
+		sort.Strings(ids)
+		if want := []string{"video0", "video1"}; !reflect.DeepEqual(ids, want) {
+			t.Errorf("GET /videos_search?search=cats+dogs = %v, want %v", ids, want)
+		}
+		channels := body.Facets[string(store.FacetChannel)]
+		if len(channels) != 1 || channels[0].Value != "channel" || channels[0].Count != 2 {
+			t.Errorf("channel facet = %+v, want 2 videos of channel", channels)
+		}
+
+		rec := s.do(http.MethodGet, "/videos_search", "")
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("GET /videos_search = %d, want %d", rec.Code, http.StatusBadRequest)
+		}
+	})
+}
+
+func TestGetAndHistory(t *testing.T) {
+	forEachStore(t, func(t *testing.T, s *testServer) {
+		videos := testVideos("Cats at play")
+		s.save(videos...)
+		videos[0].Title = "Cats at play, retitled"
+		s.save(videos...)
+
+		var video yt.Video
+		s.get("/videos/video0", &video)
+		if video.VideoId != "video0" || video.Title != "Cats at play, retitled" {
+			t.Errorf("GET /videos/video0 = %s %q, want video0 as retitled",
+				video.VideoId, video.Title)
+		}
+
+		var history struct {
+			Revisions []struct {
+				Title string `json:"title"`
+			} `json:"revisions"`
+		}
+		s.get("/videos/video0/history", &history)
+		var titles []string
+		for _, r := range history.Revisions {
+			titles = append(titles, r.Title)
+		}
+		want := []string{"Cats at play", "Cats at play, retitled"}
+		if !reflect.DeepEqual(titles, want) {
+			t.Errorf("GET /videos/video0/history titles = %q, want %q", titles, want)
+		}
+
+		for _, target := range []string{"/videos/video9", "/videos/video9/history"} {
+			if rec := s.do(http.MethodGet, target, ""); rec.Code != http.StatusNotFound {
+				t.Errorf("GET %s = %d, want %d", target, rec.Code, http.StatusNotFound)
+			}
+		}
+	})
+}
+
+func TestLookup(t *testing.T) {
+	forEachStore(t, func(t *testing.T, s *testServer) {
+		s.save(testVideos("Cats at play", "Dogs at play")...)
+
+		var body struct {
+			Videos  []yt.Video `json:"videos"`
+			Missing []string   `json:"missing"`
+		}
+		s.decode(http.MethodPost, "/videos/lookup",
+			`{"ids": ["video1", "video9", "video0", "video1"]}`, &body)
+		expectIDs(t, "POST /videos/lookup", body.Videos, "video1", "video0")
+		if want := []string{"video9"}; !reflect.DeepEqual(body.Missing, want) {
+			t.Errorf("POST /videos/lookup missing = %v, want %v", body.Missing, want)
+		}
+
+		rec := s.do(http.MethodPost, "/videos/lookup", `{"ids": []}`)
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("POST /videos/lookup without ids = %d, want %d",
+				rec.Code, http.StatusBadRequest)
+		}
+	})
+}
+
+func TestExport(t *testing.T) {
+	forEachStore(t, func(t *testing.T, s *testServer) {
+		videos := testVideos("Cats at play", "Dogs at play", "Cats asleep")
+		videos[1].ChannelId = "other"
+		s.save(videos[2])
+		s.save(videos[:2]...)
+
+		rec := s.do(http.MethodGet, "/export?channel=channel", "")
+		if rec.Code != http.StatusOK {
+			t.Fatalf("GET /export = %d, want %d", rec.Code, http.StatusOK)
+		}
+		var exported []yt.Video
+		scanner := bufio.NewScanner(rec.Body)
+		for scanner.Scan() {
+			var v yt.Video
+			if err := json.Unmarshal(scanner.Bytes(), &v); err != nil {
+				t.Fatalf("decode an exported video: %v", err)
+			}
+			exported = append(exported, v)
+		}
+		// Oldest first, as first seen
+		expectIDs(t, "GET /export?channel=channel", exported, "video2", "video0")
+	})
+}
+
+func TestSavedSearchNew(t *testing.T) {
+	forEachStore(t, func(t *testing.T, s *testServer) {
+		var search struct {
+			ID uint `json:"id"`
+		}
+		s.decode(http.MethodPost, "/saved_searches", `{"name": "cats", "search": "cats"}`,
+			&search)
+		// Searches are created checked, so only videos first seen since are new
+		time.Sleep(10 * time.Millisecond)
+		s.save(testVideos("Cats at play", "Dogs at play")...)
+		target := fmt.Sprintf("/saved_searches/%d/new", search.ID)
+
+		var body videosBody
+		s.get(target, &body)
+		expectIDs(t, "GET "+target, body.Videos, "video0")
+
+		body = videosBody{}
+		s.get(target, &body)
+		expectIDs(t, "GET "+target+" again", body.Videos)
+
+		rec := s.do(http.MethodGet, "/saved_searches/99/new", "")
+		if rec.Code != http.StatusNotFound {
+			t.Errorf("GET /saved_searches/99/new = %d, want %d", rec.Code, http.StatusNotFound)
+		}
+	})
+}
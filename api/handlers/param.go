@@ -2,11 +2,21 @@ package handlers
 
 import (
 	"fmt"
+	"github.com/ditsuke/youtube-focus/internal/yt"
+	"github.com/ditsuke/youtube-focus/store"
 	"net/url"
 	"strconv"
+	"strings"
 	"time"
 )
 
+// videoTypes maps values of ParamType to the live broadcast content of matching videos.
+var videoTypes = map[string]string{
+	TypeUpload:   yt.BroadcastNone,
+	TypeLive:     yt.BroadcastLive,
+	TypeUpcoming: yt.BroadcastUpcoming,
+}
+
 func getPaginationParams(query url.Values) (time.Time, int, error) {
 	markerUnix, err := parseParam(query, ParamFrom, time.Now().Unix())
 	if err != nil {
@@ -27,10 +37,140 @@ func getPaginationParams(query url.Values) (time.Time, int, error) {
 	return from, limit, nil
 }
 
+// ParseFilterParams parses and validates the video filters in a query.
+func ParseFilterParams(query url.Values) (store.Filter, error) {
+	var f store.Filter
+	var err error
+
+	invalid := func(param string) error {
+		return fmt.Errorf("invalid %s param", param)
+	}
+
+	if f.PublishedAfter, err = parseParam(query, ParamAfter, time.Time{}); err != nil {
+		return f, invalid(ParamAfter)
+	}
+	if f.PublishedBefore, err = parseParam(query, ParamBefore, time.Time{}); err != nil {
+		return f, invalid(ParamBefore)
+	}
+	if !f.PublishedAfter.IsZero() && !f.PublishedBefore.IsZero() &&
+		!f.PublishedAfter.Before(f.PublishedBefore) {
+		return f, fmt.Errorf("%s must be before %s", ParamAfter, ParamBefore)
+	}
+
+	minDuration, err := parseParam(query, ParamMinDuration, 0)
+	if err != nil || minDuration < 0 {
+		return f, invalid(ParamMinDuration)
+	}
+	maxDuration, err := parseParam(query, ParamMaxDuration, 0)
+	if err != nil || maxDuration < 0 {
+		return f, invalid(ParamMaxDuration)
+	}
+	if maxDuration > 0 && minDuration > maxDuration {
+		return f, fmt.Errorf("%s exceeds %s", ParamMinDuration, ParamMaxDuration)
+	}
+	f.MinDuration = time.Duration(minDuration) * time.Second
+	f.MaxDuration = time.Duration(maxDuration) * time.Second
+
+	if f.MinViews, err = parseParam(query, ParamMinViews, int64(0)); err != nil ||
+		f.MinViews < 0 {
+		return f, invalid(ParamMinViews)
+	}
+	if f.MaxViews, err = parseParam(query, ParamMaxViews, int64(0)); err != nil ||
+		f.MaxViews < 0 {
+		return f, invalid(ParamMaxViews)
+	}
+	if f.MaxViews > 0 && f.MinViews > f.MaxViews {
+		return f, fmt.Errorf("%s exceeds %s", ParamMinViews, ParamMaxViews)
+	}
+
+	videoType, _ := parseParam(query, ParamType, "")
+	if videoType != "" {
+		var ok bool
+		if f.LiveBroadcastContent, ok = videoTypes[videoType]; !ok {
+			return f, invalid(ParamType)
+		}
+	}
+
+	f.ChannelId, _ = parseParam(query, ParamChannel, "")
+	f.Language, _ = parseParam(query, ParamLanguage, "")
+	f.CategoryId, _ = parseParam(query, ParamCategory, "")
+
+	f.Removed, _ = parseParam(query, ParamRemoved, "")
+	switch f.Removed {
+	case store.RemovedExclude:
+		// as when left out
+		f.Removed = ""
+	case "", store.RemovedInclude, store.RemovedOnly:
+	default:
+		return f, invalid(ParamRemoved)
+	}
+
+	return f, nil
+}
+
+// FilterParams describes a filter by the query parameters that would produce it, as
+// returned by ParseFilterParams.
+func FilterParams(f store.Filter) map[string]string {
+	params := map[string]string{}
+	setTime := func(param string, t time.Time) {
+		if !t.IsZero() {
+			params[param] = t.Format(QueryTimeFmt)
+		}
+	}
+	setInt := func(param string, n int64) {
+		if n > 0 {
+			params[param] = strconv.FormatInt(n, 10)
+		}
+	}
+	setString := func(param, s string) {
+		if s != "" {
+			params[param] = s
+		}
+	}
+
+	setTime(ParamAfter, f.PublishedAfter)
+	setTime(ParamBefore, f.PublishedBefore)
+	setString(ParamChannel, f.ChannelId)
+	setInt(ParamMinDuration, int64(f.MinDuration.Seconds()))
+	setInt(ParamMaxDuration, int64(f.MaxDuration.Seconds()))
+	setInt(ParamMinViews, f.MinViews)
+	setInt(ParamMaxViews, f.MaxViews)
+	for videoType, content := range videoTypes {
+		if content == f.LiveBroadcastContent {
+			params[ParamType] = videoType
+		}
+	}
+	setString(ParamLanguage, f.Language)
+	setString(ParamCategory, f.CategoryId)
+	setString(ParamRemoved, f.Removed)
+
+	return params
+}
+
+// getFacetParams parses the list of facets requested in a query.
+func getFacetParams(query url.Values) ([]store.Facet, error) {
+	list, _ := parseParam(query, ParamFacets, "")
+	if list == "" {
+		return nil, nil
+	}
+
+	names := strings.Split(list, ",")
+	facets := make([]store.Facet, 0, len(names))
+	for _, name := range names {
+		f := store.Facet(strings.TrimSpace(name))
+		if !f.IsValid() {
+			return nil, fmt.Errorf("invalid %s param: unknown facet %q", ParamFacets, name)
+		}
+		facets = append(facets, f)
+	}
+
+	return facets, nil
+}
+
 // parseParam is a generic function that parses typed parameters from a url.Values instance.
 // The second value is non-nil on failure to parse when the key exists.
 // If the key does not exist, it returns the def default value.
-func parseParam[T int | int64 | time.Time | string](query url.Values, param string, def T) (T,
+func parseParam[T int | int64 | float64 | bool | time.Time | string](query url.Values, param string, def T) (T,
 	error,
 ) {
 	if !query.Has(param) {
@@ -49,6 +189,10 @@ func parseParam[T int | int64 | time.Time | string](query url.Values, param stri
 		*t, err = time.Parse(QueryTimeFmt, v)
 	case *int64:
 		*t, err = strconv.ParseInt(v, 10, 64)
+	case *float64:
+		*t, err = strconv.ParseFloat(v, 64)
+	case *bool:
+		*t, err = strconv.ParseBool(v)
 	}
 
 	return ret, err
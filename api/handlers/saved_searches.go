@@ -0,0 +1,203 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"github.com/ditsuke/youtube-focus/api/response"
+	"github.com/ditsuke/youtube-focus/internal/yt"
+	"github.com/ditsuke/youtube-focus/store"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// ParamSavedSearchID is the URL parameter identifying a saved search.
+const ParamSavedSearchID = "id"
+
+// SavedSearchHandler provides HTTP handlers for the saved searches API.
+type SavedSearchHandler struct {
+	searches store.SavedSearchStore
+	videos   store.VideoStore
+}
+
+// NewSavedSearchHandler returns a SavedSearchHandler that saves searches to the passed
+// store.SavedSearchStore, and runs them against the store.VideoStore.
+func NewSavedSearchHandler(searches store.SavedSearchStore,
+	videos store.VideoStore,
+) *SavedSearchHandler {
+	return &SavedSearchHandler{
+		searches: searches,
+		videos:   videos,
+	}
+}
+
+// SavedSearchRequest is the payload to save a search with.
+type SavedSearchRequest struct {
+	Name   string           `json:"name"`
+	Search string           `json:"search"`
+	Mode   store.SearchMode `json:"mode"`
+	// Filters holds video filters as they would be passed in a query to /videos.
+	Filters map[string]string `json:"filters"`
+	// RetentionDays is the number of days the videos matching the search are kept for, if
+	// other than the server's default.
+	RetentionDays int `json:"retention_days"`
+
+	filter store.Filter
+}
+
+// Bind validates the request and parses its filters.
+func (s *SavedSearchRequest) Bind(r *http.Request) error {
+	if len(strings.Fields(s.Search)) == 0 {
+		return fmt.Errorf("no `search` in saved search")
+	}
+	if s.Mode == "" {
+		s.Mode = store.ModeLike
+	}
+	if !s.Mode.IsValid() {
+		return fmt.Errorf("invalid mode %q", s.Mode)
+	}
+	if s.RetentionDays < 0 {
+		return fmt.Errorf("invalid retention_days %d", s.RetentionDays)
+	}
+
+	query := url.Values{}
+	for param, value := range s.Filters {
+		query.Set(param, value)
+	}
+
+	var err error
+	s.filter, err = ParseFilterParams(query)
+	return err
+}
+
+// Create saves a search.
+func (c *SavedSearchHandler) Create(w http.ResponseWriter, r *http.Request) {
+	req := &SavedSearchRequest{}
+	if err := render.Bind(r, req); err != nil {
+		_ = render.Render(w, r, response.ErrInvalidRequest(err))
+		return
+	}
+
+	search := store.SavedSearch{
+		Name:   req.Name,
+		Query:  req.Search,
+		Mode:   req.Mode,
+		Filter: req.filter,
+
+		RetentionDays: req.RetentionDays,
+	}
+	if err := c.searches.Create(&search); err != nil {
+		_ = render.Render(w, r, response.ErrStore(err))
+		return
+	}
+
+	_ = render.Render(w, r, response.NewSavedSearchResponse(search, FilterParams(search.Filter)))
+}
+
+// List all saved searches.
+func (c *SavedSearchHandler) List(w http.ResponseWriter, r *http.Request) {
+	searches, err := c.searches.List()
+	if err != nil {
+		_ = render.Render(w, r, response.ErrStore(err))
+		return
+	}
+
+	resp := &response.SavedSearchesResponse{
+		SavedSearches: make([]*response.SavedSearchResponse, len(searches)),
+	}
+	for i := range searches {
+		resp.SavedSearches[i] = response.NewSavedSearchResponse(searches[i],
+			FilterParams(searches[i].Filter))
+	}
+	_ = render.Render(w, r, resp)
+}
+
+// Get a saved search.
+func (c *SavedSearchHandler) Get(w http.ResponseWriter, r *http.Request) {
+	id, err := savedSearchID(r)
+	if err != nil {
+		_ = render.Render(w, r, response.ErrInvalidRequest(err))
+		return
+	}
+
+	search, err := c.searches.Get(id)
+	if err != nil {
+		renderSavedSearchErr(w, r, err)
+		return
+	}
+
+	_ = render.Render(w, r, response.NewSavedSearchResponse(search, FilterParams(search.Filter)))
+}
+
+// Delete a saved search.
+func (c *SavedSearchHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	id, err := savedSearchID(r)
+	if err != nil {
+		_ = render.Render(w, r, response.ErrInvalidRequest(err))
+		return
+	}
+
+	if err := c.searches.Delete(id); err != nil {
+		renderSavedSearchErr(w, r, err)
+		return
+	}
+
+	render.NoContent(w, r)
+}
+
+// New handles requests for the videos matching a saved search that are new since it was last
+// checked, and marks them read. Passing ParamFrom pages through the results of the last check
+// instead, without marking anything.
+func (c *SavedSearchHandler) New(w http.ResponseWriter, r *http.Request) {
+	id, err := savedSearchID(r)
+	if err != nil {
+		_ = render.Render(w, r, response.ErrInvalidRequest(err))
+		return
+	}
+
+	qParams := r.URL.Query()
+	from, limit, err := getPaginationParams(qParams)
+	if err != nil {
+		_ = render.Render(w, r, response.ErrInvalidRequest(err))
+		return
+	}
+
+	var videos []yt.Video
+	results := func(search store.SavedSearch) error {
+		videos, err = c.videos.SavedSearchResults(search, from, limit)
+		return err
+	}
+	if qParams.Has(ParamFrom) {
+		var search store.SavedSearch
+		if search, err = c.searches.Get(id); err == nil {
+			err = results(search)
+		}
+	} else {
+		// The marker only moves once the results are in
+		_, err = c.searches.Check(id, results)
+	}
+	if err != nil {
+		renderSavedSearchErr(w, r, err)
+		return
+	}
+	response.RenderVideos(w, r, response.NewVideosResponse(videos))
+}
+
+func savedSearchID(r *http.Request) (uint, error) {
+	id, err := strconv.ParseUint(chi.URLParam(r, ParamSavedSearchID), 10, 0)
+	if err != nil {
+		return 0, fmt.Errorf("invalid saved search id")
+	}
+	return uint(id), nil
+}
+
+func renderSavedSearchErr(w http.ResponseWriter, r *http.Request, err error) {
+	if errors.Is(err, store.ErrNotFound) {
+		_ = render.Render(w, r, response.ErrNotFound(fmt.Errorf("no such saved search")))
+		return
+	}
+	_ = render.Render(w, r, response.ErrStore(err))
+}
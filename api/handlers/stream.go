@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/ditsuke/youtube-focus/api/response"
+	"github.com/ditsuke/youtube-focus/internal/services"
+	"github.com/ditsuke/youtube-focus/internal/yt"
+	"github.com/go-chi/render"
+	"net/http"
+	"time"
+)
+
+const (
+	// EventVideo is the server-sent event carrying a new video.
+	EventVideo = "video"
+
+	// StreamBuffer is the number of batches of new videos buffered for each stream.
+	StreamBuffer = 8
+
+	// StreamKeepAlive is the interval between comments sent to keep idle streams open.
+	StreamKeepAlive = 30 * time.Second
+)
+
+// StreamHandler provides the HTTP handler streaming videos as they are stored.
+type StreamHandler struct {
+	newVideos *services.Broadcaster[[]yt.Video]
+}
+
+// NewStreamHandler returns a StreamHandler streaming the videos broadcast by newVideos.
+func NewStreamHandler(newVideos *services.Broadcaster[[]yt.Video]) *StreamHandler {
+	return &StreamHandler{newVideos: newVideos}
+}
+
+// Stream handles requests to follow new videos, as server-sent EventVideo events of videos in
+// the requested response.Shape, until either end hangs up.
+func (c *StreamHandler) Stream(w http.ResponseWriter, r *http.Request) {
+	shape, err := response.ParseShape(r)
+	if err != nil {
+		_ = render.Render(w, r, response.ErrInvalidRequest(err))
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if c.newVideos == nil || !ok {
+		_ = render.Render(w, r, response.ErrInternal(fmt.Errorf("streaming unavailable")))
+		return
+	}
+
+	batches := c.newVideos.Subscribe(r.Context(), StreamBuffer)
+	keepAlive := time.NewTicker(StreamKeepAlive)
+	defer keepAlive.Stop()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	for {
+		select {
+		case batch, ok := <-batches:
+			if !ok {
+				return
+			}
+			for i := range batch {
+				data, err := json.Marshal(shape.Video(&response.Video{Video: batch[i]}))
+				if err != nil {
+					return
+				}
+				if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", EventVideo, data); err != nil {
+					return
+				}
+			}
+			flusher.Flush()
+		case <-keepAlive.C:
+			if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
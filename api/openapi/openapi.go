@@ -0,0 +1,194 @@
+// Package openapi serves the OpenAPI 3 document describing the REST API, and checks it
+// against the routes actually registered, and the parameters they read.
+package openapi
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"github.com/go-chi/chi/v5"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// Spec is the OpenAPI document for the API.
+//
+//go:embed openapi.json
+var Spec []byte
+
+// methods are the keys of an OpenAPI path item that describe operations.
+var methods = map[string]bool{
+	"get": true, "put": true, "post": true, "delete": true,
+	"options": true, "head": true, "patch": true, "trace": true,
+}
+
+// ServeSpec is an http.HandlerFunc responding with the Spec.
+func ServeSpec(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(Spec)
+}
+
+// Validate checks that the operations in the Spec are exactly those routed by routes under
+// prefix (the Spec's server URL), and that each declares the parameters of its path, and the
+// query parameters listed for it in queryParams, by operation (eg: "GET /videos"). It returns an
+// error listing the differences if not.
+func Validate(routes chi.Routes, prefix string, queryParams map[string][]string) error {
+	documented, err := specOperations()
+	if err != nil {
+		return err
+	}
+
+	routed := map[string]bool{}
+	err = chi.Walk(routes, func(method, route string, _ http.Handler,
+		_ ...func(http.Handler) http.Handler,
+	) error {
+		if !strings.HasPrefix(route, prefix+"/") {
+			return nil
+		}
+		route = strings.TrimPrefix(route, prefix)
+		if len(route) > 1 {
+			route = strings.TrimSuffix(route, "/")
+		}
+		routed[operation(method, route)] = true
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	var diff []string
+	for op := range routed {
+		if _, ok := documented[op]; !ok {
+			diff = append(diff, "undocumented: "+op)
+		}
+	}
+	for op, params := range documented {
+		if !routed[op] {
+			diff = append(diff, "not routed: "+op)
+			continue
+		}
+		diff = append(diff, paramsDiff(op, params, queryParams[op])...)
+	}
+	for op := range queryParams {
+		if _, ok := documented[op]; !ok {
+			diff = append(diff, "parameters of an unknown operation: "+op)
+		}
+	}
+	if len(diff) > 0 {
+		sort.Strings(diff)
+		return fmt.Errorf("api routes and openapi spec diverge: %s", strings.Join(diff, "; "))
+	}
+
+	return nil
+}
+
+// parameter is a parameter of an operation.
+type parameter struct {
+	Ref      string `json:"$ref"`
+	Name     string `json:"name"`
+	In       string `json:"in"`
+	Required bool   `json:"required"`
+}
+
+// paramsDiff lists the differences between the parameters declared by an operation, and those
+// of its path and the query parameters read.
+func paramsDiff(op string, declared []parameter, query []string) []string {
+	var diff []string
+	want := map[string]bool{}
+	for _, name := range query {
+		want["query "+name] = true
+	}
+	// Path parameters are those named in braces, eg: {videoId}
+	for _, segment := range strings.Split(op, "/") {
+		if strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}") {
+			want["path "+strings.Trim(segment, "{}")] = true
+		}
+	}
+
+	got := map[string]bool{}
+	for _, p := range declared {
+		param := p.In + " " + p.Name
+		switch {
+		case p.In == "header" || p.In == "cookie":
+			// Headers are served by middleware, eg: the cache's If-None-Match
+			continue
+		case p.In == "path" && !p.Required:
+			diff = append(diff, fmt.Sprintf("optional path parameter: %s %s", op, p.Name))
+		case !want[param]:
+			diff = append(diff, fmt.Sprintf("unread parameter: %s %s", op, param))
+		}
+		got[param] = true
+	}
+	for param := range want {
+		if !got[param] {
+			diff = append(diff, fmt.Sprintf("undocumented parameter: %s %s", op, param))
+		}
+	}
+	return diff
+}
+
+// specOperations returns the operations in the Spec, mapped to their parameters, including
+// those shared by their paths.
+func specOperations() (map[string][]parameter, error) {
+	var doc struct {
+		Paths      map[string]map[string]json.RawMessage `json:"paths"`
+		Components struct {
+			Parameters map[string]parameter `json:"parameters"`
+		} `json:"components"`
+	}
+	if err := json.Unmarshal(Spec, &doc); err != nil {
+		return nil, fmt.Errorf("parse openapi spec: %w", err)
+	}
+
+	// resolve replaces references to parameters with the parameters
+	const refPrefix = "#/components/parameters/"
+	resolve := func(params []parameter) error {
+		for i, p := range params {
+			if p.Ref == "" {
+				continue
+			}
+			ref, ok := doc.Components.Parameters[strings.TrimPrefix(p.Ref, refPrefix)]
+			if !ok || !strings.HasPrefix(p.Ref, refPrefix) {
+				return fmt.Errorf("unknown parameter %s", p.Ref)
+			}
+			params[i] = ref
+		}
+		return nil
+	}
+
+	ops := map[string][]parameter{}
+	for path, item := range doc.Paths {
+		var shared []parameter
+		if raw, ok := item["parameters"]; ok {
+			if err := json.Unmarshal(raw, &shared); err != nil {
+				return nil, fmt.Errorf("parse openapi spec: %s: %w", path, err)
+			}
+			if err := resolve(shared); err != nil {
+				return nil, fmt.Errorf("parse openapi spec: %s: %w", path, err)
+			}
+		}
+		for key, raw := range item {
+			if !methods[key] {
+				continue
+			}
+			var op struct {
+				Parameters []parameter `json:"parameters"`
+			}
+			if err := json.Unmarshal(raw, &op); err != nil {
+				return nil, fmt.Errorf("parse openapi spec: %s %s: %w", key, path, err)
+			}
+			if err := resolve(op.Parameters); err != nil {
+				return nil, fmt.Errorf("parse openapi spec: %s %s: %w", key, path, err)
+			}
+			ops[operation(key, path)] = append(append([]parameter(nil), shared...),
+				op.Parameters...)
+		}
+	}
+
+	return ops, nil
+}
+
+func operation(method, path string) string {
+	return strings.ToUpper(method) + " " + path
+}
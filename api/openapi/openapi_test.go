@@ -0,0 +1,131 @@
+package openapi
+
+import (
+	"github.com/go-chi/chi/v5"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// testSpec documents a few operations, with parameters declared inline, shared by their path
+// or referenced.
+const testSpec = `{
+  "paths": {
+    "/items": {
+      "get": {
+        "parameters": [
+          {"$ref": "#/components/parameters/limit"},
+          {"name": "q", "in": "query"},
+          {"name": "If-None-Match", "in": "header"}
+        ]
+      }
+    },
+    "/items/{id}": {
+      "parameters": [{"$ref": "#/components/parameters/id"}],
+      "get": {},
+      "delete": {"parameters": [{"name": "force", "in": "query"}]}
+    },
+    "/items/{id}/parts/{part}": {
+      "get": {"parameters": [{"name": "id", "in": "path", "required": true}]}
+    }
+  },
+  "components": {
+    "parameters": {
+      "limit": {"name": "limit", "in": "query"},
+      "id": {"name": "id", "in": "path", "required": true}
+    }
+  }
+}`
+
+// withSpec replaces the Spec for the duration of a test.
+func withSpec(t *testing.T, spec string) {
+	t.Helper()
+	original := Spec
+	Spec = []byte(spec)
+	t.Cleanup(func() { Spec = original })
+}
+
+func testRoutes() chi.Routes {
+	m := chi.NewRouter()
+	handler := func(http.ResponseWriter, *http.Request) {}
+	m.Route("/v1", func(r chi.Router) {
+		r.Get("/items", handler)
+		r.Get("/items/{id}", handler)
+		r.Delete("/items/{id}", handler)
+		r.Get("/items/{id}/parts/{part}", handler)
+	})
+	return m
+}
+
+func TestValidateParameters(t *testing.T) {
+	withSpec(t, testSpec)
+
+	err := Validate(testRoutes(), "/v1", map[string][]string{
+		"GET /items":                   {"limit", "q"},
+		"DELETE /items/{id}":           {"force"},
+		"GET /items/{id}/parts/{part}": {"depth"},
+		"GET /parts":                   {"limit"},
+	})
+	if err == nil {
+		t.Fatalf("Validate succeeded, want the differences")
+	}
+	for _, want := range []string{
+		"undocumented parameter: GET /items/{id}/parts/{part} path part",
+		"undocumented parameter: GET /items/{id}/parts/{part} query depth",
+		"parameters of an unknown operation: GET /parts",
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Validate = %v, want %q", err, want)
+		}
+	}
+	if n := strings.Count(err.Error(), ";") + 1; n != 3 {
+		t.Errorf("Validate reported %d differences, want 3: %v", n, err)
+	}
+
+	err = Validate(testRoutes(), "/v1", map[string][]string{"GET /items": {"limit"}})
+	for _, want := range []string{
+		"unread parameter: GET /items query q",
+		"unread parameter: DELETE /items/{id} query force",
+	} {
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("Validate = %v, want %q", err, want)
+		}
+	}
+}
+
+func TestValidateMatchingParameters(t *testing.T) {
+	withSpec(t, strings.Replace(testSpec,
+		`{"name": "id", "in": "path", "required": true}`,
+		`{"$ref": "#/components/parameters/id"}, {"name": "part", "in": "path", "required": true}`,
+		1))
+
+	err := Validate(testRoutes(), "/v1", map[string][]string{
+		"GET /items":         {"limit", "q"},
+		"DELETE /items/{id}": {"force"},
+	})
+	if err != nil {
+		t.Errorf("Validate: %v", err)
+	}
+}
+
+func TestValidateInvalidParameters(t *testing.T) {
+	for _, tt := range []struct {
+		name, from, to, want string
+	}{
+		{"unknown reference", `"#/components/parameters/limit"`, `"#/components/parameters/nope"`,
+			"unknown parameter #/components/parameters/nope"},
+		{"optional path parameter", `"in": "path", "required": true}]}`, `"in": "path"}]}`,
+			"optional path parameter: GET /items/{id}/parts/{part} id"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			withSpec(t, strings.Replace(testSpec, tt.from, tt.to, 1))
+			err := Validate(testRoutes(), "/v1", map[string][]string{
+				"GET /items":         {"limit", "q"},
+				"DELETE /items/{id}": {"force"},
+			})
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Validate = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
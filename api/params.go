@@ -0,0 +1,47 @@
+package api
+
+import (
+	"github.com/ditsuke/youtube-focus/api/handlers"
+	"github.com/ditsuke/youtube-focus/api/response"
+)
+
+// filterParams are the query parameters read by handlers.ParseFilterParams.
+var filterParams = []string{
+	handlers.ParamAfter, handlers.ParamBefore, handlers.ParamChannel,
+	handlers.ParamMinDuration, handlers.ParamMaxDuration,
+	handlers.ParamMinViews, handlers.ParamMaxViews,
+	handlers.ParamType, handlers.ParamLanguage, handlers.ParamCategory, handlers.ParamRemoved,
+}
+
+// shapeParams are the query parameters read by response.ParseShape.
+var shapeParams = []string{response.ParamFields, response.ParamExpand}
+
+// queryParams are the query parameters read by the handler of each operation, which the spec
+// must declare: see openapi.Validate. Operations left out read none.
+var queryParams = map[string][]string{
+	"GET /videos": params(
+		[]string{handlers.ParamFrom, handlers.ParamLimit, handlers.ParamSearch,
+			handlers.ParamFuzzy, response.ParamFormat},
+		filterParams, shapeParams),
+	"GET /videos/{videoId}": shapeParams,
+	"POST /videos/lookup":   shapeParams,
+	"GET /videos/stream":    shapeParams,
+	"GET /videos_search": params(
+		[]string{handlers.ParamLimit, handlers.ParamSearch, handlers.ParamFacets,
+			response.ParamFormat},
+		filterParams, shapeParams),
+	"GET /export": params([]string{response.ParamFormat}, filterParams, shapeParams),
+	"GET /saved_searches/{id}/new": params(
+		[]string{handlers.ParamFrom, handlers.ParamLimit, response.ParamFormat}, shapeParams),
+	"GET /admin/audit": {handlers.ParamFrom, handlers.ParamLimit},
+	"GET /graphql":     {"query", "operationName", "variables"},
+}
+
+// params concatenates lists of parameters.
+func params(lists ...[]string) []string {
+	var all []string
+	for _, list := range lists {
+		all = append(all, list...)
+	}
+	return all
+}
@@ -0,0 +1,50 @@
+package response
+
+import (
+	"github.com/ditsuke/youtube-focus/store"
+	"github.com/go-chi/render"
+	"net/http"
+	"time"
+)
+
+type APIKeyResponse struct {
+	ID        uint       `json:"id"`
+	Name      string     `json:"name"`
+	Prefix    string     `json:"prefix"`
+	RateLimit int        `json:"rate_limit,omitempty"`
+	Role      store.Role `json:"role"`
+	CreatedAt time.Time  `json:"created_at"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+	// Key is the secret API key, only ever included in the response to its issue.
+	Key string `json:"key,omitempty"`
+}
+
+func NewAPIKeyResponse(k store.APIKey) *APIKeyResponse {
+	return &APIKeyResponse{
+		ID:        k.ID,
+		Name:      k.Name,
+		Prefix:    k.Prefix,
+		RateLimit: k.RateLimit,
+		Role:      k.Role,
+		CreatedAt: k.CreatedAt,
+		RevokedAt: k.RevokedAt,
+	}
+}
+
+func (k *APIKeyResponse) Render(w http.ResponseWriter, r *http.Request) error {
+	if r.Method == http.MethodPost {
+		render.Status(r, http.StatusCreated)
+		return nil
+	}
+	render.Status(r, http.StatusOK)
+	return nil
+}
+
+type APIKeysResponse struct {
+	Keys []*APIKeyResponse `json:"keys"`
+}
+
+func (k *APIKeysResponse) Render(w http.ResponseWriter, r *http.Request) error {
+	render.Status(r, http.StatusOK)
+	return nil
+}
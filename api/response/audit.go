@@ -0,0 +1,41 @@
+package response
+
+import (
+	"github.com/ditsuke/youtube-focus/store"
+	"github.com/go-chi/render"
+	"net/http"
+	"time"
+)
+
+type AuditEntryResponse struct {
+	ID         uint      `json:"id"`
+	CreatedAt  time.Time `json:"created_at"`
+	ActorKeyID *uint     `json:"actor_key_id,omitempty"`
+	Actor      string    `json:"actor"`
+	Action     string    `json:"action"`
+	Target     string    `json:"target"`
+	Detail     string    `json:"detail,omitempty"`
+	Status     int       `json:"status"`
+}
+
+type AuditLogResponse struct {
+	Entries []AuditEntryResponse `json:"entries"`
+	// Next is the ID to pass as the `from` query parameter to get the next page.
+	Next uint `json:"next"`
+}
+
+func NewAuditLogResponse(entries []store.AuditEntry) *AuditLogResponse {
+	resp := &AuditLogResponse{Entries: make([]AuditEntryResponse, len(entries))}
+	for i, e := range entries {
+		resp.Entries[i] = AuditEntryResponse(e)
+	}
+	if len(entries) > 0 {
+		resp.Next = entries[len(entries)-1].ID
+	}
+	return resp
+}
+
+func (a *AuditLogResponse) Render(w http.ResponseWriter, r *http.Request) error {
+	render.Status(r, http.StatusOK)
+	return nil
+}
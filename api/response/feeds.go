@@ -0,0 +1,141 @@
+package response
+
+import (
+	"encoding/xml"
+	"github.com/ditsuke/youtube-focus/internal/yt"
+	"io"
+	"net/http"
+	"time"
+)
+
+const (
+	feedTitle       = "YouTube Focus"
+	watchURLPrefix  = "https://www.youtube.com/watch?v="
+	atomNamespace   = "http://www.w3.org/2005/Atom"
+	searchQueryName = "search"
+)
+
+type rss struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title       string    `xml:"title"`
+	Link        string    `xml:"link"`
+	Description string    `xml:"description"`
+	Items       []rssItem `xml:"item"`
+}
+
+type rssItem struct {
+	Title       string `xml:"title"`
+	Link        string `xml:"link"`
+	Description string `xml:"description"`
+	PubDate     string `xml:"pubDate"`
+	GUID        string `xml:"guid"`
+}
+
+type atom struct {
+	XMLName xml.Name    `xml:"feed"`
+	NS      string      `xml:"xmlns,attr"`
+	ID      string      `xml:"id"`
+	Title   string      `xml:"title"`
+	Updated string      `xml:"updated"`
+	Link    atomLink    `xml:"link"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr,omitempty"`
+}
+
+type atomEntry struct {
+	ID        string   `xml:"id"`
+	Title     string   `xml:"title"`
+	Link      atomLink `xml:"link"`
+	Published string   `xml:"published"`
+	Updated   string   `xml:"updated"`
+	Summary   string   `xml:"summary"`
+	Author    struct {
+		Name string `xml:"name"`
+	} `xml:"author"`
+}
+
+// feedInfo returns the title and URL of the feed for a request. Feeds are titled by the
+// search query they are for, if any.
+func feedInfo(r *http.Request) (string, string) {
+	title := feedTitle
+	if q := r.URL.Query().Get(searchQueryName); q != "" {
+		title += ": " + q
+	}
+
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	return title, scheme + "://" + r.Host + r.URL.RequestURI()
+}
+
+func newRSS(r *http.Request, videos []yt.Video) *rss {
+	title, link := feedInfo(r)
+	feed := &rss{
+		Version: "2.0",
+		Channel: rssChannel{
+			Title:       title,
+			Link:        link,
+			Description: title,
+			Items:       make([]rssItem, len(videos)),
+		},
+	}
+	for i, v := range videos {
+		feed.Channel.Items[i] = rssItem{
+			Title:       v.Title,
+			Link:        watchURLPrefix + v.VideoId,
+			Description: v.Description,
+			PubDate:     v.PublishedAt.Format(time.RFC1123Z),
+			GUID:        watchURLPrefix + v.VideoId,
+		}
+	}
+	return feed
+}
+
+func newAtom(r *http.Request, videos []yt.Video) *atom {
+	title, link := feedInfo(r)
+	feed := &atom{
+		NS:      atomNamespace,
+		ID:      link,
+		Title:   title,
+		Updated: time.Now().UTC().Format(time.RFC3339),
+		Link:    atomLink{Href: link, Rel: "self"},
+		Entries: make([]atomEntry, len(videos)),
+	}
+	if len(videos) > 0 {
+		// Videos are latest first
+		feed.Updated = videos[0].PublishedAt.UTC().Format(time.RFC3339)
+	}
+	for i, v := range videos {
+		published := v.PublishedAt.UTC().Format(time.RFC3339)
+		e := atomEntry{
+			ID:        "yt:video:" + v.VideoId,
+			Title:     v.Title,
+			Link:      atomLink{Href: watchURLPrefix + v.VideoId},
+			Published: published,
+			Updated:   published,
+			Summary:   v.Description,
+		}
+		e.Author.Name = v.ChannelTitle
+		feed.Entries[i] = e
+	}
+	return feed
+}
+
+func writeFeed(w io.Writer, feed any) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	return enc.Encode(feed)
+}
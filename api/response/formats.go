@@ -0,0 +1,214 @@
+package response
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"github.com/ditsuke/youtube-focus/internal/yt"
+	"github.com/go-chi/render"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ParamFormat is the query parameter selecting the format of a response. It takes precedence
+// over the Accept header.
+const ParamFormat = "format"
+
+// Formats videos can be rendered in.
+const (
+	FormatJSON   = "json"
+	FormatCSV    = "csv"
+	FormatNDJSON = "ndjson"
+	FormatRSS    = "rss"
+	FormatAtom   = "atom"
+)
+
+// formatTypes maps formats to their media types.
+var formatTypes = map[string]string{
+	FormatJSON:   "application/json",
+	FormatCSV:    "text/csv",
+	FormatNDJSON: "application/x-ndjson",
+	FormatRSS:    "application/rss+xml",
+	FormatAtom:   "application/atom+xml",
+}
+
+// videoColumns are the columns of videos in tabular formats, along with their values.
+var videoColumns = []struct {
+	name  string
+	value func(v *yt.Video) string
+}{
+	{"id", func(v *yt.Video) string { return strconv.FormatUint(uint64(v.ID), 10) }},
+	{"video_id", func(v *yt.Video) string { return v.VideoId }},
+	{"title", func(v *yt.Video) string { return v.Title }},
+	{"description", func(v *yt.Video) string { return v.Description }},
+	{"published_at", func(v *yt.Video) string { return v.PublishedAt.Format(time.RFC3339) }},
+	{"first_seen_at", func(v *yt.Video) string { return v.CreatedAt.Format(time.RFC3339) }},
+	{"thumbnail_url", func(v *yt.Video) string { return v.ThumbnailUrl }},
+	{"channel_id", func(v *yt.Video) string { return v.ChannelId }},
+	{"channel_title", func(v *yt.Video) string { return v.ChannelTitle }},
+	{"live_broadcast_content", func(v *yt.Video) string { return v.LiveBroadcastContent }},
+	{"duration_seconds", func(v *yt.Video) string { return itoa(v.DurationSeconds) }},
+	{"view_count", func(v *yt.Video) string { return itoa(v.ViewCount) }},
+	{"like_count", func(v *yt.Video) string { return itoa(v.LikeCount) }},
+	{"comment_count", func(v *yt.Video) string { return itoa(v.CommentCount) }},
+	{"default_language", func(v *yt.Video) string { return v.DefaultLanguage }},
+	{"category_id", func(v *yt.Video) string { return v.CategoryId }},
+	{"removed_at", func(v *yt.Video) string {
+		if !v.DeletedAt.Valid {
+			return ""
+		}
+		return v.DeletedAt.Time.Format(time.RFC3339)
+	}},
+	{"removal_status", func(v *yt.Video) string { return v.RemovalStatus }},
+}
+
+// NegotiateFormat returns the format a request asks for, by ParamFormat or its Accept header,
+// from those allowed. It defaults to FormatJSON, and fails if ParamFormat is not allowed.
+func NegotiateFormat(r *http.Request, allowed ...string) (string, error) {
+	isAllowed := func(format string) bool {
+		for _, a := range allowed {
+			if a == format {
+				return true
+			}
+		}
+		return false
+	}
+
+	if format := r.URL.Query().Get(ParamFormat); format != "" {
+		if !isAllowed(format) {
+			return "", fmt.Errorf("invalid %s param, must be one of %s",
+				ParamFormat, strings.Join(allowed, ", "))
+		}
+		return format, nil
+	}
+
+	for _, accepted := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(accepted))
+		if err != nil {
+			continue
+		}
+		for format, formatType := range formatTypes {
+			if formatType == mediaType && isAllowed(format) {
+				return format, nil
+			}
+		}
+	}
+
+	return FormatJSON, nil
+}
+
+// RenderVideos renders a VideosResponse in the format a request asks for. Formats other than
+// JSON only carry the videos, with the next page linked in the Link header.
+func RenderVideos(w http.ResponseWriter, r *http.Request, v *VideosResponse) {
+	format, err := NegotiateFormat(r, FormatJSON, FormatCSV, FormatNDJSON, FormatRSS, FormatAtom)
+	if err != nil {
+		_ = render.Render(w, r, ErrInvalidRequest(err))
+		return
+	}
+	shape, err := ParseShape(r)
+	if err != nil {
+		_ = render.Render(w, r, ErrInvalidRequest(err))
+		return
+	}
+	if format == FormatJSON {
+		v.shape = shape
+		_ = render.Render(w, r, v)
+		return
+	}
+
+	if len(v.Videos) > 0 {
+		next := *r.URL
+		q := next.Query()
+		q.Set("from", strconv.FormatInt(v.Next, 10))
+		next.RawQuery = q.Encode()
+		w.Header().Set("Link", "<"+next.RequestURI()+`>; rel="next"`)
+	}
+	w.Header().Set("Content-Type", ContentType(format))
+
+	switch format {
+	case FormatRSS:
+		_ = writeFeed(w, newRSS(r, v.Videos))
+	case FormatAtom:
+		_ = writeFeed(w, newAtom(r, v.Videos))
+	default:
+		enc := NewVideoEncoder(format, w, shape)
+		for i := range v.Videos {
+			if err := enc.Encode(&v.Videos[i]); err != nil {
+				return
+			}
+		}
+		_ = enc.Flush()
+	}
+}
+
+// ContentType returns the Content-Type header for responses in a format.
+func ContentType(format string) string {
+	return formatTypes[format] + "; charset=utf-8"
+}
+
+// VideoEncoder writes videos one at a time, in a streamable format.
+type VideoEncoder interface {
+	Encode(v *yt.Video) error
+	// Flush writes out buffered videos, if any.
+	Flush() error
+}
+
+// NewVideoEncoder returns a VideoEncoder writing videos in the passed format to w. The format
+// must be one of FormatCSV or FormatNDJSON; others default to the latter. Videos are shaped in
+// FormatNDJSON only, as FormatCSV always has every column.
+func NewVideoEncoder(format string, w io.Writer, shape Shape) VideoEncoder {
+	if format == FormatCSV {
+		return &csvEncoder{w: csv.NewWriter(w)}
+	}
+	return ndjsonEncoder{json.NewEncoder(w), shape}
+}
+
+type ndjsonEncoder struct {
+	*json.Encoder
+	shape Shape
+}
+
+func (e ndjsonEncoder) Encode(v *yt.Video) error {
+	return e.Encoder.Encode(e.shape.Video(&Video{Video: *v}))
+}
+
+func (e ndjsonEncoder) Flush() error {
+	return nil
+}
+
+type csvEncoder struct {
+	w             *csv.Writer
+	headerWritten bool
+	record        []string
+}
+
+func (e *csvEncoder) Encode(v *yt.Video) error {
+	if !e.headerWritten {
+		e.record = make([]string, len(videoColumns))
+		for i, col := range videoColumns {
+			e.record[i] = col.name
+		}
+		if err := e.w.Write(e.record); err != nil {
+			return err
+		}
+		e.headerWritten = true
+	}
+
+	for i, col := range videoColumns {
+		e.record[i] = col.value(v)
+	}
+	return e.w.Write(e.record)
+}
+
+func (e *csvEncoder) Flush() error {
+	e.w.Flush()
+	return e.w.Error()
+}
+
+func itoa(n int64) string {
+	return strconv.FormatInt(n, 10)
+}
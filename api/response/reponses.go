@@ -1,8 +1,13 @@
 package response
 
 import (
+	"encoding/json"
+	"errors"
 	"github.com/ditsuke/youtube-focus/internal/yt"
+	"github.com/ditsuke/youtube-focus/store"
+	"github.com/go-chi/chi/v5/middleware"
 	"github.com/go-chi/render"
+	"github.com/rs/zerolog"
 	"net/http"
 )
 
@@ -11,10 +16,21 @@ type ErrResponse struct {
 	HttpStatusCode int    `json:"-"`
 	StatusText     string `json:"status"`
 	ErrorText      string `json:"error,omitempty"`
+	// RequestID identifies the request in the server's logs, for clients to quote.
+	RequestID string `json:"request_id,omitempty"`
 }
 
+// Render sets the status of the response, and tags it with the ID of the request. Errors on
+// our end are logged, as their responses do not tell.
 func (e *ErrResponse) Render(w http.ResponseWriter, r *http.Request) error {
 	render.Status(r, e.HttpStatusCode)
+	e.RequestID = middleware.GetReqID(r.Context())
+	if e.HttpStatusCode >= http.StatusInternalServerError {
+		zerolog.Ctx(r.Context()).Error().Err(e.Err).
+			Str("request_id", e.RequestID).
+			Int("status", e.HttpStatusCode).
+			Msg("request failed")
+	}
 	return nil
 }
 
@@ -27,9 +43,88 @@ func ErrInvalidRequest(err error) render.Renderer {
 	}
 }
 
+func ErrNotFound(err error) render.Renderer {
+	return &ErrResponse{
+		Err:            err,
+		HttpStatusCode: 404,
+		StatusText:     "not found",
+		ErrorText:      err.Error(),
+	}
+}
+
+func ErrUnauthorized(err error) render.Renderer {
+	return &ErrResponse{
+		Err:            err,
+		HttpStatusCode: 401,
+		StatusText:     "unauthorized",
+		ErrorText:      err.Error(),
+	}
+}
+
+func ErrForbidden(err error) render.Renderer {
+	return &ErrResponse{
+		Err:            err,
+		HttpStatusCode: 403,
+		StatusText:     "forbidden",
+		ErrorText:      err.Error(),
+	}
+}
+
+func ErrTooManyRequests(err error) render.Renderer {
+	return &ErrResponse{
+		Err:            err,
+		HttpStatusCode: 429,
+		StatusText:     "too many requests",
+		ErrorText:      err.Error(),
+	}
+}
+
+// ErrInternal is the response to a request that failed on our end. The underlying error is
+// not exposed.
+func ErrInternal(err error) render.Renderer {
+	return &ErrResponse{
+		Err:            err,
+		HttpStatusCode: 500,
+		StatusText:     "internal error",
+	}
+}
+
+// ErrUnavailable is the response to a request that failed as the store is unavailable, and
+// may succeed later. The underlying error is not exposed.
+func ErrUnavailable(err error) render.Renderer {
+	return &ErrResponse{
+		Err:            err,
+		HttpStatusCode: 503,
+		StatusText:     "unavailable",
+	}
+}
+
+// ErrStore is the response to a request that failed in a store, by the kind of the error.
+func ErrStore(err error) render.Renderer {
+	switch {
+	case errors.Is(err, store.ErrNotFound):
+		return ErrNotFound(err)
+	case errors.Is(err, store.ErrInvalidQuery):
+		return ErrInvalidRequest(err)
+	case errors.Is(err, store.ErrUnavailable):
+		return ErrUnavailable(err)
+	default:
+		return ErrInternal(err)
+	}
+}
+
 type VideosResponse struct {
 	Videos []yt.Video `json:"videos"`
 	Next   int64      `json:"next"`
+
+	// Suggestions holds "did you mean" alternatives for searches that matched nothing.
+	Suggestions []string `json:"suggestions,omitempty"`
+
+	// Facets holds counts of the matched videos by each requested facet.
+	Facets map[store.Facet][]store.FacetCount `json:"facets,omitempty"`
+
+	// shape is the shape of the videos, as requested.
+	shape Shape
 }
 
 func NewVideosResponse(videos []yt.Video) *VideosResponse {
@@ -47,3 +142,30 @@ func (v *VideosResponse) Render(w http.ResponseWriter, r *http.Request) error {
 	render.Status(r, http.StatusOK)
 	return nil
 }
+
+// MarshalJSON encodes the response with its videos shaped.
+func (v *VideosResponse) MarshalJSON() ([]byte, error) {
+	type plain VideosResponse
+	return json.Marshal(struct {
+		*plain
+		Videos []any `json:"videos"`
+	}{(*plain)(v), v.shape.Videos(v.Videos)})
+}
+
+// UnmarshalJSON decodes a response, folding the embedded objects of its videos into them.
+func (v *VideosResponse) UnmarshalJSON(data []byte) error {
+	type plain VideosResponse
+	decoded := struct {
+		*plain
+		Videos []Video `json:"videos"`
+	}{plain: (*plain)(v)}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return err
+	}
+
+	v.Videos = make([]yt.Video, len(decoded.Videos))
+	for i := range decoded.Videos {
+		v.Videos[i] = decoded.Videos[i].Video
+	}
+	return nil
+}
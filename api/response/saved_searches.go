@@ -0,0 +1,54 @@
+package response
+
+import (
+	"github.com/ditsuke/youtube-focus/store"
+	"github.com/go-chi/render"
+	"net/http"
+	"time"
+)
+
+type SavedSearchResponse struct {
+	ID        uint              `json:"id"`
+	Name      string            `json:"name"`
+	Search    string            `json:"search"`
+	Mode      store.SearchMode  `json:"mode"`
+	Filters   map[string]string `json:"filters"`
+	CreatedAt time.Time         `json:"created_at"`
+	CheckedAt time.Time         `json:"checked_at"`
+	// RetentionDays is zero when the server's default retention applies.
+	RetentionDays int `json:"retention_days"`
+}
+
+// NewSavedSearchResponse returns the response for a saved search, with its filters described
+// by query parameters.
+func NewSavedSearchResponse(s store.SavedSearch, filters map[string]string) *SavedSearchResponse {
+	return &SavedSearchResponse{
+		ID:        s.ID,
+		Name:      s.Name,
+		Search:    s.Query,
+		Mode:      s.Mode,
+		Filters:   filters,
+		CreatedAt: s.CreatedAt,
+		CheckedAt: s.CheckedAt,
+
+		RetentionDays: s.RetentionDays,
+	}
+}
+
+func (s *SavedSearchResponse) Render(w http.ResponseWriter, r *http.Request) error {
+	if r.Method == http.MethodPost {
+		render.Status(r, http.StatusCreated)
+		return nil
+	}
+	render.Status(r, http.StatusOK)
+	return nil
+}
+
+type SavedSearchesResponse struct {
+	SavedSearches []*SavedSearchResponse `json:"saved_searches"`
+}
+
+func (s *SavedSearchesResponse) Render(w http.ResponseWriter, r *http.Request) error {
+	render.Status(r, http.StatusOK)
+	return nil
+}
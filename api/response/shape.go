@@ -0,0 +1,188 @@
+package response
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/ditsuke/youtube-focus/internal/yt"
+	"net/http"
+	"reflect"
+	"strings"
+)
+
+const (
+	// ParamFields is the query parameter listing the fields of videos to respond with,
+	// comma-separated. All fields are included by default.
+	ParamFields = "fields"
+
+	// ParamExpand is the query parameter listing related objects to embed in videos,
+	// comma-separated: ExpandChannel and ExpandStats.
+	ParamExpand = "expand"
+
+	ExpandChannel = "channel"
+	ExpandStats   = "stats"
+)
+
+// Video is the representation of a yt.Video in responses, with related objects embedded on
+// request.
+type Video struct {
+	yt.Video
+	Channel *Channel `json:"channel,omitempty"`
+	Stats   *Stats   `json:"stats,omitempty"`
+}
+
+// UnmarshalJSON decodes a video, folding its embedded objects back into the yt.Video.
+func (v *Video) UnmarshalJSON(data []byte) error {
+	type plain Video
+	if err := json.Unmarshal(data, (*plain)(v)); err != nil {
+		return err
+	}
+
+	if v.Channel != nil {
+		v.ChannelTitle = v.Channel.Title
+	}
+	if v.Stats != nil {
+		v.ViewCount = v.Stats.ViewCount
+		v.LikeCount = v.Stats.LikeCount
+		v.CommentCount = v.Stats.CommentCount
+	}
+	return nil
+}
+
+type Channel struct {
+	ID    string `json:"id"`
+	Title string `json:"title"`
+}
+
+type Stats struct {
+	ViewCount    int64 `json:"view_count"`
+	LikeCount    int64 `json:"like_count"`
+	CommentCount int64 `json:"comment_count"`
+}
+
+// videoFields is the set of top-level JSON keys of a Video.
+var videoFields = jsonKeys(reflect.TypeOf(Video{}))
+
+// Shape is how videos are represented in a response: which of their fields are included, and
+// which related objects are embedded.
+type Shape struct {
+	// fields is the set of fields to include, nil for all of them.
+	fields map[string]bool
+	expand map[string]bool
+}
+
+// FullShape embeds every related object in videos.
+var FullShape = Shape{expand: map[string]bool{ExpandChannel: true, ExpandStats: true}}
+
+// ParseShape parses the shape of videos a request asks for with ParamFields and ParamExpand.
+// Selecting the field of a related object embeds it, without it having to be expanded too.
+func ParseShape(r *http.Request) (Shape, error) {
+	query := r.URL.Query()
+	s := Shape{expand: map[string]bool{}}
+
+	for _, name := range splitList(query.Get(ParamExpand)) {
+		if name != ExpandChannel && name != ExpandStats {
+			return s, fmt.Errorf("invalid %s param: unknown object %q", ParamExpand, name)
+		}
+		s.expand[name] = true
+	}
+
+	if !query.Has(ParamFields) {
+		return s, nil
+	}
+	s.fields = map[string]bool{}
+	for _, name := range splitList(query.Get(ParamFields)) {
+		if !videoFields[name] {
+			return s, fmt.Errorf("invalid %s param: unknown field %q", ParamFields, name)
+		}
+		s.fields[name] = true
+		if name == ExpandChannel || name == ExpandStats {
+			s.expand[name] = true
+		}
+	}
+	if len(s.fields) == 0 {
+		return s, fmt.Errorf("invalid %s param: no fields", ParamFields)
+	}
+
+	return s, nil
+}
+
+// IsZero reports whether the shape is the default one.
+func (s Shape) IsZero() bool {
+	return s.fields == nil && len(s.expand) == 0
+}
+
+// Video returns the representation of a video in this shape, to be encoded as JSON.
+func (s Shape) Video(v *Video) any {
+	if s.expand[ExpandChannel] {
+		v.Channel = &Channel{ID: v.ChannelId, Title: v.ChannelTitle}
+	}
+	if s.expand[ExpandStats] {
+		v.Stats = &Stats{
+			ViewCount:    v.ViewCount,
+			LikeCount:    v.LikeCount,
+			CommentCount: v.CommentCount,
+		}
+	}
+	if s.fields == nil {
+		return v
+	}
+
+	full, err := json.Marshal(v)
+	if err != nil {
+		return v
+	}
+	var all map[string]json.RawMessage
+	if err := json.Unmarshal(full, &all); err != nil {
+		return v
+	}
+
+	selected := make(map[string]json.RawMessage, len(s.fields))
+	for name := range s.fields {
+		if value, ok := all[name]; ok {
+			selected[name] = value
+		}
+	}
+	return selected
+}
+
+// Videos returns the representations of videos in this shape.
+func (s Shape) Videos(videos []yt.Video) []any {
+	shaped := make([]any, len(videos))
+	for i := range videos {
+		shaped[i] = s.Video(&Video{Video: videos[i]})
+	}
+	return shaped
+}
+
+// jsonKeys returns the JSON keys of the exported fields of a struct type, including those
+// of embedded structs.
+func jsonKeys(t reflect.Type) map[string]bool {
+	keys := map[string]bool{}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		switch {
+		case name == "-" || !f.IsExported():
+			continue
+		case f.Anonymous && name == "":
+			for k := range jsonKeys(f.Type) {
+				keys[k] = true
+			}
+		case name == "":
+			keys[f.Name] = true
+		default:
+			keys[name] = true
+		}
+	}
+	return keys
+}
+
+func splitList(list string) []string {
+	var items []string
+	for _, item := range strings.Split(list, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
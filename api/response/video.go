@@ -0,0 +1,119 @@
+package response
+
+import (
+	"encoding/json"
+	"github.com/ditsuke/youtube-focus/internal/yt"
+	"github.com/ditsuke/youtube-focus/store"
+	"github.com/go-chi/render"
+	"net/http"
+	"time"
+)
+
+// VideoResponse is the full record of a video.
+type VideoResponse struct {
+	Video
+	shape Shape
+}
+
+func NewVideoResponse(v yt.Video, shape Shape) *VideoResponse {
+	return &VideoResponse{Video: Video{Video: v}, shape: shape}
+}
+
+// MarshalJSON encodes the video in its shape.
+func (v *VideoResponse) MarshalJSON() ([]byte, error) {
+	return json.Marshal(v.shape.Video(&v.Video))
+}
+
+func (v *VideoResponse) Render(w http.ResponseWriter, r *http.Request) error {
+	render.Status(r, http.StatusOK)
+	return nil
+}
+
+type LookupResponse struct {
+	Videos []*VideoResponse `json:"videos"`
+	// Missing lists the requested IDs of videos that are not in the store.
+	Missing []string `json:"missing"`
+}
+
+// NewLookupResponse returns the response to a lookup of ids, ordering found videos as their
+// IDs were requested.
+func NewLookupResponse(ids []string, videos []yt.Video, shape Shape) *LookupResponse {
+	found := make(map[string]yt.Video, len(videos))
+	for _, v := range videos {
+		found[v.VideoId] = v
+	}
+
+	resp := &LookupResponse{
+		Videos:  make([]*VideoResponse, 0, len(videos)),
+		Missing: []string{},
+	}
+	answered := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		// duplicate IDs are only answered once
+		if answered[id] {
+			continue
+		}
+		answered[id] = true
+
+		v, ok := found[id]
+		if !ok {
+			resp.Missing = append(resp.Missing, id)
+			continue
+		}
+		resp.Videos = append(resp.Videos, NewVideoResponse(v, shape))
+	}
+
+	return resp
+}
+
+func (l *LookupResponse) Render(w http.ResponseWriter, r *http.Request) error {
+	render.Status(r, http.StatusOK)
+	return nil
+}
+
+// RevisionResponse is a version of the content of a video.
+type RevisionResponse struct {
+	Title        string    `json:"title"`
+	Description  string    `json:"description"`
+	ThumbnailUrl string    `json:"thumbnail_url"`
+	SeenAt       time.Time `json:"seen_at"`
+	// ReplacedAt is nil for the current version.
+	ReplacedAt *time.Time `json:"replaced_at"`
+}
+
+// HistoryResponse is the history of the content of a video.
+type HistoryResponse struct {
+	VideoId string `json:"video_id"`
+	// Revisions are the versions of the video, oldest first, ending with the current one.
+	Revisions []RevisionResponse `json:"revisions"`
+}
+
+// NewHistoryResponse returns the history of a video, given its prior versions, oldest first.
+func NewHistoryResponse(v yt.Video, revisions []store.VideoRevision) *HistoryResponse {
+	resp := &HistoryResponse{
+		VideoId:   v.VideoId,
+		Revisions: make([]RevisionResponse, 0, len(revisions)+1),
+	}
+	for i := range revisions {
+		r := &revisions[i]
+		resp.Revisions = append(resp.Revisions, RevisionResponse{
+			Title:        r.Title,
+			Description:  r.Description,
+			ThumbnailUrl: r.ThumbnailUrl,
+			SeenAt:       r.SeenAt,
+			ReplacedAt:   &r.ReplacedAt,
+		})
+	}
+	resp.Revisions = append(resp.Revisions, RevisionResponse{
+		Title:        v.Title,
+		Description:  v.Description,
+		ThumbnailUrl: v.ThumbnailUrl,
+		SeenAt:       v.UpdatedAt,
+	})
+	return resp
+}
+
+func (h *HistoryResponse) Render(w http.ResponseWriter, r *http.Request) error {
+	render.Status(r, http.StatusOK)
+	return nil
+}
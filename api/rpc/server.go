@@ -0,0 +1,351 @@
+// Package rpc serves the gRPC API, defined in proto/youtubefocus/v1/videos.proto, alongside the
+// REST API.
+package rpc
+
+import (
+	"context"
+	"errors"
+	"github.com/ditsuke/youtube-focus/api/auth"
+	"github.com/ditsuke/youtube-focus/api/handlers"
+	"github.com/ditsuke/youtube-focus/api/rpc/pb"
+	"github.com/ditsuke/youtube-focus/config"
+	"github.com/ditsuke/youtube-focus/internal/services"
+	"github.com/ditsuke/youtube-focus/internal/yt"
+	"github.com/ditsuke/youtube-focus/store"
+	"github.com/rs/zerolog"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"gorm.io/gorm"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// streamBuffer is the number of batches of new videos buffered for each stream.
+const streamBuffer = 8
+
+// Server serves the gRPC API on its own port.
+type Server struct {
+	pb.UnimplementedVideoServiceServer
+
+	Cfg    config.Config
+	Logger zerolog.Logger
+	// DB is the database API keys are read from, on its primary.
+	DB    *gorm.DB
+	Store store.VideoStore
+	// NewVideos broadcasts videos as they are stored, to StreamNewVideos.
+	NewVideos *services.Broadcaster[[]yt.Video]
+
+	// shutdown expires when the server is stopping, ending streams
+	shutdown context.Context
+}
+
+// StartServer serves the API until the context expires, then stops gracefully.
+func (s *Server) StartServer(ctx context.Context) {
+	server := s.newGRPCServer(ctx)
+
+	listener, err := net.Listen("tcp", net.JoinHostPort(s.Cfg.ServerHost, s.Cfg.GRPCPort))
+	if err != nil {
+		s.Logger.Fatal().Err(err).Msg("grpc listen")
+	}
+
+	go func() {
+		if err := server.Serve(listener); err != nil {
+			s.Logger.Fatal().Err(err).Timestamp().Msg("grpc server crashed")
+		}
+	}()
+
+	// Block until the context expires
+	<-ctx.Done()
+	s.Logger.Info().Msg("stopping grpc server")
+	server.GracefulStop()
+}
+
+// newGRPCServer returns a gRPC server for the service, authenticating calls if configured to.
+// Streams end when the context expires.
+func (s *Server) newGRPCServer(ctx context.Context) *grpc.Server {
+	s.shutdown = ctx
+
+	var opts []grpc.ServerOption
+	if s.Cfg.APIAuth {
+		if s.DB == nil {
+			s.Logger.Fatal().Msg("grpc auth: no database to read API keys from")
+		}
+		authn := &auth.Authenticator{
+			// Keys are read from the primary, so that revoked keys are refused at once
+			Keys:       store.APIKeyStore{DB: config.OnPrimary(s.DB)},
+			Limiter:    auth.NewLimiter(),
+			RateLimit:  s.Cfg.APIRateLimit,
+			AdminToken: s.Cfg.APIAdminToken,
+		}
+		opts = append(opts,
+			grpc.UnaryInterceptor(unaryAuth(authn)),
+			grpc.StreamInterceptor(streamAuth(authn)),
+		)
+	}
+
+	server := grpc.NewServer(opts...)
+	pb.RegisterVideoServiceServer(server, s)
+	return server
+}
+
+func (s *Server) ListVideos(_ context.Context, req *pb.ListVideosRequest,
+) (*pb.VideosResponse, error) {
+	st, err := s.filtered(req.Filter)
+	if err != nil {
+		return nil, err
+	}
+	from, limit := page(req.From, req.Limit)
+
+	videos, err := st.Retrieve(from, limit)
+	if err != nil {
+		return nil, s.storeError(err, "list videos")
+	}
+	return videosResponse(videos), nil
+}
+
+func (s *Server) SearchVideos(_ context.Context, req *pb.SearchVideosRequest,
+) (*pb.VideosResponse, error) {
+	if req.Query == "" {
+		return nil, status.Error(codes.InvalidArgument, "no query")
+	}
+	st, err := s.filtered(req.Filter)
+	if err != nil {
+		return nil, err
+	}
+	from, limit := page(req.From, req.Limit)
+
+	var videos []yt.Video
+	switch req.Mode {
+	case pb.SearchVideosRequest_MODE_FUZZY:
+		videos, err = st.FuzzySearch(req.Query, from, limit)
+	case pb.SearchVideosRequest_MODE_NATURAL:
+		videos, err = st.NaturalSearch(req.Query, limit)
+	default:
+		videos, err = st.Search(req.Query, from, limit)
+	}
+	if err != nil {
+		return nil, s.storeError(err, "search videos")
+	}
+
+	resp := videosResponse(videos)
+	// Empty pages past the first only mark the end of the results. Natural-language searches
+	// yield a single page.
+	firstPage := req.From == nil || req.Mode == pb.SearchVideosRequest_MODE_NATURAL
+	if len(videos) == 0 && firstPage {
+		resp.Suggestions, err = st.Suggest(req.Query, handlers.SuggestionsMax)
+		if err != nil {
+			return nil, s.storeError(err, "suggest titles")
+		}
+	}
+	return resp, nil
+}
+
+func (s *Server) GetVideo(_ context.Context, req *pb.GetVideoRequest) (*pb.Video, error) {
+	video, err := s.Store.Get(req.VideoId)
+	if errors.Is(err, store.ErrNotFound) {
+		return nil, status.Error(codes.NotFound, "no such video")
+	}
+	if err != nil {
+		return nil, s.storeError(err, "get video")
+	}
+
+	return toProto(&video), nil
+}
+
+func (s *Server) StreamNewVideos(_ *pb.StreamNewVideosRequest,
+	stream pb.VideoService_StreamNewVideosServer,
+) error {
+	if s.NewVideos == nil {
+		return status.Error(codes.Unavailable, "streaming is unavailable")
+	}
+
+	// The subscription ends with the stream, or the server, which would otherwise wait on the
+	// stream to stop
+	ctx, cancel := context.WithCancel(stream.Context())
+	defer cancel()
+	go func() {
+		select {
+		case <-s.shutdown.Done():
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	for batch := range s.NewVideos.Subscribe(ctx, streamBuffer) {
+		for i := range batch {
+			if err := stream.Send(toProto(&batch[i])); err != nil {
+				return err
+			}
+		}
+	}
+	return status.Error(codes.Unavailable, "stream ended")
+}
+
+// filtered returns the store filtered as requested, validating filters as the REST API does.
+func (s *Server) filtered(f *pb.Filter) (store.VideoStore, error) {
+	query := url.Values{}
+	set := func(param string, value string) {
+		if value != "" && value != "0" {
+			query.Set(param, value)
+		}
+	}
+	setTime := func(param string, t *timestamppb.Timestamp) {
+		if t != nil {
+			query.Set(param, t.AsTime().Format(handlers.QueryTimeFmt))
+		}
+	}
+
+	setTime(handlers.ParamAfter, f.GetPublishedAfter())
+	setTime(handlers.ParamBefore, f.GetPublishedBefore())
+	set(handlers.ParamChannel, f.GetChannel())
+	set(handlers.ParamMinDuration, strconv.FormatInt(f.GetMinDuration(), 10))
+	set(handlers.ParamMaxDuration, strconv.FormatInt(f.GetMaxDuration(), 10))
+	set(handlers.ParamMinViews, strconv.FormatInt(f.GetMinViews(), 10))
+	set(handlers.ParamMaxViews, strconv.FormatInt(f.GetMaxViews(), 10))
+	set(handlers.ParamType, f.GetType())
+	set(handlers.ParamLanguage, f.GetLang())
+	set(handlers.ParamCategory, f.GetCategory())
+	set(handlers.ParamRemoved, f.GetRemoved())
+
+	filter, err := handlers.ParseFilterParams(query)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	return s.Store.WithFilter(filter), nil
+}
+
+// page returns the pagination marker and limit of a request, defaulted and capped as in the
+// REST API.
+func page(from *timestamppb.Timestamp, limit int32) (time.Time, int) {
+	marker := time.Now()
+	if from != nil {
+		marker = from.AsTime()
+	}
+
+	l := int(limit)
+	if l <= 0 {
+		l = handlers.LimitDefault
+	}
+	if l > handlers.LimitMax {
+		l = handlers.LimitMax
+	}
+	return marker, l
+}
+
+func videosResponse(videos []yt.Video) *pb.VideosResponse {
+	resp := &pb.VideosResponse{Videos: make([]*pb.Video, len(videos))}
+	for i := range videos {
+		resp.Videos[i] = toProto(&videos[i])
+	}
+	if len(videos) > 0 {
+		resp.Next = timestamppb.New(videos[len(videos)-1].PublishedAt)
+	}
+	return resp
+}
+
+func toProto(v *yt.Video) *pb.Video {
+	video := &pb.Video{
+		Id:                   uint64(v.ID),
+		VideoId:              v.VideoId,
+		Title:                v.Title,
+		Description:          v.Description,
+		PublishedAt:          timestamppb.New(v.PublishedAt),
+		ThumbnailUrl:         v.ThumbnailUrl,
+		ChannelId:            v.ChannelId,
+		ChannelTitle:         v.ChannelTitle,
+		LiveBroadcastContent: v.LiveBroadcastContent,
+		DurationSeconds:      v.DurationSeconds,
+		ViewCount:            v.ViewCount,
+		LikeCount:            v.LikeCount,
+		CommentCount:         v.CommentCount,
+		DefaultLanguage:      v.DefaultLanguage,
+		CategoryId:           v.CategoryId,
+		FirstSeenAt:          timestamppb.New(v.CreatedAt),
+		RemovalStatus:        v.RemovalStatus,
+	}
+	if v.DeletedAt.Valid {
+		video.RemovedAt = timestamppb.New(v.DeletedAt.Time)
+	}
+	return video
+}
+
+// unaryAuth returns an interceptor authenticating calls by API key, as the REST API does.
+func unaryAuth(authn *auth.Authenticator) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, _ *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (interface{}, error) {
+		ctx, err := authenticate(ctx, authn)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// streamAuth returns an interceptor authenticating streams by API key, as the REST API does.
+// Each stream counts as one request against its key's rate limit.
+func streamAuth(authn *auth.Authenticator) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, _ *grpc.StreamServerInfo,
+		handler grpc.StreamHandler,
+	) error {
+		if _, err := authenticate(ss.Context(), authn); err != nil {
+			return err
+		}
+		return handler(srv, ss)
+	}
+}
+
+// authenticate checks the API key passed in the metadata of a call, in the x-api-key key or
+// as a bearer token.
+func authenticate(ctx context.Context, authn *auth.Authenticator) (context.Context, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	secret := ""
+	if keys := md.Get(strings.ToLower(auth.HeaderAPIKey)); len(keys) > 0 {
+		secret = keys[0]
+	} else if authz := md.Get("authorization"); len(authz) > 0 {
+		const bearer = "bearer "
+		if len(authz[0]) > len(bearer) && strings.EqualFold(authz[0][:len(bearer)], bearer) {
+			secret = authz[0][len(bearer):]
+		}
+	}
+
+	key, _, err := authn.Check(secret)
+	switch {
+	case errors.Is(err, auth.ErrMissingKey), errors.Is(err, auth.ErrInvalidKey):
+		return ctx, status.Error(codes.Unauthenticated, err.Error())
+	case errors.Is(err, auth.ErrRateLimited):
+		return ctx, status.Error(codes.ResourceExhausted, err.Error())
+	case err != nil:
+		return ctx, storeStatus(err).Err()
+	}
+	return auth.WithClient(ctx, key), nil
+}
+
+// storeError is the status of a request that failed in the store, by the kind of the error.
+// Errors on our end are logged, as their statuses do not tell.
+func (s *Server) storeError(err error, op string) error {
+	st := storeStatus(err)
+	if st.Code() == codes.Internal || st.Code() == codes.Unavailable {
+		s.Logger.Error().Err(err).Msg(op)
+	}
+	return st.Err()
+}
+
+func storeStatus(err error) *status.Status {
+	switch {
+	case errors.Is(err, store.ErrNotFound):
+		return status.New(codes.NotFound, err.Error())
+	case errors.Is(err, store.ErrInvalidQuery):
+		return status.New(codes.InvalidArgument, err.Error())
+	case errors.Is(err, store.ErrUnavailable):
+		return status.New(codes.Unavailable, "unavailable")
+	default:
+		return status.New(codes.Internal, "internal error")
+	}
+}
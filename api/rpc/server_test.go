@@ -0,0 +1,293 @@
+package rpc
+
+import (
+	"context"
+	"github.com/ditsuke/youtube-focus/api/rpc/pb"
+	"github.com/ditsuke/youtube-focus/config"
+	"github.com/ditsuke/youtube-focus/internal/services"
+	"github.com/ditsuke/youtube-focus/internal/yt"
+	"github.com/ditsuke/youtube-focus/store"
+	"github.com/ditsuke/youtube-focus/store/migrations"
+	"github.com/rs/zerolog"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// epoch is when the latest of testVideos was published.
+var epoch = time.Date(2022, time.September, 1, 12, 0, 0, 0, time.UTC)
+
+var testVideos = []yt.Video{
+	{VideoId: "cats", Title: "Cats at play", PublishedAt: epoch, ChannelId: "c0"},
+	{VideoId: "dogs", Title: "Dogs at the beach", PublishedAt: epoch.Add(-time.Minute),
+		ChannelId: "c1"},
+	{VideoId: "naps", Title: "Cats asleep", PublishedAt: epoch.Add(-2 * time.Minute),
+		ChannelId: "c1"},
+}
+
+// newTestServer returns a server over a store of testVideos, with new videos published on its
+// NewVideos.
+func newTestServer(t *testing.T) *Server {
+	t.Helper()
+	videos := store.NewMemoryStore()
+	if _, err := videos.Save(testVideos); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	return &Server{
+		Logger:    zerolog.Nop(),
+		Store:     videos,
+		NewVideos: services.NewBroadcaster[[]yt.Video](),
+	}
+}
+
+// withAuth configures a server to authenticate calls, returning the secret of a key it accepts.
+func withAuth(t *testing.T, s *Server) string {
+	t.Helper()
+	db, err := config.Config{DBDriver: config.DriverSQLite, SQLitePath: ":memory:"}.GetDB()
+	if err != nil {
+		t.Fatalf("open the database: %v", err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatalf("open the database: %v", err)
+	}
+	// The in-memory database is dropped with its last connection, once the test is over
+	t.Cleanup(func() { _ = sqlDB.Close() })
+	if _, err := migrations.Up(db); err != nil {
+		t.Fatalf("migrate the database: %v", err)
+	}
+
+	keys := store.APIKeyStore{DB: db}
+	secret, err := keys.Issue(&store.APIKey{Name: "reader", Role: store.RoleReader})
+	if err != nil {
+		t.Fatalf("Issue: %v", err)
+	}
+	s.Cfg.APIAuth, s.Cfg.APIRateLimit, s.DB = true, 60, db
+	return secret
+}
+
+// dial serves a server over an in-memory connection until the context expires, returning a
+// client of it.
+func dial(t *testing.T, ctx context.Context, s *Server) pb.VideoServiceClient {
+	t.Helper()
+	listener := bufconn.Listen(1 << 20)
+	server := s.newGRPCServer(ctx)
+	go func() { _ = server.Serve(listener) }()
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.DialContext(ctx, "bufconn",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+	return pb.NewVideoServiceClient(conn)
+}
+
+func ids(videos []*pb.Video) []string {
+	ids := []string{}
+	for _, v := range videos {
+		ids = append(ids, v.VideoId)
+	}
+	return ids
+}
+
+func TestListVideos(t *testing.T) {
+	client := dial(t, context.Background(), newTestServer(t))
+	ctx := context.Background()
+
+	resp, err := client.ListVideos(ctx, &pb.ListVideosRequest{Limit: 2})
+	if err != nil {
+		t.Fatalf("ListVideos: %v", err)
+	}
+	if got, want := ids(resp.Videos), []string{"cats", "dogs"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("first page = %v, want %v", got, want)
+	}
+	if !resp.Next.AsTime().Equal(testVideos[1].PublishedAt) {
+		t.Errorf("Next = %v, want %v", resp.Next.AsTime(), testVideos[1].PublishedAt)
+	}
+
+	resp, err = client.ListVideos(ctx, &pb.ListVideosRequest{From: resp.Next, Limit: 2})
+	if err != nil {
+		t.Fatalf("ListVideos: %v", err)
+	}
+	if got, want := ids(resp.Videos), []string{"naps"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("second page = %v, want %v", got, want)
+	}
+
+	resp, err = client.ListVideos(ctx, &pb.ListVideosRequest{Filter: &pb.Filter{Channel: "c1"}})
+	if err != nil {
+		t.Fatalf("ListVideos: %v", err)
+	}
+	if got, want := ids(resp.Videos), []string{"dogs", "naps"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("videos of c1 = %v, want %v", got, want)
+	}
+
+	_, err = client.ListVideos(ctx, &pb.ListVideosRequest{Filter: &pb.Filter{Type: "nonsense"}})
+	if code := status.Code(err); code != codes.InvalidArgument {
+		t.Errorf("ListVideos with an invalid filter = %v, want %v", err, codes.InvalidArgument)
+	}
+}
+
+func TestSearchVideos(t *testing.T) {
+	client := dial(t, context.Background(), newTestServer(t))
+	ctx := context.Background()
+
+	for _, tt := range []struct {
+		req         *pb.SearchVideosRequest
+		want        []string
+		suggestions []string
+	}{
+		{&pb.SearchVideosRequest{Query: "cats"}, []string{"cats", "naps"}, nil},
+		{&pb.SearchVideosRequest{Query: "cats", Filter: &pb.Filter{Channel: "c1"}},
+			[]string{"naps"}, nil},
+		{&pb.SearchVideosRequest{Query: "play cats", Mode: pb.SearchVideosRequest_MODE_FUZZY},
+			[]string{"cats"}, nil},
+		{&pb.SearchVideosRequest{Query: "cat naps"}, []string{},
+			[]string{"Cats at play", "Cats asleep"}},
+		// Past the first page, empty pages mark the end of the results
+		{&pb.SearchVideosRequest{Query: "cat naps", From: timestamppb.New(epoch)}, []string{}, nil},
+	} {
+		resp, err := client.SearchVideos(ctx, tt.req)
+		if err != nil {
+			t.Errorf("SearchVideos(%v): %v", tt.req, err)
+			continue
+		}
+		if got := ids(resp.Videos); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("SearchVideos(%v) = %v, want %v", tt.req, got, tt.want)
+		}
+		if len(resp.Suggestions) > 0 || len(tt.suggestions) > 0 {
+			if !reflect.DeepEqual(resp.Suggestions, tt.suggestions) {
+				t.Errorf("suggestions for %v = %q, want %q", tt.req, resp.Suggestions,
+					tt.suggestions)
+			}
+		}
+	}
+
+	if _, err := client.SearchVideos(ctx, &pb.SearchVideosRequest{}); status.Code(err) !=
+		codes.InvalidArgument {
+		t.Errorf("SearchVideos with no query = %v, want %v", err, codes.InvalidArgument)
+	}
+}
+
+func TestGetVideo(t *testing.T) {
+	client := dial(t, context.Background(), newTestServer(t))
+	ctx := context.Background()
+
+	video, err := client.GetVideo(ctx, &pb.GetVideoRequest{VideoId: "dogs"})
+	if err != nil {
+		t.Fatalf("GetVideo: %v", err)
+	}
+	if video.VideoId != "dogs" || video.Title != "Dogs at the beach" || video.ChannelId != "c1" ||
+		!video.PublishedAt.AsTime().Equal(testVideos[1].PublishedAt) {
+		t.Errorf("GetVideo = %v, want the video of dogs", video)
+	}
+
+	_, err = client.GetVideo(ctx, &pb.GetVideoRequest{VideoId: "nope"})
+	if code := status.Code(err); code != codes.NotFound {
+		t.Errorf("GetVideo of no video = %v, want %v", err, codes.NotFound)
+	}
+}
+
+func TestStreamNewVideos(t *testing.T) {
+	s := newTestServer(t)
+	shutdown, stop := context.WithCancel(context.Background())
+	defer stop()
+	client := dial(t, shutdown, s)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	stream, err := client.StreamNewVideos(ctx, &pb.StreamNewVideosRequest{})
+	if err != nil {
+		t.Fatalf("StreamNewVideos: %v", err)
+	}
+
+	// The stream subscribes some time after it is opened, so videos are published until one
+	// comes through
+	published := []yt.Video{{VideoId: "new", Title: "New cats", PublishedAt: epoch}}
+	go func() {
+		ticker := time.NewTicker(10 * time.Millisecond)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				s.NewVideos.Publish(published)
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	video, err := stream.Recv()
+	if err != nil {
+		t.Fatalf("Recv: %v", err)
+	}
+	if video.VideoId != "new" || video.Title != "New cats" {
+		t.Errorf("streamed %v, want the new video", video)
+	}
+
+	// Streams end as the server stops
+	stop()
+	for {
+		if _, err = stream.Recv(); err != nil {
+			break
+		}
+	}
+	if code := status.Code(err); code != codes.Unavailable {
+		t.Errorf("stream on shutdown = %v, want %v", err, codes.Unavailable)
+	}
+}
+
+func TestAuth(t *testing.T) {
+	s := newTestServer(t)
+	secret := withAuth(t, s)
+	client := dial(t, context.Background(), s)
+
+	for _, tt := range []struct {
+		name string
+		md   metadata.MD
+		want codes.Code
+	}{
+		{"no key", metadata.MD{}, codes.Unauthenticated},
+		{"bad key", metadata.Pairs("x-api-key", "nope"), codes.Unauthenticated},
+		{"bad bearer token", metadata.Pairs("authorization", "Bearer nope"),
+			codes.Unauthenticated},
+		{"key", metadata.Pairs("x-api-key", secret), codes.OK},
+		{"bearer token", metadata.Pairs("authorization", "Bearer "+secret), codes.OK},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(metadata.NewOutgoingContext(context.Background(),
+				tt.md), 5*time.Second)
+			defer cancel()
+
+			_, err := client.GetVideo(ctx, &pb.GetVideoRequest{VideoId: "cats"})
+			if code := status.Code(err); code != tt.want {
+				t.Errorf("GetVideo = %v, want %v", err, tt.want)
+			}
+
+			// Streams are authenticated before they are subscribed, failing on their first
+			// message
+			stream, err := client.StreamNewVideos(ctx, &pb.StreamNewVideosRequest{})
+			if err != nil {
+				t.Fatalf("StreamNewVideos: %v", err)
+			}
+			if tt.want == codes.OK {
+				return
+			}
+			if _, err := stream.Recv(); status.Code(err) != tt.want {
+				t.Errorf("stream = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
@@ -2,13 +2,22 @@ package api
 
 import (
 	"context"
+	"errors"
+	"github.com/ditsuke/youtube-focus/api/auth"
+	"github.com/ditsuke/youtube-focus/api/cache"
+	"github.com/ditsuke/youtube-focus/api/graphql"
 	"github.com/ditsuke/youtube-focus/api/handlers"
+	"github.com/ditsuke/youtube-focus/api/openapi"
 	"github.com/ditsuke/youtube-focus/config"
+	"github.com/ditsuke/youtube-focus/internal/services"
+	"github.com/ditsuke/youtube-focus/internal/yt"
 	"github.com/ditsuke/youtube-focus/store"
 	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
 	"github.com/go-chi/render"
 	"github.com/ironstar-io/chizerolog"
 	"github.com/rs/zerolog"
+	"gorm.io/gorm"
 	"net"
 	"net/http"
 	"time"
@@ -16,9 +25,22 @@ import (
 
 const gracefulShutdownDeadline = 15 * time.Second
 
+// APIPrefix is the path the current version of the API is served under.
+const APIPrefix = "/v1"
+
 type Server struct {
 	Cfg    config.Config
 	Logger zerolog.Logger
+	// DB is the database, as returned by config.GetReadDB: videos are read from its
+	// replicas, if any.
+	DB *gorm.DB
+	// Videos, if set, is the store videos are served from, rather than VideoMetaStores on DB,
+	// eg: a store.MemoryStore.
+	Videos store.VideoStore
+	// Cache holds video responses. It must be purged when videos are stored.
+	Cache *cache.Cache
+	// NewVideos broadcasts videos as they are stored, to streams and GraphQL subscribers.
+	NewVideos *services.Broadcaster[[]yt.Video]
 }
 
 func (s *Server) StartServer(ctx context.Context) {
@@ -26,16 +48,19 @@ func (s *Server) StartServer(ctx context.Context) {
 	routeLogger := s.Logger.With().Str("part", "router").Logger()
 
 	r.Use(render.SetContentType(render.ContentTypeJSON))
+	r.Use(middleware.RequestID, s.tagRequest)
 	r.Use(chizerolog.LoggerMiddleware(&routeLogger))
 
 	// Register routes or panic
-	if err := RegisterRoutes(s.Cfg, r); err != nil {
+	if err := s.RegisterRoutes(r); err != nil {
 		s.Logger.Fatal().Err(err).Str("op", "register routes").Msg("")
 	}
 
 	server := http.Server{
 		Addr:    net.JoinHostPort(s.Cfg.ServerHost, s.Cfg.ServerPort),
 		Handler: r,
+		// Requests share the server's context, so that streams end on shutdown
+		BaseContext: func(net.Listener) context.Context { return ctx },
 	}
 
 	// Spawn the server in a new goroutine
@@ -57,14 +82,118 @@ func (s *Server) StartServer(ctx context.Context) {
 	}
 }
 
-func RegisterRoutes(cfg config.Config, m *chi.Mux) error {
-	db, err := cfg.GetDB()
+// tagRequest echoes the ID of a request in its response, for clients to quote, and carries a
+// logger tagged with it in the request's context.
+func (s *Server) tagRequest(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := middleware.GetReqID(r.Context())
+		w.Header().Set(middleware.RequestIDHeader, id)
+		logger := s.Logger.With().Str("part", "router").Str("request_id", id).Logger()
+		next.ServeHTTP(w, r.WithContext(logger.WithContext(r.Context())))
+	})
+}
+
+// RegisterRoutes registers the API's routes on m, failing if they diverge from its OpenAPI
+// spec.
+func (s *Server) RegisterRoutes(m *chi.Mux) error {
+	cfg := s.Cfg
+	db := s.DB
+	if db == nil {
+		return errors.New("no database")
+	}
+	var videoStore, primaryVideoStore store.VideoStore = s.Videos, s.Videos
+	if s.Videos == nil {
+		videoStore = &store.VideoMetaStore{
+			DB:             db,
+			FuzzyThreshold: cfg.SearchSimilarityThreshold,
+		}
+		primaryVideoStore = &store.VideoMetaStore{
+			DB:             config.OnPrimary(db),
+			FuzzyThreshold: cfg.SearchSimilarityThreshold,
+		}
+	}
+	videoSvc := handlers.New(videoStore)
+	// Cached responses are read from the primary, as those of a replica that has yet to see
+	// new videos would stay cached past the purge meant to evict them
+	cachedVideoSvc := videoSvc
+	if s.Cache.Enabled() {
+		cachedVideoSvc = handlers.New(primaryVideoStore)
+	}
+	streamSvc := handlers.NewStreamHandler(s.NewVideos)
+	// New results of saved searches are read from the primary, as those a replica has yet to
+	// see would be skipped once the search's read marker moves past them
+	savedSearchSvc := handlers.NewSavedSearchHandler(
+		store.SavedSearchStore{DB: config.OnPrimary(db)}, primaryVideoStore)
+
+	authn := &auth.Authenticator{
+		// Keys are read from the primary, so that revoked keys are refused at once
+		Keys:       store.APIKeyStore{DB: config.OnPrimary(db)},
+		Limiter:    auth.NewLimiter(),
+		RateLimit:  cfg.APIRateLimit,
+		AdminToken: cfg.APIAdminToken,
+	}
+	auditor := &auth.Auditor{
+		Logger: s.Logger,
+		Store:  store.AuditStore{DB: db},
+	}
+	graphqlSvc, err := graphql.New(s.Logger, videoStore, s.NewVideos)
 	if err != nil {
 		return err
 	}
-	videoSvc := handlers.New(store.VideoMetaStore{DB: db})
+	apiKeySvc := handlers.NewAPIKeyHandler(authn.Keys)
+	auditSvc := handlers.NewAuditHandler(auditor.Store)
+
+	m.Route(APIPrefix, func(r chi.Router) {
+		r.Get("/openapi.json", openapi.ServeSpec)
+
+		// Readers
+		r.Group(func(r chi.Router) {
+			if cfg.APIAuth {
+				r.Use(authn.Authenticate)
+			}
+
+			r.Group(func(r chi.Router) {
+				if s.Cache != nil {
+					r.Use(s.Cache.Middleware)
+				}
+
+				r.Get("/videos", cachedVideoSvc.Search)
+				r.Get("/videos/{"+handlers.ParamVideoID+"}", cachedVideoSvc.Get)
+				r.Get("/videos/{"+handlers.ParamVideoID+"}/history", cachedVideoSvc.History)
+				r.Get("/videos_search", cachedVideoSvc.AdvancedSearch)
+			})
+			r.Post("/videos/lookup", videoSvc.Lookup)
+			r.Get("/videos/stream", streamSvc.Stream)
+			r.Get("/export", videoSvc.Export)
+			r.Get("/graphql", graphqlSvc.ServeHTTP)
+			r.Post("/graphql", graphqlSvc.ServeHTTP)
+
+			r.Get("/saved_searches", savedSearchSvc.List)
+			r.Get("/saved_searches/{"+handlers.ParamSavedSearchID+"}", savedSearchSvc.Get)
+			r.Get("/saved_searches/{"+handlers.ParamSavedSearchID+"}/new", savedSearchSvc.New)
+		})
+
+		// Editors, whether or not auth is required of other clients, as the API would
+		// otherwise be open to changes
+		r.Group(func(r chi.Router) {
+			r.Use(authn.Authenticate, auth.RequireRole(store.RoleEditor), auditor.Audit)
+
+			r.Post("/saved_searches", savedSearchSvc.Create)
+			r.Delete("/saved_searches/{"+handlers.ParamSavedSearchID+"}", savedSearchSvc.Delete)
+		})
+
+		// Admins, whether or not auth is required of other clients
+		r.Route("/admin", func(r chi.Router) {
+			r.Use(authn.Authenticate, auth.RequireRole(store.RoleAdmin), auditor.Audit)
+
+			r.Post("/keys", apiKeySvc.Issue)
+			r.Get("/keys", apiKeySvc.List)
+			r.Delete("/keys/{"+handlers.ParamAPIKeyID+"}", apiKeySvc.Revoke)
+
+			r.Get("/audit", auditSvc.List)
+		})
+	})
 
-	m.Get("/videos", videoSvc.Search)
-	m.Get("/videos_search", videoSvc.AdvancedSearch)
-	return nil
+	// Refuse to serve an API that does not match its documentation
+	return openapi.Validate(m, APIPrefix, queryParams)
 }
@@ -0,0 +1,118 @@
+package api
+
+import (
+	"github.com/ditsuke/youtube-focus/api/openapi"
+	"github.com/ditsuke/youtube-focus/config"
+	"github.com/ditsuke/youtube-focus/store/migrations"
+	"github.com/go-chi/chi/v5"
+	"github.com/rs/zerolog"
+	"gorm.io/gorm"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// newTestDB returns an empty in-memory SQLite database, dropped at the end of the test.
+func newTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := config.Config{DBDriver: config.DriverSQLite, SQLitePath: ":memory:"}.GetDB()
+	if err != nil {
+		t.Fatalf("open the database: %v", err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatalf("open the database: %v", err)
+	}
+	t.Cleanup(func() { _ = sqlDB.Close() })
+	return db
+}
+
+func TestRoutesMatchSpec(t *testing.T) {
+	for _, authOn := range []bool{false, true} {
+		s := &Server{
+			Cfg:    config.Config{APIAuth: authOn},
+			Logger: zerolog.Nop(),
+			DB:     newTestDB(t),
+		}
+		m := chi.NewRouter()
+		// Registration fails if the routes diverge from the spec
+		if err := s.RegisterRoutes(m); err != nil {
+			t.Fatalf("RegisterRoutes with APIAuth %v: %v", authOn, err)
+		}
+
+		rec := httptest.NewRecorder()
+		m.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, APIPrefix+"/openapi.json", nil))
+		if rec.Code != http.StatusOK || rec.Body.String() != string(openapi.Spec) {
+			t.Errorf("GET %s/openapi.json = %d, want %d and the spec",
+				APIPrefix, rec.Code, http.StatusOK)
+		}
+	}
+}
+
+func TestValidateReportsDivergence(t *testing.T) {
+	s := &Server{Logger: zerolog.Nop(), DB: newTestDB(t)}
+	m := chi.NewRouter()
+	if err := s.RegisterRoutes(m); err != nil {
+		t.Fatalf("RegisterRoutes: %v", err)
+	}
+	m.Get(APIPrefix+"/undocumented", func(http.ResponseWriter, *http.Request) {})
+
+	err := openapi.Validate(m, APIPrefix, queryParams)
+	if err == nil || !strings.Contains(err.Error(), "undocumented: GET /undocumented") {
+		t.Errorf("Validate with an undocumented route: %v", err)
+	}
+
+	// Parameters read and not declared, or declared and not read
+	drifted := map[string][]string{}
+	for op, params := range queryParams {
+		drifted[op] = params
+	}
+	drifted["GET /videos"] = append([]string{"sort"}, queryParams["GET /videos"][1:]...)
+	err = openapi.Validate(m, APIPrefix, drifted)
+	for _, want := range []string{
+		"undocumented parameter: GET /videos query sort",
+		"unread parameter: GET /videos query " + queryParams["GET /videos"][0],
+	} {
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("Validate with diverging parameters: %v, want %q", err, want)
+		}
+	}
+
+	m = chi.NewRouter()
+	m.Get(APIPrefix+"/openapi.json", openapi.ServeSpec)
+	err = openapi.Validate(m, APIPrefix, queryParams)
+	if err == nil || !strings.Contains(err.Error(), "not routed: GET /videos") {
+		t.Errorf("Validate without the documented routes: %v", err)
+	}
+}
+
+func TestChangesRequireAuth(t *testing.T) {
+	db := newTestDB(t)
+	if _, err := migrations.Up(db); err != nil {
+		t.Fatalf("migrate the database: %v", err)
+	}
+	// Reads are open to anyone
+	s := &Server{Cfg: config.Config{APIAuth: false}, Logger: zerolog.Nop(), DB: db}
+	m := chi.NewRouter()
+	if err := s.RegisterRoutes(m); err != nil {
+		t.Fatalf("RegisterRoutes: %v", err)
+	}
+
+	for _, tt := range []struct {
+		method, path string
+		want         int
+	}{
+		{http.MethodGet, "/saved_searches", http.StatusOK},
+		{http.MethodPost, "/saved_searches", http.StatusUnauthorized},
+		{http.MethodDelete, "/saved_searches/1", http.StatusUnauthorized},
+		{http.MethodGet, "/admin/keys", http.StatusUnauthorized},
+	} {
+		rec := httptest.NewRecorder()
+		body := strings.NewReader(`{"name": "cats", "search": "cats"}`)
+		m.ServeHTTP(rec, httptest.NewRequest(tt.method, APIPrefix+tt.path, body))
+		if rec.Code != tt.want {
+			t.Errorf("%s %s without a key = %d, want %d", tt.method, tt.path, rec.Code, tt.want)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// The admin API requires a key with the RoleAdmin role, or the admin token.
+
+// IssueAPIKey issues an API key. The response is the only time its secret is revealed.
+func (c *Client) IssueAPIKey(ctx context.Context, req APIKeyRequest,
+) (*APIKeyResponse, error) {
+	var key APIKeyResponse
+	if err := c.do(ctx, http.MethodPost, "/admin/keys", req, &key); err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+// APIKeys lists every API key, revoked or not.
+func (c *Client) APIKeys(ctx context.Context) ([]*APIKeyResponse, error) {
+	var resp struct {
+		Keys []*APIKeyResponse `json:"keys"`
+	}
+	if err := c.get(ctx, "/admin/keys", nil, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Keys, nil
+}
+
+// RevokeAPIKey revokes an API key.
+func (c *Client) RevokeAPIKey(ctx context.Context, id uint) (*APIKeyResponse, error) {
+	var key APIKeyResponse
+	path := "/admin/keys/" + strconv.FormatUint(uint64(id), 10)
+	if err := c.do(ctx, http.MethodDelete, path, nil, &key); err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+// AuditLog gets a page of up to limit entries of the audit log, latest first, before the entry
+// with the ID from, or from the latest when zero. The response's Next is the from of the next
+// page.
+func (c *Client) AuditLog(ctx context.Context, from uint, limit int,
+) (*AuditLogResponse, error) {
+	query := url.Values{}
+	if from > 0 {
+		query.Set(paramFrom, strconv.FormatUint(uint64(from), 10))
+	}
+	if limit > 0 {
+		query.Set(paramLimit, strconv.Itoa(limit))
+	}
+
+	var resp AuditLogResponse
+	if err := c.get(ctx, "/admin/audit", query, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
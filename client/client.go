@@ -0,0 +1,211 @@
+// Package client is a Go client for the YouTube Focus REST API. It depends on none of the
+// server's packages, and so none of their database drivers: responses are decoded into types
+// of its own, mirroring the JSON the server renders.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// RetriesDefault is the number of times requests are retried by default.
+	RetriesDefault = 3
+
+	// BackoffDefault is the wait before the first retry by default, doubled for each retry
+	// after.
+	BackoffDefault = 500 * time.Millisecond
+)
+
+// Client makes requests to the API. It is safe for concurrent use.
+type Client struct {
+	base    string
+	apiKey  string
+	http    *http.Client
+	retries int
+	backoff time.Duration
+}
+
+type Opt func(c *Client)
+
+// WithAPIKey authenticates requests with an API key.
+func WithAPIKey(key string) Opt {
+	return func(c *Client) {
+		c.apiKey = key
+	}
+}
+
+// WithHTTPClient makes requests with an http.Client other than http.DefaultClient.
+func WithHTTPClient(client *http.Client) Opt {
+	return func(c *Client) {
+		c.http = client
+	}
+}
+
+// WithRetries sets the number of times requests are retried, and the wait before the first
+// retry. Requests are retried when rate-limited, and when the server fails to respond to
+// those that can safely be repeated (ie: any but a POST).
+func WithRetries(retries int, backoff time.Duration) Opt {
+	return func(c *Client) {
+		c.retries = retries
+		c.backoff = backoff
+	}
+}
+
+// New returns a Client of the API served at server, eg: "http://localhost:8080".
+func New(server string, opts ...Opt) (*Client, error) {
+	u, err := url.Parse(server)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return nil, fmt.Errorf("invalid server url %q", server)
+	}
+
+	c := &Client{
+		base:    strings.TrimSuffix(server, "/") + Prefix,
+		http:    http.DefaultClient,
+		retries: RetriesDefault,
+		backoff: BackoffDefault,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c, nil
+}
+
+// Error is an error response of the API.
+type Error struct {
+	// StatusCode is the HTTP status code of the response.
+	StatusCode int
+	// Status describes the kind of error, eg: "not found".
+	Status string
+	// Message describes the error, if the API tells.
+	Message string
+	// RequestID identifies the request in the server's logs.
+	RequestID string
+}
+
+func (e *Error) Error() string {
+	msg := "api: " + e.Status
+	if e.Message != "" {
+		msg += ": " + e.Message
+	}
+	if e.RequestID != "" {
+		msg += fmt.Sprintf(" (request %s)", e.RequestID)
+	}
+	return msg
+}
+
+// get decodes the JSON response to a GET of path with a query into out.
+func (c *Client) get(ctx context.Context, path string, query url.Values, out interface{}) error {
+	return c.do(ctx, http.MethodGet, withQuery(path, query), nil, out)
+}
+
+// do makes a request with a JSON body, if any, decoding the JSON response into out, if any.
+func (c *Client) do(ctx context.Context, method, path string, body, out interface{}) error {
+	var payload []byte
+	if body != nil {
+		var err error
+		if payload, err = json.Marshal(body); err != nil {
+			return err
+		}
+	}
+
+	resp, err := c.request(ctx, method, path, payload)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// request makes a request, retrying it as configured, and fails with an *Error for responses
+// other than 2xx. The response body must be closed by the caller.
+func (c *Client) request(ctx context.Context, method, path string, payload []byte,
+) (*http.Response, error) {
+	backoff := c.backoff
+	for attempt := 0; ; attempt++ {
+		resp, err := c.send(ctx, method, path, payload)
+		if err != nil {
+			return nil, err
+		}
+		if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+			return resp, nil
+		}
+
+		apiErr := decodeError(resp)
+		retryable := resp.StatusCode == http.StatusTooManyRequests ||
+			(resp.StatusCode >= 500 && method != http.MethodPost)
+		if !retryable || attempt >= c.retries {
+			return nil, apiErr
+		}
+
+		wait := backoff
+		if after, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil {
+			wait = time.Duration(after) * time.Second
+		}
+		backoff *= 2
+
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+func (c *Client) send(ctx context.Context, method, path string, payload []byte,
+) (*http.Response, error) {
+	var body io.Reader
+	if payload != nil {
+		body = bytes.NewReader(payload)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.base+path, body)
+	if err != nil {
+		return nil, err
+	}
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.apiKey != "" {
+		req.Header.Set(headerAPIKey, c.apiKey)
+	}
+	return c.http.Do(req)
+}
+
+// decodeError reads an error response, closing its body.
+func decodeError(resp *http.Response) *Error {
+	defer resp.Body.Close()
+
+	apiErr := &Error{
+		StatusCode: resp.StatusCode,
+		Status:     http.StatusText(resp.StatusCode),
+		RequestID:  resp.Header.Get(headerRequestID),
+	}
+	var body errResponse
+	if err := json.NewDecoder(resp.Body).Decode(&body); err == nil && body.StatusText != "" {
+		apiErr.Status = body.StatusText
+		apiErr.Message = body.ErrorText
+		if apiErr.RequestID == "" {
+			apiErr.RequestID = body.RequestID
+		}
+	}
+	return apiErr
+}
+
+func withQuery(path string, query url.Values) string {
+	if len(query) == 0 {
+		return path
+	}
+	return path + "?" + query.Encode()
+}
@@ -0,0 +1,642 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/ditsuke/youtube-focus/api"
+	"github.com/ditsuke/youtube-focus/api/auth"
+	"github.com/ditsuke/youtube-focus/api/handlers"
+	"github.com/ditsuke/youtube-focus/api/response"
+	"github.com/ditsuke/youtube-focus/config"
+	"github.com/ditsuke/youtube-focus/internal/services"
+	"github.com/ditsuke/youtube-focus/internal/yt"
+	"github.com/ditsuke/youtube-focus/store"
+	"github.com/ditsuke/youtube-focus/store/migrations"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/rs/zerolog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// adminToken is the admin token of the test server.
+const adminToken = "test-admin-token"
+
+// epoch is when the latest of the test videos was published.
+var epoch = time.Date(2022, time.September, 1, 12, 0, 0, 0, time.UTC)
+
+// testServer is the API served over HTTP from an in-memory SQLite database.
+type testServer struct {
+	url       string
+	videos    *store.VideoMetaStore
+	newVideos *services.Broadcaster[[]yt.Video]
+}
+
+func newTestServer(t *testing.T) *testServer {
+	t.Helper()
+	db, err := config.Config{DBDriver: config.DriverSQLite, SQLitePath: ":memory:"}.GetDB()
+	if err != nil {
+		t.Fatalf("open the database: %v", err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatalf("open the database: %v", err)
+	}
+	// The in-memory database is dropped with its last connection, once the test is over
+	t.Cleanup(func() { _ = sqlDB.Close() })
+	if _, err := migrations.Up(db); err != nil {
+		t.Fatalf("migrate the database: %v", err)
+	}
+
+	ts := &testServer{
+		videos:    &store.VideoMetaStore{DB: db},
+		newVideos: services.NewBroadcaster[[]yt.Video](),
+	}
+	s := &api.Server{
+		Cfg:       config.Config{APIAdminToken: adminToken, APIRateLimit: 60},
+		Logger:    zerolog.Nop(),
+		DB:        db,
+		NewVideos: ts.newVideos,
+	}
+	m := chi.NewRouter()
+	if err := s.RegisterRoutes(m); err != nil {
+		t.Fatalf("RegisterRoutes: %v", err)
+	}
+	srv := httptest.NewServer(m)
+	t.Cleanup(srv.Close)
+	ts.url = srv.URL
+	return ts
+}
+
+// save stores n videos, video0 to video<n-1>, published a minute apart from epoch, latest
+// first, and returns them.
+func (ts *testServer) save(t *testing.T, n int) []yt.Video {
+	t.Helper()
+	videos := make([]yt.Video, n)
+	for i := range videos {
+		videos[i] = yt.Video{
+			VideoId:      fmt.Sprintf("video%d", i),
+			Title:        fmt.Sprintf("Video %d", i),
+			Description:  fmt.Sprintf("The video numbered %d", i),
+			PublishedAt:  epoch.Add(-time.Duration(i) * time.Minute),
+			ChannelId:    "channel",
+			ChannelTitle: "Channel",
+			ViewCount:    int64(1000 * (i + 1)),
+		}
+	}
+	if _, err := ts.videos.Save(videos); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	return videos
+}
+
+func (ts *testServer) client(t *testing.T, opts ...Opt) *Client {
+	t.Helper()
+	c, err := New(ts.url, append([]Opt{WithRetries(0, 0)}, opts...)...)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	return c
+}
+
+func videoIDs(videos []Video) []string {
+	ids := make([]string, len(videos))
+	for i, v := range videos {
+		ids[i] = v.VideoId
+	}
+	return ids
+}
+
+func TestConstantsMatchServer(t *testing.T) {
+	strs := [][2]string{
+		{Prefix, api.APIPrefix},
+		{paramFrom, handlers.ParamFrom},
+		{paramLimit, handlers.ParamLimit},
+		{paramSearch, handlers.ParamSearch},
+		{paramFuzzy, handlers.ParamFuzzy},
+		{paramFacets, handlers.ParamFacets},
+		{paramAfter, handlers.ParamAfter},
+		{paramBefore, handlers.ParamBefore},
+		{paramChannel, handlers.ParamChannel},
+		{paramMinDuration, handlers.ParamMinDuration},
+		{paramMaxDuration, handlers.ParamMaxDuration},
+		{paramMinViews, handlers.ParamMinViews},
+		{paramMaxViews, handlers.ParamMaxViews},
+		{paramType, handlers.ParamType},
+		{paramLanguage, handlers.ParamLanguage},
+		{paramCategory, handlers.ParamCategory},
+		{paramRemoved, handlers.ParamRemoved},
+		{paramFields, response.ParamFields},
+		{paramExpand, response.ParamExpand},
+		{paramFormat, response.ParamFormat},
+		{formatNDJSON, response.FormatNDJSON},
+		{queryTimeFmt, handlers.QueryTimeFmt},
+		{headerAPIKey, auth.HeaderAPIKey},
+		{headerRequestID, middleware.RequestIDHeader},
+		{eventVideo, handlers.EventVideo},
+		{ExpandChannel, response.ExpandChannel},
+		{ExpandStats, response.ExpandStats},
+		{TypeUpload, handlers.TypeUpload},
+		{TypeLive, handlers.TypeLive},
+		{TypeUpcoming, handlers.TypeUpcoming},
+		{RemovedExclude, store.RemovedExclude},
+		{RemovedInclude, store.RemovedInclude},
+		{RemovedOnly, store.RemovedOnly},
+		{string(ModeLike), string(store.ModeLike)},
+		{string(ModeFuzzy), string(store.ModeFuzzy)},
+		{string(ModeNatural), string(store.ModeNatural)},
+		{string(FacetChannel), string(store.FacetChannel)},
+		{string(FacetDay), string(store.FacetDay)},
+		{string(FacetCategory), string(store.FacetCategory)},
+		{string(FacetDuration), string(store.FacetDuration)},
+		{string(FacetWatch), string(store.FacetWatch)},
+		{string(RoleReader), string(store.RoleReader)},
+		{string(RoleEditor), string(store.RoleEditor)},
+		{string(RoleAdmin), string(store.RoleAdmin)},
+	}
+	for _, s := range strs {
+		if s[0] != s[1] {
+			t.Errorf("client has %q where the server has %q", s[0], s[1])
+		}
+	}
+
+	ints := [][2]int{
+		{LimitDefault, handlers.LimitDefault},
+		{LimitMax, handlers.LimitMax},
+		{LookupMax, handlers.LookupMax},
+	}
+	for _, n := range ints {
+		if n[0] != n[1] {
+			t.Errorf("client has %d where the server has %d", n[0], n[1])
+		}
+	}
+}
+
+// jsonFields returns the names of the fields of a struct type in JSON, sorted.
+func jsonFields(typ reflect.Type) []string {
+	var names []string
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		tag := field.Tag.Get("json")
+		if field.Anonymous && tag == "" {
+			names = append(names, jsonFields(field.Type)...)
+			continue
+		}
+		name, _, _ := strings.Cut(tag, ",")
+		if !field.IsExported() || name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func TestTypesMatchServer(t *testing.T) {
+	types := []struct{ client, server interface{} }{
+		{VideosResponse{}, response.VideosResponse{}},
+		{FacetCount{}, store.FacetCount{}},
+		{LookupResponse{}, response.LookupResponse{}},
+		{Revision{}, response.RevisionResponse{}},
+		{HistoryResponse{}, response.HistoryResponse{}},
+		{SavedSearchResponse{}, response.SavedSearchResponse{}},
+		{APIKeyRequest{}, handlers.APIKeyRequest{}},
+		{APIKeyResponse{}, response.APIKeyResponse{}},
+		{AuditEntryResponse{}, response.AuditEntryResponse{}},
+		{AuditLogResponse{}, response.AuditLogResponse{}},
+		{savedSearchRequest{}, handlers.SavedSearchRequest{}},
+		{lookupRequest{}, handlers.LookupRequest{}},
+		{errResponse{}, response.ErrResponse{}},
+	}
+	for _, tt := range types {
+		got := jsonFields(reflect.TypeOf(tt.client))
+		want := jsonFields(reflect.TypeOf(tt.server))
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%T has fields %v, where %T has %v", tt.client, got, tt.server, want)
+		}
+	}
+
+	// Videos are encoded in their shape, so the fields of a fully shaped one are compared
+	removed := yt.Video{RemovalStatus: yt.RemovalDeleted}
+	body, err := json.Marshal(response.NewVideoResponse(removed, response.FullShape))
+	if err != nil {
+		t.Fatalf("encode a video: %v", err)
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(body, &fields); err != nil {
+		t.Fatalf("decode a video: %v", err)
+	}
+	var want []string
+	for name := range fields {
+		want = append(want, name)
+	}
+	sort.Strings(want)
+	if got := jsonFields(reflect.TypeOf(Video{})); !reflect.DeepEqual(got, want) {
+		t.Errorf("Video has fields %v, where the server encodes %v", got, want)
+	}
+}
+
+func TestFilterParamsMatchServer(t *testing.T) {
+	f := Filter{
+		PublishedAfter:  epoch.Add(-time.Hour),
+		PublishedBefore: epoch,
+		ChannelId:       "channel",
+		MinDuration:     time.Minute,
+		MaxDuration:     time.Hour,
+		MinViews:        10,
+		MaxViews:        1000,
+		Type:            TypeLive,
+		Language:        "en",
+		CategoryId:      "20",
+		Removed:         RemovedInclude,
+	}
+	want := handlers.FilterParams(store.Filter{
+		PublishedAfter:       f.PublishedAfter,
+		PublishedBefore:      f.PublishedBefore,
+		ChannelId:            f.ChannelId,
+		MinDuration:          f.MinDuration,
+		MaxDuration:          f.MaxDuration,
+		MinViews:             f.MinViews,
+		MaxViews:             f.MaxViews,
+		LiveBroadcastContent: yt.BroadcastLive,
+		Language:             f.Language,
+		CategoryId:           f.CategoryId,
+		Removed:              f.Removed,
+	})
+	if got := f.params(); !reflect.DeepEqual(got, want) {
+		t.Errorf("params = %v, want %v", got, want)
+	}
+}
+
+func TestParseFilterMatchesServer(t *testing.T) {
+	f := Filter{
+		PublishedAfter:  epoch.Add(-time.Hour),
+		PublishedBefore: epoch,
+		ChannelId:       "channel",
+		MinDuration:     time.Minute,
+		MaxDuration:     time.Hour,
+		MinViews:        10,
+		MaxViews:        1000,
+		Type:            TypeLive,
+		Language:        "en",
+		CategoryId:      "20",
+		Removed:         RemovedInclude,
+	}
+	got, err := ParseFilter(f.params())
+	if err != nil || !reflect.DeepEqual(got, f) {
+		t.Errorf("ParseFilter(params) = %+v, %v, want %+v", got, err, f)
+	}
+
+	tests := []map[string]string{
+		{"after": "yesterday"},
+		{"after": epoch.Format(queryTimeFmt), "before": epoch.Format(queryTimeFmt)},
+		{"min_duration": "-1"},
+		{"min_duration": "60", "max_duration": "30"},
+		{"min_views": "many"},
+		{"min_views": ""},
+		{"min_views": "10", "max_views": "5"},
+		{"type": "short"},
+		{"removed": "sometimes"},
+		{"channel": "channel", "lang": "en", "removed": "exclude"},
+	}
+	for _, params := range tests {
+		query := url.Values{}
+		for param, value := range params {
+			query.Set(param, value)
+		}
+		_, want := handlers.ParseFilterParams(query)
+		if _, err := ParseFilter(params); (err == nil) != (want == nil) {
+			t.Errorf("ParseFilter(%v) = %v, where the server returns %v", params, err, want)
+		}
+	}
+}
+
+func TestVideos(t *testing.T) {
+	ts := newTestServer(t)
+	ts.save(t, 5)
+	c := ts.client(t)
+	ctx := context.Background()
+
+	it := c.Videos(ctx, VideosQuery{PageSize: 2, Expand: []string{ExpandChannel, ExpandStats}})
+	var videos []Video
+	for it.Next() {
+		videos = append(videos, it.Video())
+	}
+	if err := it.Err(); err != nil {
+		t.Fatalf("Videos: %v", err)
+	}
+	want := []string{"video0", "video1", "video2", "video3", "video4"}
+	if ids := videoIDs(videos); !reflect.DeepEqual(ids, want) {
+		t.Errorf("Videos = %v, want %v", ids, want)
+	}
+	v := videos[1]
+	if v.Channel == nil || v.Channel.Title != "Channel" || v.Stats == nil ||
+		v.Stats.ViewCount != 2000 || !v.PublishedAt.Equal(epoch.Add(-time.Minute)) {
+		t.Errorf("video1 = %+v, channel %+v, stats %+v", v, v.Channel, v.Stats)
+	}
+
+	it = c.Videos(ctx, VideosQuery{
+		Search: "numbered 3",
+		Filter: Filter{MinViews: 2000},
+	})
+	videos = nil
+	for it.Next() {
+		videos = append(videos, it.Video())
+	}
+	if err := it.Err(); err != nil {
+		t.Fatalf("Videos: %v", err)
+	}
+	if ids := videoIDs(videos); !reflect.DeepEqual(ids, []string{"video3"}) {
+		t.Errorf("search of Videos = %v, want [video3]", ids)
+	}
+
+	it = c.Videos(ctx, VideosQuery{Search: "video numbred"})
+	if it.Next() {
+		t.Errorf("search with a typo matched %s", it.Video().VideoId)
+	}
+	if err := it.Err(); err != nil {
+		t.Fatalf("Videos: %v", err)
+	}
+	if len(it.Suggestions()) == 0 {
+		t.Errorf("search with a typo has no suggestions")
+	}
+}
+
+func TestNaturalSearch(t *testing.T) {
+	ts := newTestServer(t)
+	ts.save(t, 3)
+	c := ts.client(t)
+
+	resp, err := c.NaturalSearch(context.Background(), VideosQuery{Search: "video", PageSize: 2},
+		FacetChannel)
+	if err != nil {
+		t.Fatalf("NaturalSearch: %v", err)
+	}
+	if ids := videoIDs(resp.Videos); !reflect.DeepEqual(ids, []string{"video0", "video1"}) {
+		t.Errorf("NaturalSearch = %v, want [video0 video1]", ids)
+	}
+	want := map[Facet][]FacetCount{
+		FacetChannel: {{Value: "channel", Label: "Channel", Count: 3}},
+	}
+	if !reflect.DeepEqual(resp.Facets, want) {
+		t.Errorf("facets of NaturalSearch = %+v, want %+v", resp.Facets, want)
+	}
+}
+
+func TestVideoLookupAndHistory(t *testing.T) {
+	ts := newTestServer(t)
+	videos := ts.save(t, 2)
+	c := ts.client(t)
+	ctx := context.Background()
+
+	video, err := c.Video(ctx, "video1", ExpandStats)
+	if err != nil {
+		t.Fatalf("Video: %v", err)
+	}
+	if video.Title != "Video 1" || video.Stats == nil || video.Stats.ViewCount != 2000 ||
+		video.Channel != nil || video.FirstSeenAt.IsZero() || video.RemovedAt != nil {
+		t.Errorf("Video = %+v, stats %+v", video, video.Stats)
+	}
+
+	_, err = c.Video(ctx, "missing")
+	var apiErr *Error
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusNotFound ||
+		apiErr.Status != "not found" {
+		t.Errorf("Video of a missing video: %v, want a not found *Error", err)
+	}
+
+	lookup, err := c.Lookup(ctx, []string{"video1", "missing", "video0"})
+	if err != nil {
+		t.Fatalf("Lookup: %v", err)
+	}
+	var found []string
+	for _, v := range lookup.Videos {
+		found = append(found, v.VideoId)
+	}
+	if !reflect.DeepEqual(found, []string{"video1", "video0"}) ||
+		!reflect.DeepEqual(lookup.Missing, []string{"missing"}) {
+		t.Errorf("Lookup = %v, missing %v", found, lookup.Missing)
+	}
+
+	videos[0].Title = "Video 0, retitled"
+	if _, err := ts.videos.Save(videos); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	history, err := c.History(ctx, "video0")
+	if err != nil {
+		t.Fatalf("History: %v", err)
+	}
+	var titles []string
+	for _, r := range history.Revisions {
+		titles = append(titles, r.Title)
+	}
+	if want := []string{"Video 0", "Video 0, retitled"}; !reflect.DeepEqual(titles, want) ||
+		history.Revisions[0].ReplacedAt == nil || history.Revisions[1].ReplacedAt != nil {
+		t.Errorf("History = %+v, want the titles %q", history.Revisions, want)
+	}
+}
+
+func TestExport(t *testing.T) {
+	ts := newTestServer(t)
+	ts.save(t, 3)
+	c := ts.client(t)
+
+	var videos []Video
+	err := c.Export(context.Background(), Filter{MaxViews: 2000}, func(v *Video) error {
+		videos = append(videos, *v)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Export: %v", err)
+	}
+	if ids := videoIDs(videos); !reflect.DeepEqual(ids, []string{"video0", "video1"}) {
+		t.Errorf("Export = %v, want [video0 video1]", ids)
+	}
+	if len(videos) > 0 && (videos[0].Channel == nil || videos[0].Stats == nil) {
+		t.Errorf("exported video = %+v, want its channel and stats embedded", videos[0])
+	}
+}
+
+func TestSavedSearches(t *testing.T) {
+	ts := newTestServer(t)
+	ctx := context.Background()
+	search := SavedSearch{Name: "numbered", Search: "numbered", Filter: Filter{MinViews: 2000}}
+
+	// Changes take an editor's key, though the test server lets anyone read
+	var apiErr *Error
+	if _, err := ts.client(t).CreateSavedSearch(ctx, search); !errors.As(err, &apiErr) ||
+		apiErr.StatusCode != http.StatusUnauthorized {
+		t.Errorf("CreateSavedSearch without a key: %v, want an unauthorized *Error", err)
+	}
+	key, err := ts.client(t, WithAPIKey(adminToken)).IssueAPIKey(ctx,
+		APIKeyRequest{Name: "editor", Role: RoleEditor})
+	if err != nil {
+		t.Fatalf("IssueAPIKey: %v", err)
+	}
+	c := ts.client(t, WithAPIKey(key.Key))
+
+	saved, err := c.CreateSavedSearch(ctx, search)
+	if err != nil {
+		t.Fatalf("CreateSavedSearch: %v", err)
+	}
+	if saved.Mode != ModeLike || saved.Filters[paramMinViews] != "2000" {
+		t.Errorf("CreateSavedSearch = %+v", saved)
+	}
+
+	list, err := c.SavedSearches(ctx)
+	if err != nil {
+		t.Fatalf("SavedSearches: %v", err)
+	}
+	if len(list) != 1 || list[0].ID != saved.ID {
+		t.Errorf("SavedSearches = %+v, want the saved search", list)
+	}
+
+	// Videos first seen once the search is saved are new to it
+	ts.save(t, 3)
+	it := c.NewVideos(ctx, saved.ID, 10)
+	var videos []Video
+	for it.Next() {
+		videos = append(videos, it.Video())
+	}
+	if err := it.Err(); err != nil {
+		t.Fatalf("NewVideos: %v", err)
+	}
+	if ids := videoIDs(videos); !reflect.DeepEqual(ids, []string{"video1", "video2"}) {
+		t.Errorf("NewVideos = %v, want [video1 video2]", ids)
+	}
+
+	if err := c.DeleteSavedSearch(ctx, saved.ID); err != nil {
+		t.Fatalf("DeleteSavedSearch: %v", err)
+	}
+	if _, err := c.SavedSearch(ctx, saved.ID); !errors.As(err, &apiErr) ||
+		apiErr.StatusCode != http.StatusNotFound {
+		t.Errorf("SavedSearch of a deleted search: %v, want a not found *Error", err)
+	}
+}
+
+func TestAdmin(t *testing.T) {
+	ts := newTestServer(t)
+	ctx := context.Background()
+
+	var apiErr *Error
+	if _, err := ts.client(t).APIKeys(ctx); !errors.As(err, &apiErr) ||
+		apiErr.StatusCode != http.StatusUnauthorized {
+		t.Errorf("APIKeys without a key: %v, want an unauthorized *Error", err)
+	}
+
+	admin := ts.client(t, WithAPIKey(adminToken))
+	key, err := admin.IssueAPIKey(ctx, APIKeyRequest{Name: "reader"})
+	if err != nil {
+		t.Fatalf("IssueAPIKey: %v", err)
+	}
+	if key.Key == "" || key.Role != RoleReader {
+		t.Errorf("IssueAPIKey = %+v, want a reader's key", key)
+	}
+
+	reader := ts.client(t, WithAPIKey(key.Key))
+	if _, err := reader.APIKeys(ctx); !errors.As(err, &apiErr) ||
+		apiErr.StatusCode != http.StatusForbidden {
+		t.Errorf("APIKeys with a reader's key: %v, want a forbidden *Error", err)
+	}
+
+	revoked, err := admin.RevokeAPIKey(ctx, key.ID)
+	if err != nil {
+		t.Fatalf("RevokeAPIKey: %v", err)
+	}
+	if revoked.RevokedAt == nil {
+		t.Errorf("RevokeAPIKey = %+v, want it revoked", revoked)
+	}
+	keys, err := admin.APIKeys(ctx)
+	if err != nil {
+		t.Fatalf("APIKeys: %v", err)
+	}
+	if len(keys) != 1 || keys[0].ID != key.ID || keys[0].Key != "" {
+		t.Errorf("APIKeys = %+v, want the issued key, without its secret", keys)
+	}
+
+	audit, err := admin.AuditLog(ctx, 0, 10)
+	if err != nil {
+		t.Fatalf("AuditLog: %v", err)
+	}
+	if len(audit.Entries) != 2 {
+		t.Errorf("AuditLog has %d entries, want the issue and the revocation",
+			len(audit.Entries))
+	}
+}
+
+func TestStream(t *testing.T) {
+	ts := newTestServer(t)
+	c := ts.client(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// Videos are only streamed to subscribers, so they are published until one is received
+	go func() {
+		video := yt.Video{VideoId: "streamed", ChannelId: "channel", ChannelTitle: "Channel"}
+		for ctx.Err() == nil {
+			ts.newVideos.Publish([]yt.Video{video})
+			time.Sleep(10 * time.Millisecond)
+		}
+	}()
+
+	stop := errors.New("stop")
+	var got *Video
+	err := c.Stream(ctx, func(v *Video) error {
+		got = v
+		return stop
+	}, ExpandChannel)
+	if !errors.Is(err, stop) {
+		t.Fatalf("Stream: %v, want the error of fn", err)
+	}
+	if got.VideoId != "streamed" || got.Channel == nil || got.Channel.Title != "Channel" {
+		t.Errorf("streamed video = %+v, channel %+v", got, got.Channel)
+	}
+}
+
+func TestRetries(t *testing.T) {
+	var requests int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&requests, 1) < 3 {
+			w.Header().Set(headerRequestID, "request")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			_, _ = w.Write([]byte(`{"status": "unavailable"}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"video_id": "video0"}`))
+	}))
+	defer srv.Close()
+	ctx := context.Background()
+
+	c, err := New(srv.URL, WithRetries(2, time.Millisecond))
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	video, err := c.Video(ctx, "video0")
+	if err != nil || video.VideoId != "video0" || requests != 3 {
+		t.Errorf("Video after 2 failures = %+v, %v in %d requests", video, err, requests)
+	}
+
+	atomic.StoreInt32(&requests, 0)
+	c, err = New(srv.URL, WithRetries(1, time.Millisecond))
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	_, err = c.Video(ctx, "video0")
+	var apiErr *Error
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusServiceUnavailable ||
+		apiErr.Status != "unavailable" || apiErr.RequestID != "request" {
+		t.Errorf("Video after 2 failures with 1 retry: %v", err)
+	}
+}
@@ -0,0 +1,263 @@
+package client
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// Prefix is the path the current version of the API is served under.
+const Prefix = "/v1"
+
+// Query parameters of the API. See the OpenAPI spec served at Prefix + "/openapi.json".
+const (
+	paramFrom   = "from"
+	paramLimit  = "limit"
+	paramSearch = "search"
+	paramFuzzy  = "fuzzy"
+	paramFacets = "facets"
+
+	paramAfter       = "after"
+	paramBefore      = "before"
+	paramChannel     = "channel"
+	paramMinDuration = "min_duration"
+	paramMaxDuration = "max_duration"
+	paramMinViews    = "min_views"
+	paramMaxViews    = "max_views"
+	paramType        = "type"
+	paramLanguage    = "lang"
+	paramCategory    = "category"
+	paramRemoved     = "removed"
+
+	paramFields = "fields"
+	paramExpand = "expand"
+	paramFormat = "format"
+
+	formatNDJSON = "ndjson"
+
+	// queryTimeFmt is the format of times in queries.
+	queryTimeFmt = time.RFC3339
+)
+
+const (
+	// headerAPIKey is the header API keys are passed in.
+	headerAPIKey = "X-API-Key"
+	// headerRequestID is the header responses carry the ID of their request in.
+	headerRequestID = "X-Request-Id"
+
+	// eventVideo is the server-sent event carrying a new video in streams.
+	eventVideo = "video"
+)
+
+const (
+	// LimitDefault is the number of videos in a page when none is requested, and LimitMax
+	// the most a page can hold.
+	LimitDefault = 10
+	LimitMax     = 20
+
+	// LookupMax is the maximum number of videos that can be looked up at a time.
+	LookupMax = 100
+)
+
+// Related objects that can be embedded in videos.
+const (
+	ExpandChannel = "channel"
+	ExpandStats   = "stats"
+)
+
+// Values of Filter.Type.
+const (
+	TypeUpload   = "upload"
+	TypeLive     = "live"
+	TypeUpcoming = "upcoming"
+)
+
+// Values of Filter.Removed.
+const (
+	// RemovedExclude leaves out videos removed from YouTube, as filters do by default.
+	RemovedExclude = "exclude"
+	// RemovedInclude matches removed videos along with the rest.
+	RemovedInclude = "include"
+	// RemovedOnly matches removed videos alone.
+	RemovedOnly = "only"
+)
+
+// SearchMode is the kind of search a saved search runs.
+type SearchMode string
+
+const (
+	// ModeLike matches videos whose title or description contain the search.
+	ModeLike SearchMode = "like"
+	// ModeFuzzy tolerates typos.
+	ModeFuzzy SearchMode = "fuzzy"
+	// ModeNatural searches in natural language.
+	ModeNatural SearchMode = "natural"
+)
+
+// Facet is a dimension by which the results of a natural-language search can be counted.
+type Facet string
+
+const (
+	FacetChannel  Facet = "channel"
+	FacetDay      Facet = "day"
+	FacetCategory Facet = "category"
+	FacetDuration Facet = "duration"
+	// FacetWatch counts videos by the saved searches that match them.
+	FacetWatch Facet = "watch"
+)
+
+// Role is the role granted to an API key.
+type Role string
+
+const (
+	// RoleReader may read videos and saved searches.
+	RoleReader Role = "reader"
+	// RoleEditor may also create and delete saved searches.
+	RoleEditor Role = "editor"
+	// RoleAdmin may also manage API keys and read the audit log.
+	RoleAdmin Role = "admin"
+)
+
+// Filter narrows down the videos listed or searched. Zero-valued fields do not filter.
+type Filter struct {
+	PublishedAfter  time.Time
+	PublishedBefore time.Time
+
+	ChannelId string
+
+	MinDuration time.Duration
+	MaxDuration time.Duration
+
+	MinViews int64
+	MaxViews int64
+
+	// Type is one of TypeUpload, TypeLive or TypeUpcoming.
+	Type string
+
+	// Language matches a language tag along with its regional variants, eg: "en" also
+	// matches "en-US".
+	Language   string
+	CategoryId string
+
+	// Removed is one of the Removed* values, and RemovedExclude if empty.
+	Removed string
+}
+
+// params returns the query parameters describing the filter.
+func (f Filter) params() map[string]string {
+	params := map[string]string{}
+	setTime := func(param string, t time.Time) {
+		if !t.IsZero() {
+			params[param] = t.Format(queryTimeFmt)
+		}
+	}
+	setInt := func(param string, n int64) {
+		if n > 0 {
+			params[param] = strconv.FormatInt(n, 10)
+		}
+	}
+	setString := func(param, s string) {
+		if s != "" {
+			params[param] = s
+		}
+	}
+
+	setTime(paramAfter, f.PublishedAfter)
+	setTime(paramBefore, f.PublishedBefore)
+	setString(paramChannel, f.ChannelId)
+	setInt(paramMinDuration, int64(f.MinDuration.Seconds()))
+	setInt(paramMaxDuration, int64(f.MaxDuration.Seconds()))
+	setInt(paramMinViews, f.MinViews)
+	setInt(paramMaxViews, f.MaxViews)
+	setString(paramType, f.Type)
+	setString(paramLanguage, f.Language)
+	setString(paramCategory, f.CategoryId)
+	setString(paramRemoved, f.Removed)
+
+	return params
+}
+
+// ParseFilter returns the filter described by query parameters, such as those of
+// SavedSearchResponse.Filters, validated as the API would.
+func ParseFilter(params map[string]string) (Filter, error) {
+	var f Filter
+	invalid := func(param string) error {
+		return fmt.Errorf("invalid %s param", param)
+	}
+	parseTime := func(param string) (time.Time, error) {
+		v, ok := params[param]
+		if !ok {
+			return time.Time{}, nil
+		}
+		t, err := time.Parse(queryTimeFmt, v)
+		if err != nil {
+			return t, invalid(param)
+		}
+		return t, nil
+	}
+	parseInt := func(param string) (int64, error) {
+		v, ok := params[param]
+		if !ok {
+			return 0, nil
+		}
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || n < 0 {
+			return 0, invalid(param)
+		}
+		return n, nil
+	}
+
+	var err error
+	if f.PublishedAfter, err = parseTime(paramAfter); err != nil {
+		return f, err
+	}
+	if f.PublishedBefore, err = parseTime(paramBefore); err != nil {
+		return f, err
+	}
+	if !f.PublishedAfter.IsZero() && !f.PublishedBefore.IsZero() &&
+		!f.PublishedAfter.Before(f.PublishedBefore) {
+		return f, fmt.Errorf("%s must be before %s", paramAfter, paramBefore)
+	}
+
+	minDuration, err := parseInt(paramMinDuration)
+	if err != nil {
+		return f, err
+	}
+	maxDuration, err := parseInt(paramMaxDuration)
+	if err != nil {
+		return f, err
+	}
+	if maxDuration > 0 && minDuration > maxDuration {
+		return f, fmt.Errorf("%s exceeds %s", paramMinDuration, paramMaxDuration)
+	}
+	f.MinDuration = time.Duration(minDuration) * time.Second
+	f.MaxDuration = time.Duration(maxDuration) * time.Second
+
+	if f.MinViews, err = parseInt(paramMinViews); err != nil {
+		return f, err
+	}
+	if f.MaxViews, err = parseInt(paramMaxViews); err != nil {
+		return f, err
+	}
+	if f.MaxViews > 0 && f.MinViews > f.MaxViews {
+		return f, fmt.Errorf("%s exceeds %s", paramMinViews, paramMaxViews)
+	}
+
+	switch f.Type = params[paramType]; f.Type {
+	case "", TypeUpload, TypeLive, TypeUpcoming:
+	default:
+		return f, invalid(paramType)
+	}
+
+	f.ChannelId = params[paramChannel]
+	f.Language = params[paramLanguage]
+	f.CategoryId = params[paramCategory]
+
+	switch f.Removed = params[paramRemoved]; f.Removed {
+	case "", RemovedExclude, RemovedInclude, RemovedOnly:
+	default:
+		return f, invalid(paramRemoved)
+	}
+
+	return f, nil
+}
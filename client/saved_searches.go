@@ -0,0 +1,79 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// SavedSearch describes a search to save.
+type SavedSearch struct {
+	Name   string
+	Search string
+	// Mode is the mode of the search, ModeLike by default.
+	Mode   SearchMode
+	Filter Filter
+	// RetentionDays is the number of days the videos matching the search are kept for, the
+	// server's default if zero.
+	RetentionDays int
+}
+
+// CreateSavedSearch saves a search. It takes an editor's API key, even from servers that
+// let anyone read.
+func (c *Client) CreateSavedSearch(ctx context.Context, s SavedSearch,
+) (*SavedSearchResponse, error) {
+	req := savedSearchRequest{
+		Name:    s.Name,
+		Search:  s.Search,
+		Mode:    s.Mode,
+		Filters: s.Filter.params(),
+
+		RetentionDays: s.RetentionDays,
+	}
+	var saved SavedSearchResponse
+	if err := c.do(ctx, http.MethodPost, "/saved_searches", req, &saved); err != nil {
+		return nil, err
+	}
+	return &saved, nil
+}
+
+// SavedSearches lists every saved search.
+func (c *Client) SavedSearches(ctx context.Context) ([]*SavedSearchResponse, error) {
+	var resp struct {
+		SavedSearches []*SavedSearchResponse `json:"saved_searches"`
+	}
+	if err := c.get(ctx, "/saved_searches", nil, &resp); err != nil {
+		return nil, err
+	}
+	return resp.SavedSearches, nil
+}
+
+// SavedSearch gets a saved search by its ID.
+func (c *Client) SavedSearch(ctx context.Context, id uint) (*SavedSearchResponse, error) {
+	var saved SavedSearchResponse
+	if err := c.get(ctx, savedSearchPath(id), nil, &saved); err != nil {
+		return nil, err
+	}
+	return &saved, nil
+}
+
+// DeleteSavedSearch deletes a saved search. Like CreateSavedSearch, it takes an editor's
+// API key.
+func (c *Client) DeleteSavedSearch(ctx context.Context, id uint) error {
+	return c.do(ctx, http.MethodDelete, savedSearchPath(id), nil, nil)
+}
+
+// NewVideos iterates over the videos matching a saved search that are new since it was last
+// checked, with the related objects in expand embedded. Requesting the first page marks them
+// as seen.
+func (c *Client) NewVideos(ctx context.Context, id uint, pageSize int, expand ...string,
+) *VideoIterator {
+	query := url.Values{}
+	setShape(query, nil, expand)
+	return c.iterate(ctx, savedSearchPath(id)+"/new", query, pageSize)
+}
+
+func savedSearchPath(id uint) string {
+	return "/saved_searches/" + strconv.FormatUint(uint64(id), 10)
+}
@@ -0,0 +1,159 @@
+package client
+
+import "time"
+
+// Video is a video, as the API renders it. Channel and Stats are only embedded on request,
+// with ExpandChannel and ExpandStats.
+type Video struct {
+	// ID identifies the video in the store the API serves.
+	ID uint `json:"id"`
+	// FirstSeenAt is the time the video was first stored.
+	FirstSeenAt time.Time `json:"first_seen_at"`
+	// RemovedAt is the time the video was found to be removed from YouTube, if it was, and
+	// RemovalStatus tells why.
+	RemovedAt     *time.Time `json:"removed_at"`
+	RemovalStatus string     `json:"removal_status,omitempty"`
+
+	VideoId      string    `json:"video_id"`
+	Title        string    `json:"title"`
+	Description  string    `json:"description"`
+	PublishedAt  time.Time `json:"published_at"`
+	ThumbnailUrl string    `json:"thumbnail_url"`
+
+	ChannelId string `json:"channel_id"`
+	// LiveBroadcastContent is "none" for uploads, or "live" or "upcoming".
+	LiveBroadcastContent string `json:"live_broadcast_content"`
+
+	DurationSeconds int64  `json:"duration_seconds"`
+	DefaultLanguage string `json:"default_language"`
+	CategoryId      string `json:"category_id"`
+
+	Channel *Channel `json:"channel,omitempty"`
+	Stats   *Stats   `json:"stats,omitempty"`
+}
+
+type Channel struct {
+	ID    string `json:"id"`
+	Title string `json:"title"`
+}
+
+type Stats struct {
+	ViewCount    int64 `json:"view_count"`
+	LikeCount    int64 `json:"like_count"`
+	CommentCount int64 `json:"comment_count"`
+}
+
+type VideosResponse struct {
+	Videos []Video `json:"videos"`
+	// Next is the from of the next page.
+	Next int64 `json:"next"`
+
+	// Suggestions holds "did you mean" alternatives for searches that matched nothing.
+	Suggestions []string `json:"suggestions,omitempty"`
+
+	// Facets holds counts of the matched videos by each requested facet.
+	Facets map[Facet][]FacetCount `json:"facets,omitempty"`
+}
+
+// FacetCount is the number of videos sharing a value of some Facet.
+type FacetCount struct {
+	Value string `json:"value"`
+	// Label is a human-readable name for the value, where one is available.
+	Label string `json:"label,omitempty"`
+	Count int64  `json:"count"`
+}
+
+type LookupResponse struct {
+	Videos []*Video `json:"videos"`
+	// Missing lists the requested IDs of videos that are not in the store.
+	Missing []string `json:"missing"`
+}
+
+// Revision is a version of the content of a video.
+type Revision struct {
+	Title        string    `json:"title"`
+	Description  string    `json:"description"`
+	ThumbnailUrl string    `json:"thumbnail_url"`
+	SeenAt       time.Time `json:"seen_at"`
+	// ReplacedAt is nil for the current version.
+	ReplacedAt *time.Time `json:"replaced_at"`
+}
+
+// HistoryResponse is the history of the content of a video.
+type HistoryResponse struct {
+	VideoId string `json:"video_id"`
+	// Revisions are the versions of the video, oldest first, ending with the current one.
+	Revisions []Revision `json:"revisions"`
+}
+
+type SavedSearchResponse struct {
+	ID     uint       `json:"id"`
+	Name   string     `json:"name"`
+	Search string     `json:"search"`
+	Mode   SearchMode `json:"mode"`
+	// Filters holds the filters of the search, as query parameters.
+	Filters   map[string]string `json:"filters"`
+	CreatedAt time.Time         `json:"created_at"`
+	CheckedAt time.Time         `json:"checked_at"`
+	// RetentionDays is zero when the server's default retention applies.
+	RetentionDays int `json:"retention_days"`
+}
+
+// APIKeyRequest describes an API key to issue.
+type APIKeyRequest struct {
+	Name string `json:"name"`
+	// RateLimit is the number of requests per minute to allow with the key, zero for the
+	// server default.
+	RateLimit int `json:"rate_limit"`
+	// Role is the role granted to the key, RoleReader by default.
+	Role Role `json:"role"`
+}
+
+type APIKeyResponse struct {
+	ID        uint       `json:"id"`
+	Name      string     `json:"name"`
+	Prefix    string     `json:"prefix"`
+	RateLimit int        `json:"rate_limit,omitempty"`
+	Role      Role       `json:"role"`
+	CreatedAt time.Time  `json:"created_at"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+	// Key is the secret API key, only ever included in the response to its issue.
+	Key string `json:"key,omitempty"`
+}
+
+type AuditEntryResponse struct {
+	ID         uint      `json:"id"`
+	CreatedAt  time.Time `json:"created_at"`
+	ActorKeyID *uint     `json:"actor_key_id,omitempty"`
+	Actor      string    `json:"actor"`
+	Action     string    `json:"action"`
+	Target     string    `json:"target"`
+	Detail     string    `json:"detail,omitempty"`
+	Status     int       `json:"status"`
+}
+
+type AuditLogResponse struct {
+	Entries []AuditEntryResponse `json:"entries"`
+	// Next is the from of the next page.
+	Next uint `json:"next"`
+}
+
+// savedSearchRequest, lookupRequest and errResponse are the bodies of requests and error
+// responses.
+type savedSearchRequest struct {
+	Name          string            `json:"name"`
+	Search        string            `json:"search"`
+	Mode          SearchMode        `json:"mode"`
+	Filters       map[string]string `json:"filters"`
+	RetentionDays int               `json:"retention_days"`
+}
+
+type lookupRequest struct {
+	IDs []string `json:"ids"`
+}
+
+type errResponse struct {
+	StatusText string `json:"status"`
+	ErrorText  string `json:"error,omitempty"`
+	RequestID  string `json:"request_id,omitempty"`
+}
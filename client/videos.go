@@ -0,0 +1,268 @@
+package client
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// VideosQuery selects videos to list or search.
+type VideosQuery struct {
+	// Search matches videos by their title and description, if set.
+	Search string
+	// Fuzzy tolerates typos in Search.
+	Fuzzy  bool
+	Filter Filter
+
+	// PageSize is the number of videos requested at a time, up to LimitMax.
+	PageSize int
+
+	// Fields selects the fields of videos, by their JSON keys, and Expand the related objects
+	// embedded in them, ExpandChannel and ExpandStats.
+	Fields []string
+	Expand []string
+}
+
+func (q *VideosQuery) values() url.Values {
+	query := url.Values{}
+	for param, value := range q.Filter.params() {
+		query.Set(param, value)
+	}
+	if q.Search != "" {
+		query.Set(paramSearch, q.Search)
+	}
+	if q.Fuzzy {
+		query.Set(paramFuzzy, "true")
+	}
+	setShape(query, q.Fields, q.Expand)
+	return query
+}
+
+// VideoIterator iterates over videos across pages of responses, requesting a page at a time:
+//
+//	it := c.Videos(ctx, client.VideosQuery{Search: "golang"})
+//	for it.Next() {
+//		fmt.Println(it.Video().Title)
+//	}
+//	if err := it.Err(); err != nil { ... }
+type VideoIterator struct {
+	c     *Client
+	ctx   context.Context
+	path  string
+	query url.Values
+	limit int
+
+	page []Video
+	i    int
+	done bool
+	err  error
+
+	suggestions []string
+}
+
+func (c *Client) iterate(ctx context.Context, path string, query url.Values, pageSize int,
+) *VideoIterator {
+	if pageSize <= 0 {
+		pageSize = LimitDefault
+	}
+	if pageSize > LimitMax {
+		pageSize = LimitMax
+	}
+	query.Set(paramLimit, strconv.Itoa(pageSize))
+	return &VideoIterator{c: c, ctx: ctx, path: path, query: query, limit: pageSize, i: -1}
+}
+
+// Next advances to the next video, requesting the next page when the current one runs out.
+// It returns false once the videos are exhausted or a request fails.
+func (it *VideoIterator) Next() bool {
+	if it.i+1 < len(it.page) {
+		it.i++
+		return true
+	}
+	if it.done || it.err != nil {
+		return false
+	}
+
+	var resp VideosResponse
+	if it.err = it.c.get(it.ctx, it.path, it.query, &resp); it.err != nil {
+		return false
+	}
+	if it.page == nil {
+		it.suggestions = resp.Suggestions
+	}
+
+	it.page, it.i = resp.Videos, 0
+	// A short page is the last
+	it.done = len(resp.Videos) < it.limit
+	it.query.Set(paramFrom, strconv.FormatInt(resp.Next, 10))
+	return len(it.page) > 0
+}
+
+// Video returns the current video.
+func (it *VideoIterator) Video() Video {
+	return it.page[it.i]
+}
+
+// Err returns the error that ended iteration, if any.
+func (it *VideoIterator) Err() error {
+	return it.err
+}
+
+// Suggestions returns the "did you mean" alternatives to a search that matched nothing, once
+// the first page is requested.
+func (it *VideoIterator) Suggestions() []string {
+	return it.suggestions
+}
+
+// Videos iterates over the videos matching a query, latest first.
+func (c *Client) Videos(ctx context.Context, q VideosQuery) *VideoIterator {
+	return c.iterate(ctx, "/videos", q.values(), q.PageSize)
+}
+
+// Video gets a video by its ID, with the related objects in expand embedded.
+func (c *Client) Video(ctx context.Context, id string, expand ...string,
+) (*Video, error) {
+	query := url.Values{}
+	setShape(query, nil, expand)
+
+	var video Video
+	if err := c.get(ctx, "/videos/"+url.PathEscape(id), query, &video); err != nil {
+		return nil, err
+	}
+	return &video, nil
+}
+
+// History gets the versions of the title, description and thumbnail of a video by its ID,
+// oldest first, ending with the current one.
+func (c *Client) History(ctx context.Context, id string) (*HistoryResponse, error) {
+	var history HistoryResponse
+	if err := c.get(ctx, "/videos/"+url.PathEscape(id)+"/history", nil, &history); err != nil {
+		return nil, err
+	}
+	return &history, nil
+}
+
+// Lookup gets videos in bulk by their IDs, up to LookupMax at a time, with the
+// related objects in expand embedded.
+func (c *Client) Lookup(ctx context.Context, ids []string, expand ...string,
+) (*LookupResponse, error) {
+	query := url.Values{}
+	setShape(query, nil, expand)
+
+	var resp LookupResponse
+	err := c.do(ctx, http.MethodPost, withQuery("/videos/lookup", query),
+		lookupRequest{IDs: ids}, &resp)
+	if err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// NaturalSearch searches videos in natural language, counting the matches by facets, if any.
+// Natural-language searches yield a single page of up to q.PageSize videos, and ignore
+// q.Fuzzy.
+func (c *Client) NaturalSearch(ctx context.Context, q VideosQuery, facets ...Facet,
+) (*VideosResponse, error) {
+	query := q.values()
+	if q.PageSize > 0 {
+		query.Set(paramLimit, strconv.Itoa(q.PageSize))
+	}
+	query.Del(paramFuzzy)
+	if len(facets) > 0 {
+		names := make([]string, len(facets))
+		for i, f := range facets {
+			names[i] = string(f)
+		}
+		query.Set(paramFacets, strings.Join(names, ","))
+	}
+
+	var resp VideosResponse
+	if err := c.get(ctx, "/videos_search", query, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// Export calls fn with every video matching a filter, oldest first, with every related object
+// embedded. Unlike Videos, the export is a single request, and is not retried once underway.
+func (c *Client) Export(ctx context.Context, filter Filter, fn func(v *Video) error) error {
+	query := url.Values{paramFormat: {formatNDJSON}}
+	for param, value := range filter.params() {
+		query.Set(param, value)
+	}
+
+	resp, err := c.request(ctx, http.MethodGet, withQuery("/export", query), nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	dec := json.NewDecoder(resp.Body)
+	for dec.More() {
+		var v Video
+		if err := dec.Decode(&v); err != nil {
+			return err
+		}
+		if err := fn(&v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Stream calls fn with videos as they are stored, with the related objects in expand
+// embedded, until fn fails, the context expires or the server ends the stream.
+func (c *Client) Stream(ctx context.Context, fn func(v *Video) error,
+	expand ...string,
+) error {
+	query := url.Values{}
+	setShape(query, nil, expand)
+
+	resp, err := c.request(ctx, http.MethodGet, withQuery("/videos/stream", query), nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(nil, 1<<20)
+	event := ""
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case line == "":
+			event = ""
+		case strings.HasPrefix(line, "event: "):
+			event = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: ") && event == eventVideo:
+			var v Video
+			if err := json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &v); err != nil {
+				return err
+			}
+			if err := fn(&v); err != nil {
+				return err
+			}
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	return fmt.Errorf("stream ended")
+}
+
+func setShape(query url.Values, fields, expand []string) {
+	if len(fields) > 0 {
+		query.Set(paramFields, strings.Join(fields, ","))
+	}
+	if len(expand) > 0 {
+		query.Set(paramExpand, strings.Join(expand, ","))
+	}
+}
@@ -0,0 +1,103 @@
+// Command dump writes every video in the store to a file, removed videos included, to be
+// loaded into another store with cmd/load. It is configured from the environment as the
+// server is.
+//
+// Usage:
+//
+//	dump [-format ndjson|columnar] [-resume] <file>
+//
+// Dumps are checkpointed to <file>.checkpoint as they are written, and -resume continues an
+// interrupted dump from its checkpoint. Dumps to "-" are written to stdout, and cannot be
+// resumed.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"github.com/ditsuke/youtube-focus/config"
+	"github.com/ditsuke/youtube-focus/internal/yt"
+	"github.com/ditsuke/youtube-focus/store"
+	"github.com/ditsuke/youtube-focus/store/dump"
+	_ "github.com/joho/godotenv/autoload"
+	"github.com/sethvargo/go-envconfig"
+	"log"
+	"os"
+	"time"
+)
+
+// progressEvery is the least time between reports of progress.
+const progressEvery = 2 * time.Second
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: dump [-format ndjson|columnar] [-resume] <file>")
+	flag.PrintDefaults()
+	os.Exit(2)
+}
+
+func main() {
+	format := flag.String("format", dump.FormatNDJSON, "`format` of the dump: ndjson or columnar")
+	resume := flag.Bool("resume", false, "resume an interrupted dump from its checkpoint")
+	flag.Usage = usage
+	flag.Parse()
+	if flag.NArg() != 1 || (flag.Arg(0) == "-" && *resume) {
+		usage()
+	}
+	path := flag.Arg(0)
+	checkpointPath := path + ".checkpoint"
+
+	cfg := config.Config{}
+	if err := envconfig.Process(context.Background(), &cfg); err != nil {
+		log.Fatalln(err)
+	}
+	db, err := cfg.GetDB()
+	if err != nil {
+		log.Fatalln(err)
+	}
+	videos := &store.VideoMetaStore{DB: db}
+
+	var total int64
+	if err := db.Unscoped().Model(&yt.Video{}).Count(&total).Error; err != nil {
+		log.Fatalln(err)
+	}
+
+	cp := dump.Checkpoint{Format: *format}
+	if *resume {
+		if cp, err = dump.Resume(checkpointPath, *format); err != nil {
+			log.Fatalln(err)
+		}
+		if cp.Records > 0 {
+			log.Printf("resuming after %d videos", cp.Records)
+		}
+	}
+
+	out := os.Stdout
+	if path != "-" {
+		if out, err = dump.Open(path, cp.Offset); err != nil {
+			log.Fatalln(err)
+		}
+		defer out.Close()
+	}
+
+	reported := time.Now()
+	err = dump.Dump(videos, out, &cp, func(cp dump.Checkpoint) error {
+		if time.Since(reported) >= progressEvery {
+			log.Printf("dumped %d of %d videos", cp.Records, total)
+			reported = time.Now()
+		}
+		if path == "-" {
+			return nil
+		}
+		return cp.Save(checkpointPath)
+	})
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	if path != "-" {
+		if err := os.Remove(checkpointPath); err != nil && !os.IsNotExist(err) {
+			log.Fatalln(err)
+		}
+	}
+	log.Printf("dumped %d videos", cp.Records)
+}
@@ -0,0 +1,124 @@
+// Command load stores the videos of a dump written by cmd/dump, configured from the
+// environment as the server is. Videos already stored are skipped, by their video IDs, so
+// loads can safely be repeated.
+//
+// Usage:
+//
+//	load [-format ndjson|columnar] [-batch N] [-resume] <file>
+//
+// Loads are checkpointed to <file>.load-checkpoint after each batch, and -resume skips the
+// videos loaded before an interruption. Loads of "-" read stdin, and cannot be resumed.
+package main
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"github.com/ditsuke/youtube-focus/config"
+	"github.com/ditsuke/youtube-focus/store"
+	"github.com/ditsuke/youtube-focus/store/dump"
+	_ "github.com/joho/godotenv/autoload"
+	"github.com/sethvargo/go-envconfig"
+	"io"
+	"log"
+	"os"
+	"time"
+)
+
+// progressEvery is the least time between reports of progress.
+const progressEvery = 2 * time.Second
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: load [-format ndjson|columnar] [-batch N] [-resume] <file>")
+	flag.PrintDefaults()
+	os.Exit(2)
+}
+
+func main() {
+	format := flag.String("format", dump.FormatNDJSON, "`format` of the dump: ndjson or columnar")
+	batchSize := flag.Int("batch", 500, "number of videos stored per transaction")
+	resume := flag.Bool("resume", false, "skip the videos loaded before an interruption")
+	flag.Usage = usage
+	flag.Parse()
+	if flag.NArg() != 1 || *batchSize <= 0 || (flag.Arg(0) == "-" && *resume) {
+		usage()
+	}
+	path := flag.Arg(0)
+	checkpointPath := path + ".load-checkpoint"
+
+	cfg := config.Config{}
+	if err := envconfig.Process(context.Background(), &cfg); err != nil {
+		log.Fatalln(err)
+	}
+	db, err := cfg.GetDB()
+	if err != nil {
+		log.Fatalln(err)
+	}
+	videos := &store.VideoMetaStore{DB: db}
+
+	in, size := os.Stdin, int64(0)
+	if path != "-" {
+		if in, err = os.Open(path); err != nil {
+			log.Fatalln(err)
+		}
+		defer in.Close()
+		if info, err := in.Stat(); err == nil {
+			size = info.Size()
+		}
+	}
+	counter := &countingReader{r: in}
+	r, err := dump.NewReader(*format, bufio.NewReader(counter))
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	cp := dump.Checkpoint{Format: *format}
+	if *resume {
+		if cp, err = dump.Resume(checkpointPath, *format); err != nil {
+			log.Fatalln(err)
+		}
+		if cp.Records > 0 {
+			log.Printf("resuming after %d videos", cp.Records)
+		}
+	}
+
+	reported := time.Now()
+	stored, err := dump.Load(videos, r, *batchSize, &cp,
+		func(cp dump.Checkpoint, stored int64) error {
+			if time.Since(reported) >= progressEvery {
+				progress := fmt.Sprintf("loaded %d videos, %d new", cp.Records, stored)
+				if size > 0 {
+					progress += fmt.Sprintf(" (%d%% of the dump)", counter.n*100/size)
+				}
+				log.Println(progress)
+				reported = time.Now()
+			}
+			if path == "-" {
+				return nil
+			}
+			return cp.Save(checkpointPath)
+		})
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	if path != "-" {
+		if err := os.Remove(checkpointPath); err != nil && !os.IsNotExist(err) {
+			log.Fatalln(err)
+		}
+	}
+	log.Printf("loaded %d videos, %d new", cp.Records, stored)
+}
+
+// countingReader counts the bytes read through it.
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,111 @@
+// Command migrate versions the database schema, configured from the environment as the
+// server is.
+//
+// Usage:
+//
+//	migrate up          apply every pending migration
+//	migrate down [N]    revert the last N applied migrations, 1 by default
+//	migrate status      list migrations, and whether they are applied
+//	migrate partition   partition the videos table of a postgres database by publish year,
+//	                    or add the partitions missing through next year
+package main
+
+import (
+	"context"
+	"fmt"
+	"github.com/ditsuke/youtube-focus/config"
+	"github.com/ditsuke/youtube-focus/store/migrations"
+	_ "github.com/joho/godotenv/autoload"
+	"github.com/sethvargo/go-envconfig"
+	"log"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+)
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: migrate up | down [N] | status | partition")
+	os.Exit(2)
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+	}
+	command, args := os.Args[1], os.Args[2:]
+
+	steps := 1
+	switch {
+	case command != "up" && command != "down" && command != "status" && command != "partition":
+		usage()
+	case command == "down" && len(args) == 1:
+		n, err := strconv.Atoi(args[0])
+		if err != nil || n <= 0 {
+			usage()
+		}
+		steps = n
+	case len(args) > 0:
+		usage()
+	}
+
+	cfg := config.Config{}
+	if err := envconfig.Process(context.Background(), &cfg); err != nil {
+		log.Fatalln(err)
+	}
+	db, err := cfg.GetDB()
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	switch command {
+	case "up":
+		applied, err := migrations.Up(db)
+		for _, m := range applied {
+			log.Println("applied", m)
+		}
+		if err != nil {
+			log.Fatalln(err)
+		}
+		if len(applied) == 0 {
+			log.Println("no pending migrations")
+		}
+	case "down":
+		reverted, err := migrations.Down(db, steps)
+		for _, m := range reverted {
+			log.Println("reverted", m)
+		}
+		if err != nil {
+			log.Fatalln(err)
+		}
+		if len(reverted) == 0 {
+			log.Println("no applied migrations")
+		}
+	case "partition":
+		added, err := migrations.Partition(db, time.Now().UTC().Year()+1)
+		for _, name := range added {
+			log.Println("added partition", name)
+		}
+		if err != nil {
+			log.Fatalln(err)
+		}
+		if len(added) == 0 {
+			log.Println("no partitions missing")
+		}
+	case "status":
+		statuses, err := migrations.List(db)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, "MIGRATION\tAPPLIED")
+		for _, s := range statuses {
+			applied := "pending"
+			if s.AppliedAt != nil {
+				applied = s.AppliedAt.Format(time.RFC3339)
+			}
+			fmt.Fprintf(tw, "%s\t%s\n", s.Migration, applied)
+		}
+		_ = tw.Flush()
+	}
+}
@@ -0,0 +1,68 @@
+// Command prune removes the videos kept past their retention, as the server does every
+// PRUNE_INTERVAL seconds, configured from the environment as the server is.
+//
+// Usage:
+//
+//	prune [-dry-run]    prune videos, or only report those that would be pruned
+package main
+
+import (
+	"context"
+	"flag"
+	"github.com/ditsuke/youtube-focus/config"
+	"github.com/ditsuke/youtube-focus/store"
+	_ "github.com/joho/godotenv/autoload"
+	"github.com/rs/zerolog"
+	"github.com/sethvargo/go-envconfig"
+	"log"
+	"time"
+)
+
+func main() {
+	dryRun := flag.Bool("dry-run", false, "only report the videos that would be pruned")
+	flag.Parse()
+
+	cfg := config.Config{}
+	if err := envconfig.Process(context.Background(), &cfg); err != nil {
+		log.Fatalln(err)
+	}
+	db, err := cfg.GetDB()
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	videos := &store.VideoMetaStore{Logger: zerolog.New(zerolog.NewConsoleWriter()), DB: db}
+	policy := store.RetentionPolicy{Days: cfg.RetentionDays, Archive: cfg.RetentionArchive}
+	err = prune(videos, policy, cfg.PruneBatchSize, *dryRun || cfg.PruneDryRun,
+		log.Default())
+	if err != nil {
+		log.Fatalln(err)
+	}
+}
+
+// prune prunes videos under a policy, or only reports those that would be in a dry run, and
+// logs what was pruned to logger, even if pruning fails partway.
+func prune(videos *store.VideoMetaStore, policy store.RetentionPolicy, batchSize int,
+	dryRun bool, logger *log.Logger,
+) error {
+	report, err := videos.Prune(policy, batchSize, dryRun)
+
+	verb := "deleted"
+	switch {
+	case report.DryRun:
+		verb = "would prune"
+	case report.Archived:
+		verb = "archived"
+	}
+	if report.Videos > 0 {
+		logger.Printf("%s %d videos, published from %s to %s", verb, report.Videos,
+			report.Oldest.Format(time.RFC3339), report.Newest.Format(time.RFC3339))
+	}
+	if err != nil {
+		return err
+	}
+	if report.Videos == 0 {
+		logger.Println("no videos past their retention")
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"bytes"
+	"github.com/ditsuke/youtube-focus/config"
+	"github.com/ditsuke/youtube-focus/internal/yt"
+	"github.com/ditsuke/youtube-focus/store"
+	"github.com/ditsuke/youtube-focus/store/migrations"
+	"log"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPrune(t *testing.T) {
+	db, err := config.Config{DBDriver: config.DriverSQLite, SQLitePath: ":memory:"}.GetDB()
+	if err != nil {
+		t.Fatalf("open the database: %v", err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatalf("open the database: %v", err)
+	}
+	t.Cleanup(func() { _ = sqlDB.Close() })
+	if _, err := migrations.Up(db); err != nil {
+		t.Fatalf("migrate the database: %v", err)
+	}
+
+	videos := &store.VideoMetaStore{DB: db}
+	_, err = videos.Save([]yt.Video{
+		{VideoId: "old", PublishedAt: time.Now().Add(-40 * 24 * time.Hour)},
+		{VideoId: "recent", PublishedAt: time.Now().Add(-10 * 24 * time.Hour)},
+	})
+	if err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	count := func() int64 {
+		var n int64
+		if err := db.Model(&yt.Video{}).Count(&n).Error; err != nil {
+			t.Fatalf("count videos: %v", err)
+		}
+		return n
+	}
+	policy := store.RetentionPolicy{Days: 30, Archive: true}
+
+	for _, run := range []struct {
+		dryRun bool
+		log    string
+		videos int64
+	}{
+		{dryRun: true, log: "would prune 1 videos", videos: 2},
+		{dryRun: false, log: "archived 1 videos", videos: 1},
+		{dryRun: false, log: "no videos past their retention", videos: 1},
+	} {
+		var out bytes.Buffer
+		if err := prune(videos, policy, 10, run.dryRun, log.New(&out, "", 0)); err != nil {
+			t.Fatalf("prune with dryRun %v: %v", run.dryRun, err)
+		}
+		if !strings.HasPrefix(out.String(), run.log) {
+			t.Errorf("prune with dryRun %v logged %q, want %q", run.dryRun, out.String(), run.log)
+		}
+		if n := count(); n != run.videos {
+			t.Errorf("%d videos after prune with dryRun %v, want %d", n, run.dryRun, run.videos)
+		}
+	}
+}
@@ -0,0 +1,146 @@
+// Command ytmon queries the YouTube Focus REST API from the command line.
+//
+// Usage:
+//
+//	ytmon [-server URL] [-key KEY] [-o table|json] <command> [arguments]
+//
+// Commands:
+//
+//	list [-n N] [-filter name=value]...             list the latest videos
+//	search [-n N] [-fuzzy|-natural] [-filter name=value]... <query>
+//	                                                search videos
+//	get <video id>                                  get a video
+//	tail                                            follow videos as they are stored
+//	watch add [-mode like|fuzzy|natural] [-filter name=value]... [-retention days]
+//	          <name> <query>                        watch a search for new videos
+//	watch list                                      list watches
+//	watch new [-n N] <id>                           list the new videos of a watch
+//	watch rm <id>                                   stop watching a search
+//
+// Watches are the API's saved searches, added and removed with an editor's key. Filters are
+// named as the query parameters of /v1/videos, eg: -filter type=upload -filter min_views=1000.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/ditsuke/youtube-focus/client"
+	"os"
+	"os/signal"
+	"strings"
+)
+
+const (
+	// envServer and envAPIKey are the environment variables the -server and -key flags default
+	// to.
+	envServer = "YTMON_SERVER"
+	envAPIKey = "YTMON_API_KEY"
+
+	defaultServer = "http://localhost:8080"
+)
+
+// Output formats.
+const (
+	outputTable = "table"
+	outputJSON  = "json"
+)
+
+var errUsage = errors.New("usage")
+
+func main() {
+	flags := flag.NewFlagSet("ytmon", flag.ExitOnError)
+	server := flags.String("server", envOr(envServer, defaultServer), "API server `URL`")
+	key := flags.String("key", os.Getenv(envAPIKey), "API `key`")
+	output := flags.String("o", outputTable, "output `format`, table or json")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: ytmon [flags] list|search|get|tail|watch ...")
+		flags.PrintDefaults()
+	}
+	_ = flags.Parse(os.Args[1:])
+
+	if *output != outputTable && *output != outputJSON {
+		fmt.Fprintf(os.Stderr, "ytmon: invalid output format %q\n", *output)
+		os.Exit(2)
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer cancel()
+
+	api, err := client.New(*server, client.WithAPIKey(*key))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "ytmon:", err)
+		os.Exit(2)
+	}
+	c := &cli{api: api, out: newPrinter(os.Stdout, *output)}
+	err = c.run(ctx, flags.Args())
+	if errors.Is(err, errUsage) {
+		flags.Usage()
+		os.Exit(2)
+	}
+	if err != nil && ctx.Err() == nil {
+		fmt.Fprintln(os.Stderr, "ytmon:", err)
+		os.Exit(1)
+	}
+}
+
+// cli runs commands against the API.
+type cli struct {
+	api *client.Client
+	out *printer
+}
+
+func (c *cli) run(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		return errUsage
+	}
+
+	command, args := args[0], args[1:]
+	switch command {
+	case "list":
+		return c.list(ctx, args)
+	case "search":
+		return c.search(ctx, args)
+	case "get":
+		return c.get(ctx, args)
+	case "tail":
+		return c.tail(ctx, args)
+	case "watch":
+		return c.watch(ctx, args)
+	default:
+		return errUsage
+	}
+}
+
+// filterFlags collects repeated -filter name=value flags.
+type filterFlags map[string]string
+
+func (f filterFlags) String() string {
+	pairs := make([]string, 0, len(f))
+	for name, value := range f {
+		pairs = append(pairs, name+"="+value)
+	}
+	return strings.Join(pairs, ",")
+}
+
+func (f filterFlags) Set(pair string) error {
+	name, value, ok := strings.Cut(pair, "=")
+	if !ok || name == "" {
+		return fmt.Errorf("filters must be of the form name=value")
+	}
+	f[name] = value
+	return nil
+}
+
+// filter returns the filter described by the flags, validated as the API would.
+func (f filterFlags) filter() (client.Filter, error) {
+	return client.ParseFilter(f)
+}
+
+func envOr(name, def string) string {
+	if value := os.Getenv(name); value != "" {
+		return value
+	}
+	return def
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/ditsuke/youtube-focus/client"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// titleWidth is the width titles are truncated to in tables.
+const titleWidth = 60
+
+// printer writes results as tables, or as the JSON the API responded with.
+type printer struct {
+	w      io.Writer
+	format string
+}
+
+func newPrinter(w io.Writer, format string) *printer {
+	return &printer{w: w, format: format}
+}
+
+func (p *printer) videos(resp *client.VideosResponse) error {
+	if p.format == outputJSON {
+		return p.json(resp.Videos)
+	}
+
+	if len(resp.Videos) == 0 {
+		if len(resp.Suggestions) > 0 {
+			_, err := fmt.Fprintf(p.w, "No videos. Did you mean: %s?\n",
+				strings.Join(resp.Suggestions, ", "))
+			return err
+		}
+		_, err := fmt.Fprintln(p.w, "No videos.")
+		return err
+	}
+
+	tw := p.table()
+	for i := range resp.Videos {
+		p.row(tw, &resp.Videos[i])
+	}
+	return tw.Flush()
+}
+
+func (p *printer) video(v *client.Video) error {
+	if p.format == outputJSON {
+		return p.json(v)
+	}
+	stats := statsOf(v)
+
+	tw := tabwriter.NewWriter(p.w, 0, 4, 2, ' ', 0)
+	fields := [][2]string{
+		{"ID", v.VideoId},
+		{"Title", v.Title},
+		{"Channel", channelTitle(v) + " (" + v.ChannelId + ")"},
+		{"Published", v.PublishedAt.Local().Format(time.RFC1123)},
+		{"Duration", (time.Duration(v.DurationSeconds) * time.Second).String()},
+		{"Type", v.LiveBroadcastContent},
+		{"Views", strconv.FormatInt(stats.ViewCount, 10)},
+		{"Likes", strconv.FormatInt(stats.LikeCount, 10)},
+		{"Comments", strconv.FormatInt(stats.CommentCount, 10)},
+		{"Language", v.DefaultLanguage},
+		{"Category", v.CategoryId},
+		{"Thumbnail", v.ThumbnailUrl},
+		{"First seen", v.FirstSeenAt.Local().Format(time.RFC1123)},
+	}
+	if v.RemovedAt != nil {
+		fields = append(fields, [2]string{"Removed",
+			v.RemovalStatus + ", " + v.RemovedAt.Local().Format(time.RFC1123)})
+	}
+	for _, f := range fields {
+		fmt.Fprintf(tw, "%s:\t%s\n", f[0], f[1])
+	}
+	if _, err := fmt.Fprintf(tw, "\n%s\n", v.Description); err != nil {
+		return err
+	}
+	return tw.Flush()
+}
+
+// streamed prints a video as soon as it arrives, one line each.
+func (p *printer) streamed(v *client.Video) error {
+	if p.format == outputJSON {
+		data, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(p.w, "%s\n", data)
+		return err
+	}
+
+	tw := tabwriter.NewWriter(p.w, 0, 4, 2, ' ', 0)
+	p.row(tw, v)
+	return tw.Flush()
+}
+
+func (p *printer) table() *tabwriter.Writer {
+	tw := tabwriter.NewWriter(p.w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "PUBLISHED\tID\tCHANNEL\tVIEWS\tTITLE")
+	return tw
+}
+
+func (p *printer) row(tw *tabwriter.Writer, v *client.Video) {
+	fmt.Fprintf(tw, "%s\t%s\t%s\t%d\t%s\n",
+		v.PublishedAt.Local().Format("2006-01-02 15:04"),
+		v.VideoId, truncate(channelTitle(v), titleWidth/2), statsOf(v).ViewCount,
+		truncate(v.Title, titleWidth))
+}
+
+// channelTitle and statsOf return the channel title and statistics of a video, as embedded
+// with expandAll.
+func channelTitle(v *client.Video) string {
+	if v.Channel == nil {
+		return ""
+	}
+	return v.Channel.Title
+}
+
+func statsOf(v *client.Video) client.Stats {
+	if v.Stats == nil {
+		return client.Stats{}
+	}
+	return *v.Stats
+}
+
+func (p *printer) json(v interface{}) error {
+	enc := json.NewEncoder(p.w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+func truncate(s string, width int) string {
+	runes := []rune(s)
+	if len(runes) <= width {
+		return s
+	}
+	return string(runes[:width-1]) + "…"
+}
+
+func (p *printer) watches(watches []*client.SavedSearchResponse) error {
+	if p.format == outputJSON {
+		return p.json(watches)
+	}
+
+	tw := tabwriter.NewWriter(p.w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tNAME\tMODE\tSEARCH\tFILTERS\tCHECKED")
+	for _, w := range watches {
+		filters := make([]string, 0, len(w.Filters))
+		for name, value := range w.Filters {
+			filters = append(filters, name+"="+value)
+		}
+		sort.Strings(filters)
+
+		checked := "never"
+		if !w.CheckedAt.IsZero() {
+			checked = w.CheckedAt.Local().Format("2006-01-02 15:04")
+		}
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t%s\t%s\n", w.ID, w.Name, w.Mode, w.Search,
+			strings.Join(filters, " "), checked)
+	}
+	return tw.Flush()
+}
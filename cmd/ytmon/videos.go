@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"github.com/ditsuke/youtube-focus/client"
+	"strings"
+)
+
+// expandAll embeds the channel and statistics of videos, which tables show.
+var expandAll = []string{client.ExpandChannel, client.ExpandStats}
+
+// list lists the latest videos.
+func (c *cli) list(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("list", flag.ContinueOnError)
+	n := flags.Int("n", client.LimitDefault, "number of videos")
+	filters := filterFlags{}
+	flags.Var(filters, "filter", "filter videos by `name=value`, repeatable")
+	if err := flags.Parse(args); err != nil || flags.NArg() > 0 {
+		return errUsage
+	}
+	filter, err := filters.filter()
+	if err != nil {
+		return err
+	}
+
+	q := client.VideosQuery{Filter: filter, PageSize: *n, Expand: expandAll}
+	videos, err := collect(c.api.Videos(ctx, q), *n)
+	if err != nil {
+		return err
+	}
+	return c.out.videos(videos)
+}
+
+// search searches videos.
+func (c *cli) search(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("search", flag.ContinueOnError)
+	n := flags.Int("n", client.LimitDefault, "number of videos")
+	fuzzy := flags.Bool("fuzzy", false, "tolerate typos")
+	natural := flags.Bool("natural", false, "search in natural language, without paging")
+	filters := filterFlags{}
+	flags.Var(filters, "filter", "filter videos by `name=value`, repeatable")
+	if err := flags.Parse(args); err != nil || flags.NArg() == 0 || (*fuzzy && *natural) {
+		return errUsage
+	}
+	filter, err := filters.filter()
+	if err != nil {
+		return err
+	}
+
+	q := client.VideosQuery{
+		Search:   strings.Join(flags.Args(), " "),
+		Fuzzy:    *fuzzy,
+		Filter:   filter,
+		PageSize: *n,
+		Expand:   expandAll,
+	}
+	if *natural {
+		// Natural-language searches do not page
+		if q.PageSize > client.LimitMax {
+			q.PageSize = client.LimitMax
+		}
+		videos, err := c.api.NaturalSearch(ctx, q)
+		if err != nil {
+			return err
+		}
+		return c.out.videos(videos)
+	}
+
+	videos, err := collect(c.api.Videos(ctx, q), *n)
+	if err != nil {
+		return err
+	}
+	return c.out.videos(videos)
+}
+
+// get gets a video by its ID.
+func (c *cli) get(ctx context.Context, args []string) error {
+	if len(args) != 1 {
+		return errUsage
+	}
+
+	video, err := c.api.Video(ctx, args[0], expandAll...)
+	if err != nil {
+		return err
+	}
+	return c.out.video(video)
+}
+
+// tail prints videos as they are stored, until interrupted.
+func (c *cli) tail(ctx context.Context, args []string) error {
+	if len(args) != 0 {
+		return errUsage
+	}
+
+	return c.api.Stream(ctx, c.out.streamed, expandAll...)
+}
+
+// collect gets up to n videos from an iterator. Searches that match nothing yield their
+// suggestions.
+func collect(it *client.VideoIterator, n int) (*client.VideosResponse, error) {
+	videos := &client.VideosResponse{Videos: []client.Video{}}
+	for len(videos.Videos) < n && it.Next() {
+		videos.Videos = append(videos.Videos, it.Video())
+	}
+	if err := it.Err(); err != nil {
+		return nil, err
+	}
+	videos.Suggestions = it.Suggestions()
+	return videos, nil
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"github.com/ditsuke/youtube-focus/client"
+	"strconv"
+	"strings"
+)
+
+// watch manages watches, which are the API's saved searches.
+func (c *cli) watch(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		return errUsage
+	}
+
+	command, args := args[0], args[1:]
+	switch command {
+	case "add":
+		return c.watchAdd(ctx, args)
+	case "list":
+		return c.watchList(ctx, args)
+	case "new":
+		return c.watchNew(ctx, args)
+	case "rm":
+		return c.watchRemove(ctx, args)
+	default:
+		return errUsage
+	}
+}
+
+func (c *cli) watchAdd(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("watch add", flag.ContinueOnError)
+	mode := flags.String("mode", string(client.ModeLike), "search `mode`: like, fuzzy or natural")
+	retention := flags.Int("retention", 0,
+		"keep matching videos for `days`, the server's default if 0")
+	filters := filterFlags{}
+	flags.Var(filters, "filter", "filter videos by `name=value`, repeatable")
+	if err := flags.Parse(args); err != nil || flags.NArg() < 2 {
+		return errUsage
+	}
+
+	filter, err := filters.filter()
+	if err != nil {
+		return err
+	}
+
+	watch, err := c.api.CreateSavedSearch(ctx, client.SavedSearch{
+		Name:   flags.Arg(0),
+		Search: strings.Join(flags.Args()[1:], " "),
+		Mode:   client.SearchMode(*mode),
+		Filter: filter,
+
+		RetentionDays: *retention,
+	})
+	if err != nil {
+		return err
+	}
+	return c.out.watches([]*client.SavedSearchResponse{watch})
+}
+
+func (c *cli) watchList(ctx context.Context, args []string) error {
+	if len(args) != 0 {
+		return errUsage
+	}
+
+	watches, err := c.api.SavedSearches(ctx)
+	if err != nil {
+		return err
+	}
+	return c.out.watches(watches)
+}
+
+// watchNew lists the videos of a watch first seen since it was last checked, marking them as
+// seen.
+func (c *cli) watchNew(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("watch new", flag.ContinueOnError)
+	n := flags.Int("n", client.LimitDefault, "number of videos")
+	if err := flags.Parse(args); err != nil {
+		return errUsage
+	}
+	id, err := watchID(flags.Args())
+	if err != nil {
+		return err
+	}
+
+	videos, err := collect(c.api.NewVideos(ctx, id, *n, expandAll...), *n)
+	if err != nil {
+		return err
+	}
+	return c.out.videos(videos)
+}
+
+func (c *cli) watchRemove(ctx context.Context, args []string) error {
+	id, err := watchID(args)
+	if err != nil {
+		return err
+	}
+	return c.api.DeleteSavedSearch(ctx, id)
+}
+
+func watchID(args []string) (uint, error) {
+	if len(args) != 1 {
+		return 0, errUsage
+	}
+	id, err := strconv.ParseUint(args[0], 10, 0)
+	if err != nil {
+		return 0, errUsage
+	}
+	return uint(id), nil
+}
@@ -2,8 +2,21 @@ package config
 
 import (
 	"fmt"
+	"github.com/glebarez/sqlite"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// The databases videos can be stored in.
+const (
+	DriverPostgres = "postgres"
+	DriverSQLite   = "sqlite"
+	// DriverMemory keeps videos in a store.MemoryStore, and the rest, eg: saved searches and
+	// API keys, in an in-memory SQLite database. Nothing outlives the process.
+	DriverMemory = "memory"
 )
 
 type Config struct {
@@ -11,25 +24,139 @@ type Config struct {
 	YouTubeVideoQuery   string   `env:"YOUTUBE_VIDEO_QUERY,default=game"`
 	YouTubePollInterval int      `env:"YOUTUBE_POLL_INTERVAL,default=20"`
 
+	// VerifyInterval is the number of seconds between checks of whether stored videos are
+	// still on YouTube, 0 to never check. Each check covers VerifyBatchSize videos.
+	VerifyInterval  int `env:"VERIFY_INTERVAL,default=60"`
+	VerifyBatchSize int `env:"VERIFY_BATCH_SIZE,default=50"`
+
+	// RetentionDays is the number of days videos are kept for after they are published, 0 to
+	// keep them forever. Saved searches may set a retention of their own for the videos they
+	// match.
+	RetentionDays int `env:"RETENTION_DAYS,default=0"`
+	// RetentionArchive moves videos past their retention to the videos_archive table, rather
+	// than deleting them.
+	RetentionArchive bool `env:"RETENTION_ARCHIVE,default=true"`
+	// PruneInterval is the number of seconds between prunes of videos past their retention,
+	// 0 to never prune. Prunes remove PruneBatchSize videos per transaction, and only log
+	// what they would remove when PruneDryRun is set.
+	PruneInterval  int  `env:"PRUNE_INTERVAL,default=3600"`
+	PruneBatchSize int  `env:"PRUNE_BATCH_SIZE,default=500"`
+	PruneDryRun    bool `env:"PRUNE_DRY_RUN,default=false"`
+
+	// DBDriver is the database videos are stored in, DriverPostgres, DriverSQLite or
+	// DriverMemory.
+	DBDriver string `env:"DB_DRIVER,default=postgres"`
+	// SQLitePath is the file of the SQLite database, or ":memory:" to keep it in memory.
+	SQLitePath string `env:"SQLITE_PATH,default=youtube-focus.db"`
+
 	PostgresHost string `env:"PGHOST,default=localhost"`
 	PostgresPort string `env:"PGPORT,default=5432"`
 	PostgresUser string `env:"PGUSER"`
 	PostgresPass string `env:"PGPASSWORD"`
 	PostgresDB   string `env:"PGDB"`
 
+	// PostgresReplicas are the read replicas of the database, as host or host:port, which
+	// share its user, password and database. The APIs read from them, rather than from the
+	// primary, unless they are more than ReplicaMaxLag seconds behind it (0 for any lag), or
+	// unreachable. Their health is checked every ReplicaCheckInterval seconds.
+	PostgresReplicas     []string `env:"PGREPLICAS"`
+	ReplicaCheckInterval int      `env:"PGREPLICA_CHECK_INTERVAL,default=5"`
+	ReplicaMaxLag        int      `env:"PGREPLICA_MAX_LAG,default=30"`
+
+	SearchSimilarityThreshold float64 `env:"SEARCH_SIMILARITY_THRESHOLD,default=0.5"`
+
 	ServerPort string `env:"PORT,default=8080"`
 	ServerHost string `env:"HOST,default=localhost"`
+	// GRPCPort is the port the gRPC API is served on, on the ServerHost.
+	GRPCPort string `env:"GRPC_PORT,default=9090"`
+
+	// APIAuth requires clients to authenticate with an API key to read when true. Changes
+	// always require a key with the role for them.
+	APIAuth bool `env:"API_AUTH,default=false"`
+	// APIRateLimit is the default number of requests per minute allowed per API key.
+	APIRateLimit int `env:"API_RATE_LIMIT,default=60"`
+	// APIAdminToken authenticates administrators, eg: to issue API keys.
+	APIAdminToken string `env:"API_ADMIN_TOKEN"`
+
+	// APICacheSize is the number of video responses cached in memory, 0 to disable caching.
+	APICacheSize int `env:"API_CACHE_SIZE,default=1024"`
+	// APICacheMaxAge is the number of seconds clients may reuse video responses for.
+	APICacheMaxAge int `env:"API_CACHE_MAX_AGE,default=5"`
+	// APICacheTTL is the number of seconds video responses are cached for, unless new videos
+	// evict them sooner. 0 keeps them until then.
+	APICacheTTL int `env:"API_CACHE_TTL,default=60"`
+}
+
+// redacted replaces secrets in a Redacted config.
+const redacted = "REDACTED"
+
+// Redacted returns a copy of the config with its secrets, the YouTube API keys, the postgres
+// password and the admin token, replaced, eg: to log it.
+func (c Config) Redacted() Config {
+	keys := make([]string, len(c.YouTubeAPIKeys))
+	for i := range keys {
+		keys[i] = redacted
+	}
+	c.YouTubeAPIKeys = keys
+	if c.PostgresPass != "" {
+		c.PostgresPass = redacted
+	}
+	if c.APIAdminToken != "" {
+		c.APIAdminToken = redacted
+	}
+	return c
 }
 
 func (c Config) GetDB() (*gorm.DB, error) {
-	return gorm.Open(postgres.Open(c.GetDSN()))
+	switch c.DBDriver {
+	case DriverPostgres:
+		return gorm.Open(postgres.Open(c.GetDSN()))
+	case DriverSQLite, DriverMemory:
+		return gorm.Open(sqlite.Open(c.GetSQLiteDSN()), &gorm.Config{
+			// SQLite keeps times as they are given, so that they only compare as they should
+			// when in a single zone
+			NowFunc: func() time.Time { return time.Now().UTC() },
+		})
+	default:
+		return nil, fmt.Errorf("unknown DB_DRIVER %q", c.DBDriver)
+	}
 }
 
 func (c Config) GetDSN() string {
+	return c.dsn(c.PostgresHost, c.PostgresPort)
+}
+
+// dsn returns the DSN of the database on a server other than the primary, eg: a replica.
+func (c Config) dsn(host, port string) string {
 	return fmt.Sprintf(
 		"user=%s password=%s port=%s host=%s dbname=%s",
 		c.PostgresUser, c.PostgresPass,
-		c.PostgresPort, c.PostgresHost,
+		port, host,
 		c.PostgresDB,
 	)
 }
+
+// InMemory reports whether the database is kept in memory, and so starts out empty each run.
+func (c Config) InMemory() bool {
+	return c.DBDriver == DriverMemory || c.DBDriver == DriverSQLite && c.SQLitePath == ":memory:"
+}
+
+// GetSQLiteDSN returns the DSN of the SQLite database at SQLitePath, or of the in-memory one of
+// DriverMemory. In-memory databases are shared by every connection of the process, which would
+// otherwise each get their own.
+func (c Config) GetSQLiteDSN() string {
+	pragmas := url.Values{"_pragma": {
+		"busy_timeout(5000)",
+		"foreign_keys(1)",
+	}}
+	if c.InMemory() {
+		return "file::memory:?cache=shared&" + pragmas.Encode()
+	}
+	pragmas.Add("_pragma", "journal_mode(WAL)")
+
+	sep := "?"
+	if strings.Contains(c.SQLitePath, "?") {
+		sep = "&"
+	}
+	return "file:" + c.SQLitePath + sep + pragmas.Encode()
+}
@@ -0,0 +1,32 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestRedacted(t *testing.T) {
+	cfg := Config{
+		YouTubeAPIKeys: []string{"yt-key-1", "yt-key-2"},
+		PostgresUser:   "focus",
+		PostgresPass:   "pg-pass",
+		APIAdminToken:  "admin-token",
+	}
+	logged := fmt.Sprintf("%+v", cfg.Redacted())
+	for _, secret := range []string{"yt-key-1", "yt-key-2", "pg-pass", "admin-token"} {
+		if strings.Contains(logged, secret) {
+			t.Errorf("redacted config %s has the secret %q", logged, secret)
+		}
+	}
+	if !strings.Contains(logged, "focus") {
+		t.Errorf("redacted config %s has no PostgresUser", logged)
+	}
+	if cfg.PostgresPass != "pg-pass" || cfg.YouTubeAPIKeys[0] != "yt-key-1" {
+		t.Errorf("Redacted changed the config it was called on")
+	}
+
+	if unset := (Config{}).Redacted(); unset.PostgresPass != "" || unset.APIAdminToken != "" {
+		t.Errorf("Redacted set unset secrets: %+v", unset)
+	}
+}
@@ -0,0 +1,230 @@
+package config
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"github.com/ditsuke/youtube-focus/store"
+	"github.com/rs/zerolog"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/plugin/dbresolver"
+	"net"
+	"sync"
+	"time"
+)
+
+// replicaCheckTimeout is the time replicas have to answer checks of their health.
+const replicaCheckTimeout = 2 * time.Second
+
+// replicaLagQuery selects the number of seconds a replica is behind its primary, which is
+// none while it has replayed all it has received, however long ago that was.
+const replicaLagQuery = "SELECT COALESCE(CASE " +
+	"WHEN pg_last_wal_receive_lsn() = pg_last_wal_replay_lsn() THEN 0 " +
+	"ELSE EXTRACT(EPOCH FROM now() - pg_last_xact_replay_timestamp()) END, 0)"
+
+// GetReadDB is like GetDB, but spreads reads across the read replicas of the database, if
+// any, and makes writes, and the reads of transactions, on the primary. Reads fall back to the
+// primary while no replica is healthy, and otherwise tolerate replicas being up to
+// ReplicaMaxLag seconds behind it. Reads, and read transactions, that a replica fails to
+// serve between checks of its health are retried on the primary, and the replica is taken
+// as unhealthy until the next check. Changes to the health of replicas are logged.
+func (c Config) GetReadDB(logger zerolog.Logger) (*gorm.DB, error) {
+	db, err := c.GetDB()
+	if err != nil || len(c.PostgresReplicas) == 0 {
+		return db, err
+	}
+	if c.DBDriver != DriverPostgres {
+		return nil, fmt.Errorf("read replicas need DB_DRIVER %q", DriverPostgres)
+	}
+
+	policy := &replicaPolicy{
+		logger:   logger,
+		interval: time.Duration(c.ReplicaCheckInterval) * time.Second,
+		maxLag:   time.Duration(c.ReplicaMaxLag) * time.Second,
+	}
+	var replicas []gorm.Dialector
+	for _, replica := range c.PostgresReplicas {
+		host, port, err := net.SplitHostPort(replica)
+		if err != nil {
+			host, port = replica, c.PostgresPort
+		}
+		replicas = append(replicas, postgres.Open(c.dsn(host, port)))
+		policy.hosts = append(policy.hosts, replica)
+	}
+	// The primary is resolved between last, to fall back to
+	replicas = append(replicas, postgres.Open(c.GetDSN()))
+
+	err = db.Use(dbresolver.Register(dbresolver.Config{Replicas: replicas, Policy: policy}))
+	if err != nil {
+		return nil, err
+	}
+	return db, nil
+}
+
+// OnPrimary returns a DB of GetReadDB that makes its reads on the primary, eg: for those that
+// must see the latest writes.
+func OnPrimary(db *gorm.DB) *gorm.DB {
+	return db.Clauses(dbresolver.Write).Session(&gorm.Session{})
+}
+
+// replicaPolicy resolves reads to the healthy replicas in turn, or to the primary, the last of
+// the connection pools it resolves between, while none is. The health of replicas is checked
+// as reads are resolved, at most every interval, in the background so that reads never wait
+// for checks but the first.
+type replicaPolicy struct {
+	logger   zerolog.Logger
+	hosts    []string
+	interval time.Duration
+	maxLag   time.Duration
+
+	mu sync.Mutex
+	// healthy tells which replicas were healthy as of checkedAt, nil until first checked.
+	healthy   []bool
+	checkedAt time.Time
+	checking  bool
+	next      int
+}
+
+func (p *replicaPolicy) Resolve(pools []gorm.ConnPool) gorm.ConnPool {
+	replicas, primary := pools[:len(pools)-1], pools[len(pools)-1]
+
+	p.mu.Lock()
+	unchecked := p.healthy == nil
+	if !unchecked && !p.checking && time.Since(p.checkedAt) >= p.interval {
+		p.checking = true
+		go p.check(replicas)
+	}
+	p.mu.Unlock()
+	if unchecked {
+		p.check(replicas)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for range replicas {
+		p.next = (p.next + 1) % len(replicas)
+		if p.healthy[p.next] {
+			return &fallbackPool{ConnPool: replicas[p.next], primary: primary, policy: p, i: p.next}
+		}
+	}
+	return primary
+}
+
+// fail takes a replica as unhealthy until the next check, after it failed to serve a read.
+func (p *replicaPolicy) fail(i int, err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.healthy[i] {
+		p.healthy[i] = false
+		p.logger.Warn().Err(err).Str("replica", p.hosts[i]).Msg("replica unhealthy")
+	}
+}
+
+// check checks the health of every replica.
+func (p *replicaPolicy) check(replicas []gorm.ConnPool) {
+	errs := make([]error, len(replicas))
+	for i, replica := range replicas {
+		errs[i] = p.checkReplica(replica)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	healthy := make([]bool, len(replicas))
+	for i, err := range errs {
+		healthy[i] = err == nil
+		switch {
+		case err != nil && (p.healthy == nil || p.healthy[i]):
+			p.logger.Warn().Err(err).Str("replica", p.hosts[i]).Msg("replica unhealthy")
+		case err == nil && p.healthy != nil && !p.healthy[i]:
+			p.logger.Info().Str("replica", p.hosts[i]).Msg("replica healthy again")
+		}
+	}
+	p.healthy, p.checkedAt, p.checking = healthy, time.Now(), false
+}
+
+// checkReplica fails unless a replica answers in time, and is at most maxLag behind the
+// primary.
+func (p *replicaPolicy) checkReplica(replica gorm.ConnPool) error {
+	ctx, cancel := context.WithTimeout(context.Background(), replicaCheckTimeout)
+	defer cancel()
+
+	var lag float64
+	if err := replica.QueryRowContext(ctx, replicaLagQuery).Scan(&lag); err != nil {
+		return err
+	}
+	if p.maxLag > 0 && lag > p.maxLag.Seconds() {
+		return fmt.Errorf("%.0fs behind the primary", lag)
+	}
+	return nil
+}
+
+// fallbackPool is the connection pool of the ith replica, retrying what it fails to serve on
+// the primary. Queries are only retried if the replica was unavailable, rather than the query
+// failing, and if their context is still live.
+type fallbackPool struct {
+	gorm.ConnPool
+	primary gorm.ConnPool
+	policy  *replicaPolicy
+	i       int
+}
+
+// fallBack reports whether a failure of the replica is to be retried on the primary, taking
+// the replica as unhealthy if so.
+func (f *fallbackPool) fallBack(ctx context.Context, err error) bool {
+	if err == nil || ctx.Err() != nil || !store.IsUnavailable(err) {
+		return false
+	}
+	f.policy.fail(f.i, err)
+	return true
+}
+
+func (f *fallbackPool) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	stmt, err := f.ConnPool.PrepareContext(ctx, query)
+	if f.fallBack(ctx, err) {
+		return f.primary.PrepareContext(ctx, query)
+	}
+	return stmt, err
+}
+
+func (f *fallbackPool) ExecContext(ctx context.Context, query string, args ...interface{},
+) (sql.Result, error) {
+	result, err := f.ConnPool.ExecContext(ctx, query, args...)
+	if f.fallBack(ctx, err) {
+		return f.primary.ExecContext(ctx, query, args...)
+	}
+	return result, err
+}
+
+func (f *fallbackPool) QueryContext(ctx context.Context, query string, args ...interface{},
+) (*sql.Rows, error) {
+	rows, err := f.ConnPool.QueryContext(ctx, query, args...)
+	if f.fallBack(ctx, err) {
+		return f.primary.QueryContext(ctx, query, args...)
+	}
+	return rows, err
+}
+
+func (f *fallbackPool) QueryRowContext(ctx context.Context, query string, args ...interface{},
+) *sql.Row {
+	row := f.ConnPool.QueryRowContext(ctx, query, args...)
+	if f.fallBack(ctx, row.Err()) {
+		return f.primary.QueryRowContext(ctx, query, args...)
+	}
+	return row
+}
+
+// BeginTx begins read transactions, on the primary if the replica fails to. Failures past
+// their beginning are not retried.
+func (f *fallbackPool) BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error) {
+	replica, ok := f.ConnPool.(gorm.TxBeginner)
+	primary, primaryOK := f.primary.(gorm.TxBeginner)
+	if !ok || !primaryOK {
+		return nil, gorm.ErrInvalidTransaction
+	}
+	tx, err := replica.BeginTx(ctx, opts)
+	if f.fallBack(ctx, err) {
+		return primary.BeginTx(ctx, opts)
+	}
+	return tx, err
+}
@@ -0,0 +1,287 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"github.com/rs/zerolog"
+	"gorm.io/gorm"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeDB is a database answering replicaLagQuery with its lag, and every other query with
+// its name, one row of one column. Databases that are down fail as unreachable.
+type fakeDB struct {
+	name string
+
+	mu   sync.Mutex
+	down bool
+	lag  float64
+}
+
+func (d *fakeDB) set(down bool, lag float64) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.down, d.lag = down, lag
+}
+
+func (d *fakeDB) Connect(context.Context) (driver.Conn, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.down {
+		return nil, driver.ErrBadConn
+	}
+	return &fakeConn{db: d}, nil
+}
+
+func (d *fakeDB) Driver() driver.Driver { return nil }
+
+type fakeConn struct {
+	db *fakeDB
+}
+
+func (c *fakeConn) QueryContext(_ context.Context, query string, _ []driver.NamedValue,
+) (driver.Rows, error) {
+	c.db.mu.Lock()
+	defer c.db.mu.Unlock()
+	switch {
+	case c.db.down:
+		return nil, driver.ErrBadConn
+	case query == replicaLagQuery:
+		return &fakeRows{column: "lag", value: c.db.lag}, nil
+	case query == "SELECT invalid":
+		return nil, errors.New("syntax error")
+	default:
+		return &fakeRows{column: "name", value: c.db.name}, nil
+	}
+}
+
+func (c *fakeConn) Prepare(string) (driver.Stmt, error) {
+	return nil, errors.New("statements are not prepared")
+}
+
+func (c *fakeConn) Close() error { return nil }
+
+func (c *fakeConn) Begin() (driver.Tx, error) {
+	c.db.mu.Lock()
+	defer c.db.mu.Unlock()
+	if c.db.down {
+		return nil, driver.ErrBadConn
+	}
+	return fakeTx{}, nil
+}
+
+type fakeTx struct{}
+
+func (fakeTx) Commit() error   { return nil }
+func (fakeTx) Rollback() error { return nil }
+
+type fakeRows struct {
+	column string
+	value  driver.Value
+	read   bool
+}
+
+func (r *fakeRows) Columns() []string { return []string{r.column} }
+func (r *fakeRows) Close() error      { return nil }
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if r.read {
+		return io.EOF
+	}
+	r.read = true
+	dest[0] = r.value
+	return nil
+}
+
+// replicaSet is a primary database and its replicas, each with a pool of connections to it.
+type replicaSet struct {
+	primary  *fakeDB
+	replicas []*fakeDB
+	// pools are the pools of the replicas, followed by the primary's, as GetReadDB has them
+	// resolved between.
+	pools []gorm.ConnPool
+}
+
+func newReplicaSet(t *testing.T, replicas ...string) *replicaSet {
+	t.Helper()
+	s := &replicaSet{primary: &fakeDB{name: "primary"}}
+	for _, name := range replicas {
+		s.replicas = append(s.replicas, &fakeDB{name: name})
+	}
+	for _, db := range append(s.replicas, s.primary) {
+		pool := sql.OpenDB(db)
+		t.Cleanup(func() { _ = pool.Close() })
+		s.pools = append(s.pools, pool)
+	}
+	return s
+}
+
+// newPolicy returns a policy over replicas, checked every interval, logging to log.
+func newPolicy(log io.Writer, interval, maxLag time.Duration, replicas ...string,
+) *replicaPolicy {
+	return &replicaPolicy{
+		logger:   zerolog.New(log),
+		hosts:    replicas,
+		interval: interval,
+		maxLag:   maxLag,
+	}
+}
+
+// served returns the name of the database a query to pool is served by.
+func served(t *testing.T, pool gorm.ConnPool) string {
+	t.Helper()
+	var name string
+	if err := pool.QueryRowContext(context.Background(), "SELECT name").Scan(&name); err != nil {
+		t.Fatalf("query: %v", err)
+	}
+	return name
+}
+
+// resolved returns the names of the databases serving n reads resolved by policy.
+func resolved(t *testing.T, policy *replicaPolicy, s *replicaSet, n int) []string {
+	t.Helper()
+	names := make([]string, n)
+	for i := range names {
+		names[i] = served(t, policy.Resolve(s.pools))
+	}
+	return names
+}
+
+func TestReplicaPolicySpreadsReads(t *testing.T) {
+	s := newReplicaSet(t, "r0", "r1")
+	policy := newPolicy(io.Discard, time.Hour, 0, "r0", "r1")
+
+	got := strings.Join(resolved(t, policy, s, 4), " ")
+	if want := "r1 r0 r1 r0"; got != want {
+		t.Errorf("reads served by %s, want %s", got, want)
+	}
+}
+
+func TestReplicaPolicyFallsBackToPrimary(t *testing.T) {
+	s := newReplicaSet(t, "r0", "r1")
+	s.replicas[0].set(true, 0)
+	s.replicas[1].set(true, 0)
+	var log bytes.Buffer
+	policy := newPolicy(&log, time.Hour, 0, "r0", "r1")
+
+	got := strings.Join(resolved(t, policy, s, 2), " ")
+	if want := "primary primary"; got != want {
+		t.Errorf("reads served by %s, want %s", got, want)
+	}
+	if n := strings.Count(log.String(), "replica unhealthy"); n != 2 {
+		t.Errorf("logged %d replicas unhealthy, want 2: %s", n, log.String())
+	}
+}
+
+func TestReplicaPolicyMaxLag(t *testing.T) {
+	s := newReplicaSet(t, "r0", "r1")
+	s.replicas[0].set(false, 30)
+	s.replicas[1].set(false, 5)
+
+	policy := newPolicy(io.Discard, time.Hour, 10*time.Second, "r0", "r1")
+	got := strings.Join(resolved(t, policy, s, 3), " ")
+	if want := "r1 r1 r1"; got != want {
+		t.Errorf("reads served by %s with a replica lagging, want %s", got, want)
+	}
+
+	// Lag is tolerated without a maximum
+	policy = newPolicy(io.Discard, time.Hour, 0, "r0", "r1")
+	got = strings.Join(resolved(t, policy, s, 2), " ")
+	if want := "r1 r0"; got != want {
+		t.Errorf("reads served by %s without a maximum lag, want %s", got, want)
+	}
+}
+
+func TestReplicaFailureRetriedOnPrimary(t *testing.T) {
+	s := newReplicaSet(t, "r0", "r1")
+	var log bytes.Buffer
+	policy := newPolicy(&log, time.Hour, 0, "r0", "r1")
+	// Both replicas are healthy when checked, and go down before the next check
+	resolved(t, policy, s, 2)
+	s.replicas[0].set(true, 0)
+	s.replicas[1].set(true, 0)
+
+	if got := served(t, policy.Resolve(s.pools)); got != "primary" {
+		t.Errorf("read failing on r1 served by %s, want the primary", got)
+	}
+	pool := policy.Resolve(s.pools)
+	tx, err := pool.(gorm.TxBeginner).BeginTx(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("BeginTx failing on r0: %v", err)
+	}
+	var name string
+	if err := tx.QueryRow("SELECT name").Scan(&name); err != nil || name != "primary" {
+		t.Errorf("transaction failing on r0 served by %q, %v, want the primary", name, err)
+	}
+	_ = tx.Rollback()
+
+	// Both are now taken as unhealthy until the next check
+	if pool := policy.Resolve(s.pools); pool != s.pools[2] {
+		t.Errorf("Resolve with failed replicas = %v, want the primary", pool)
+	}
+	if n := strings.Count(log.String(), "replica unhealthy"); n != 2 {
+		t.Errorf("logged %d replicas unhealthy, want 2: %s", n, log.String())
+	}
+}
+
+func TestReplicaQueryErrorNotRetried(t *testing.T) {
+	s := newReplicaSet(t, "r0")
+	policy := newPolicy(io.Discard, time.Hour, 0, "r0")
+
+	var name string
+	err := policy.Resolve(s.pools).QueryRowContext(context.Background(), "SELECT invalid").
+		Scan(&name)
+	if err == nil || !strings.Contains(err.Error(), "syntax error") {
+		t.Errorf("invalid query = %q, %v, want its error", name, err)
+	}
+	if got := served(t, policy.Resolve(s.pools)); got != "r0" {
+		t.Errorf("read after an invalid query served by %s, want r0", got)
+	}
+}
+
+func TestReplicaRecovers(t *testing.T) {
+	s := newReplicaSet(t, "r0", "r1")
+	s.replicas[0].set(true, 0)
+	var log bytes.Buffer
+	policy := newPolicy(&log, time.Hour, 0, "r0", "r1")
+
+	got := strings.Join(resolved(t, policy, s, 2), " ")
+	if want := "r1 r1"; got != want {
+		t.Errorf("reads served by %s with r0 down, want %s", got, want)
+	}
+
+	// Reads past the interval check again in the background
+	s.replicas[0].set(false, 0)
+	policy.mu.Lock()
+	policy.checkedAt = time.Now().Add(-policy.interval)
+	policy.mu.Unlock()
+	policy.Resolve(s.pools)
+	for start := time.Now(); ; time.Sleep(time.Millisecond) {
+		policy.mu.Lock()
+		checked := !policy.checking && policy.healthy[0]
+		policy.mu.Unlock()
+		if checked {
+			break
+		}
+		if time.Since(start) > 5*time.Second {
+			t.Fatalf("r0 was not checked healthy again")
+		}
+	}
+
+	names := resolved(t, policy, s, 2)
+	sort.Strings(names)
+	if got, want := strings.Join(names, " "), "r0 r1"; got != want {
+		t.Errorf("reads served by %s once r0 recovered, want %s", got, want)
+	}
+	if !strings.Contains(log.String(), "replica healthy again") {
+		t.Errorf("recovery not logged: %s", log.String())
+	}
+}
@@ -1,4 +1,4 @@
 #!/bin/sh
 
-/app/migrator -no-gen # generate/migrate tables
+/app/migrate up || exit 1
 /app/server
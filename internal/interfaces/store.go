@@ -3,7 +3,8 @@ package interfaces
 import "time"
 
 type Store[T any, M time.Time] interface {
-	Save([]T)
-	Retrieve(marker M, limit int) []T
-	Search(query string, marker M, limit int) []T
+	// Save records, returning those new to the store.
+	Save([]T) ([]T, error)
+	Retrieve(marker M, limit int) ([]T, error)
+	Search(query string, marker M, limit int) ([]T, error)
 }
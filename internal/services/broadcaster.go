@@ -0,0 +1,49 @@
+package services
+
+import (
+	"context"
+	"sync"
+)
+
+// Broadcaster fans values out to any number of subscribers. Subscribers too slow to keep up
+// miss values, rather than holding up the publisher.
+type Broadcaster[T any] struct {
+	mu          sync.Mutex
+	subscribers map[chan T]struct{}
+}
+
+func NewBroadcaster[T any]() *Broadcaster[T] {
+	return &Broadcaster[T]{subscribers: map[chan T]struct{}{}}
+}
+
+// Subscribe returns a channel receiving published values, buffering up to buffer of them.
+// The subscription ends, closing the channel, when the context expires.
+func (b *Broadcaster[T]) Subscribe(ctx context.Context, buffer int) <-chan T {
+	c := make(chan T, buffer)
+	b.mu.Lock()
+	b.subscribers[c] = struct{}{}
+	b.mu.Unlock()
+
+	go func() {
+		<-ctx.Done()
+		b.mu.Lock()
+		delete(b.subscribers, c)
+		close(c)
+		b.mu.Unlock()
+	}()
+
+	return c
+}
+
+// Publish sends a value to every subscriber with room for it.
+func (b *Broadcaster[T]) Publish(v T) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for c := range b.subscribers {
+		select {
+		case c <- v:
+		default:
+		}
+	}
+}
@@ -0,0 +1,39 @@
+package services
+
+import (
+	"context"
+	"github.com/rs/zerolog"
+	"time"
+)
+
+// Job runs a task periodically. Failed runs are logged, and the task run again at the next
+// tick.
+type Job struct {
+	Logger   zerolog.Logger
+	Interval time.Duration
+	RunFunc  func() error
+}
+
+// Spawn kicks off the Job in a new goroutine. The context passed can be used for
+// cancellation.
+func (j *Job) Spawn(ctx context.Context) {
+	go j.Start(ctx)
+}
+
+// Start is like Spawn, but blocks the calling goroutine.
+func (j *Job) Start(ctx context.Context) {
+	ticker := time.NewTicker(j.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := j.RunFunc(); err != nil {
+				j.Logger.Warn().Err(err).Msg("job failed")
+			}
+		case <-ctx.Done():
+			j.Logger.Debug().Str("reason", "context cancellation").Msg("stopping job")
+			return
+		}
+	}
+}
@@ -10,6 +10,8 @@ import (
 type Persister[T any] struct {
 	Logger zerolog.Logger
 	Store  interfaces.Store[T, time.Time]
+	// OnSave, if set, is called with the records new to the Store after each save.
+	OnSave func([]T)
 }
 
 // Spawn kicks off the Persister service in a new goroutine.
@@ -23,7 +25,13 @@ func (p *Persister[T]) Start(ctx context.Context, rx <-chan []T) {
 	for {
 		select {
 		case records := <-rx:
-			p.Store.Save(records)
+			saved, err := p.Store.Save(records)
+			if err != nil {
+				p.Logger.Error().Err(err).Int("records", len(records)).Msg("failed to save records")
+			}
+			if len(saved) > 0 && p.OnSave != nil {
+				p.OnSave(saved)
+			}
 		case <-ctx.Done():
 		}
 	}
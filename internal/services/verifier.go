@@ -0,0 +1,88 @@
+package services
+
+import (
+	"context"
+	"github.com/rs/zerolog"
+	"time"
+)
+
+// Verifier periodically checks that stored records are still available at their source, a
+// batch at a time, cycling through the store. It marks those that are not, and updates the
+// rest to their current versions.
+type Verifier[T any] struct {
+	Logger    zerolog.Logger
+	Interval  time.Duration
+	BatchSize int
+
+	// BatchFunc returns the keys of up to limit records following the one at cursor after,
+	// along with the cursor of the last. Passing a zero cursor starts from the first record.
+	BatchFunc func(after uint, limit int) (keys []string, last uint, err error)
+	// CheckFunc returns the current versions of the records still available, and the keys of
+	// those no longer available, mapped to the reason why.
+	CheckFunc func(keys []string) (current []T, unavailable map[string]string, err error)
+	// UpdateFunc updates records to their current versions.
+	UpdateFunc func(current []T) error
+	// MarkFunc records records as no longer available, returning the number marked.
+	MarkFunc func(unavailable map[string]string) (int64, error)
+}
+
+// Spawn kicks off the Verifier service in a new goroutine. The context passed can be used for
+// cancellation.
+func (v *Verifier[T]) Spawn(ctx context.Context) {
+	go v.Start(ctx)
+}
+
+// Start is like Spawn, but blocks the calling goroutine.
+func (v *Verifier[T]) Start(ctx context.Context) {
+	ticker := time.NewTicker(v.Interval)
+	defer ticker.Stop()
+
+	var cursor uint
+	for {
+		select {
+		case <-ticker.C:
+			cursor = v.verify(cursor)
+		case <-ctx.Done():
+			v.Logger.Debug().Str("reason", "context cancellation").Msg("stopping verify service")
+			return
+		}
+	}
+}
+
+// verify checks the batch of records after a cursor, returning the cursor of the next batch.
+// Failed batches are retried on the next tick.
+func (v *Verifier[T]) verify(cursor uint) uint {
+	keys, last, err := v.BatchFunc(cursor, v.BatchSize)
+	if err != nil {
+		v.Logger.Warn().Err(err).Msg("failed to list records to verify")
+		return cursor
+	}
+	if len(keys) == 0 {
+		if cursor != 0 {
+			v.Logger.Debug().Msg("verified every record, starting over")
+		}
+		return 0
+	}
+
+	current, unavailable, err := v.CheckFunc(keys)
+	if err != nil {
+		v.Logger.Warn().Err(err).Int("records", len(keys)).Msg("failed to verify records")
+		return cursor
+	}
+	if len(current) > 0 {
+		if err := v.UpdateFunc(current); err != nil {
+			v.Logger.Error().Err(err).Int("records", len(current)).Msg("failed to update records")
+			return cursor
+		}
+	}
+	if len(unavailable) > 0 {
+		marked, err := v.MarkFunc(unavailable)
+		if err != nil {
+			v.Logger.Error().Err(err).Int("records", len(unavailable)).
+				Msg("failed to mark unavailable records")
+			return cursor
+		}
+		v.Logger.Info().Int64("records", marked).Msg("marked unavailable records")
+	}
+	return last
+}
@@ -0,0 +1,171 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/rs/zerolog"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeRecords are records at cursors 1 to N, by their keys, with the keys of those no longer
+// available starting with "gone". Checks, updates and marks fail as many times as set to.
+type fakeRecords struct {
+	keys []string
+
+	failChecks, failUpdates, failMarks int
+	// checked are the batches of keys checked, failing or not
+	checked []string
+	updated []string
+	marked  []string
+}
+
+func newFakeRecords(keys ...string) *fakeRecords {
+	return &fakeRecords{keys: keys}
+}
+
+func (f *fakeRecords) verifier() *Verifier[string] {
+	return &Verifier[string]{
+		Logger:    zerolog.Nop(),
+		Interval:  time.Millisecond,
+		BatchSize: 2,
+		BatchFunc: f.batch,
+		CheckFunc: f.check,
+		UpdateFunc: func(current []string) error {
+			if f.failUpdates > 0 {
+				f.failUpdates--
+				return errors.New("update failed")
+			}
+			f.updated = append(f.updated, current...)
+			return nil
+		},
+		MarkFunc: func(unavailable map[string]string) (int64, error) {
+			if f.failMarks > 0 {
+				f.failMarks--
+				return 0, errors.New("mark failed")
+			}
+			for key := range unavailable {
+				f.marked = append(f.marked, key)
+			}
+			return int64(len(unavailable)), nil
+		},
+	}
+}
+
+func (f *fakeRecords) batch(after uint, limit int) ([]string, uint, error) {
+	var keys []string
+	last := after
+	for i := int(after); i < len(f.keys) && len(keys) < limit; i++ {
+		keys = append(keys, f.keys[i])
+		last = uint(i + 1)
+	}
+	return keys, last, nil
+}
+
+func (f *fakeRecords) check(keys []string) ([]string, map[string]string, error) {
+	f.checked = append(f.checked, strings.Join(keys, ","))
+	if f.failChecks > 0 {
+		f.failChecks--
+		return nil, nil, errors.New("api keys exhausted")
+	}
+	var current []string
+	unavailable := map[string]string{}
+	for _, key := range keys {
+		if strings.HasPrefix(key, "gone") {
+			unavailable[key] = "deleted"
+		} else {
+			current = append(current, key+"'")
+		}
+	}
+	return current, unavailable, nil
+}
+
+func TestVerifierCycles(t *testing.T) {
+	f := newFakeRecords("a", "gone-b", "c", "d", "e")
+	v := f.verifier()
+
+	var cursors []uint
+	var cursor uint
+	for i := 0; i < 5; i++ {
+		cursor = v.verify(cursor)
+		cursors = append(cursors, cursor)
+	}
+	if want := []uint{2, 4, 5, 0, 2}; !reflect.DeepEqual(cursors, want) {
+		t.Errorf("cursors = %v, want %v", cursors, want)
+	}
+	if want := []string{"a,gone-b", "c,d", "e", "a,gone-b"}; !reflect.DeepEqual(f.checked, want) {
+		t.Errorf("checked %v, want %v", f.checked, want)
+	}
+	if want := []string{"a'", "c'", "d'", "e'", "a'"}; !reflect.DeepEqual(f.updated, want) {
+		t.Errorf("updated %v, want %v", f.updated, want)
+	}
+	if want := []string{"gone-b", "gone-b"}; !reflect.DeepEqual(f.marked, want) {
+		t.Errorf("marked %v, want %v", f.marked, want)
+	}
+}
+
+func TestVerifierRetriesFromCursor(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		fail func(f *fakeRecords)
+	}{
+		{"check", func(f *fakeRecords) { f.failChecks = 2 }},
+		{"update", func(f *fakeRecords) { f.failUpdates = 2 }},
+		{"mark", func(f *fakeRecords) { f.failMarks = 2 }},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFakeRecords("a", "b", "c", "gone-d", "e")
+			v := f.verifier()
+
+			// The second batch fails twice, and is retried until it succeeds
+			cursor := v.verify(0)
+			tt.fail(f)
+			var cursors []uint
+			for i := 0; i < 4; i++ {
+				cursor = v.verify(cursor)
+				cursors = append(cursors, cursor)
+			}
+			if want := []uint{2, 2, 4, 5}; !reflect.DeepEqual(cursors, want) {
+				t.Errorf("cursors = %v, want %v", cursors, want)
+			}
+			got, want := f.checked[len(f.checked)-2:], []string{"c,gone-d", "e"}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("last checked %v, want %v", got, want)
+			}
+			if want := []string{"gone-d"}; !reflect.DeepEqual(f.marked, want) {
+				t.Errorf("marked %v, want %v", f.marked, want)
+			}
+		})
+	}
+}
+
+func TestVerifierStart(t *testing.T) {
+	f := newFakeRecords("a", "b", "c")
+	f.failChecks = 1
+	v := f.verifier()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	checked := make(chan string)
+	check := v.CheckFunc
+	v.CheckFunc = func(keys []string) ([]string, map[string]string, error) {
+		current, unavailable, err := check(keys)
+		select {
+		case checked <- fmt.Sprint(keys, err):
+		case <-ctx.Done():
+		}
+		return current, unavailable, err
+	}
+	v.Spawn(ctx)
+
+	var got []string
+	for len(got) < 4 {
+		got = append(got, <-checked)
+	}
+	want := []string{"[a b] api keys exhausted", "[a b] <nil>", "[c] <nil>", "[a b] <nil>"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("checked %q, want %q", got, want)
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"google.golang.org/api/googleapi"
 	"google.golang.org/api/option"
 	"google.golang.org/api/youtube/v3"
+	"html"
 	"net/http"
 	"sync"
 	"time"
@@ -16,8 +17,10 @@ import (
 // Client is a wrapper around the YouTube Data API v3, with a method to fetch the latest videos
 // along with multi-token support to circumvent rate-limiting.
 type Client struct {
-	logger       zerolog.Logger
-	tokens       []string
+	logger zerolog.Logger
+	tokens []string
+	// endpoint is the base URL of the API, if not YouTube's
+	endpoint     string
 	muTokenState struct {
 		sync.Mutex
 		Ptr        int
@@ -33,6 +36,13 @@ func WithLogger(logger zerolog.Logger) Opt {
 	}
 }
 
+// WithEndpoint points the client at another endpoint of the API than YouTube's, eg: a fake.
+func WithEndpoint(url string) Opt {
+	return func(c *Client) {
+		c.endpoint = url
+	}
+}
+
 // New returns a new instance of Client, returns a non-nil error if an error is returned by youtube.NewService
 // This function employs the options pattern to configure the client in-situ.
 func New(apiKeys []string, opts ...Opt) (*Client, error) {
@@ -50,7 +60,7 @@ func New(apiKeys []string, opts ...Opt) (*Client, error) {
 // QueryLatestVideos returns Video metas matching a query in reverse chronological order (ie: latest).
 // Query is capped at the default 5 items at the moment.
 func (c *Client) QueryLatestVideos(query string) ([]Video, error) {
-	service, err := youtube.NewService(context.Background(), option.WithAPIKey(c.getCurrentToken()))
+	service, err := c.newService()
 	if err != nil {
 		return nil, errors.Wrap(err, "youtube api service")
 	}
@@ -79,19 +89,192 @@ func (c *Client) QueryLatestVideos(query string) ([]Video, error) {
 	for i, result := range r.Items {
 		publish, _ := time.Parse(time.RFC3339, result.Snippet.PublishedAt)
 		videos[i] = Video{
-			Title:        result.Snippet.Title,
-			Description:  result.Snippet.Description,
-			VideoId:      result.Id.VideoId,
-			PublishedAt:  publish,
-			ThumbnailUrl: result.Snippet.Thumbnails.Default.Url,
+			// Search results escape HTML in titles and descriptions, unlike video listings
+			Title:                html.UnescapeString(result.Snippet.Title),
+			Description:          html.UnescapeString(result.Snippet.Description),
+			VideoId:              result.Id.VideoId,
+			PublishedAt:          publish,
+			ThumbnailUrl:         result.Snippet.Thumbnails.Default.Url,
+			ChannelId:            result.Snippet.ChannelId,
+			ChannelTitle:         result.Snippet.ChannelTitle,
+			LiveBroadcastContent: result.Snippet.LiveBroadcastContent,
 		}
 	}
 
+	// Details are nice-to-have, so the videos are still worth returning without them
+	if err := c.lookupDetails(service, videos); err != nil {
+		c.logger.Warn().Err(err).Msg("video details lookup")
+	}
+
 	// employ the round-robin strategy to cycle between tokens
 	c.useNextToken(true)
 	return videos, nil
 }
 
+// lookupDetails fills in the details of videos that search results leave out: duration,
+// statistics, category and language, along with their full descriptions, which search results
+// truncate.
+func (c *Client) lookupDetails(service *youtube.Service, videos []Video) error {
+	if len(videos) == 0 {
+		return nil
+	}
+
+	ids := make([]string, len(videos))
+	for i := range videos {
+		ids[i] = videos[i].VideoId
+	}
+
+	r, err := service.Videos.List([]string{"snippet", "contentDetails", "statistics"}).
+		Id(ids...).
+		Do()
+	if err != nil {
+		return errors.Wrap(err, "videos list query")
+	}
+
+	details := make(map[string]*youtube.Video, len(r.Items))
+	for _, item := range r.Items {
+		details[item.Id] = item
+	}
+
+	for i := range videos {
+		if d, ok := details[videos[i].VideoId]; ok {
+			c.fillDetails(&videos[i], d)
+		}
+	}
+
+	return nil
+}
+
+// fillDetails fills in a video with those of its details listed in d.
+func (c *Client) fillDetails(v *Video, d *youtube.Video) {
+	if d.Snippet != nil {
+		v.Title = d.Snippet.Title
+		v.Description = d.Snippet.Description
+		v.CategoryId = d.Snippet.CategoryId
+		v.DefaultLanguage = d.Snippet.DefaultAudioLanguage
+		if v.DefaultLanguage == "" {
+			v.DefaultLanguage = d.Snippet.DefaultLanguage
+		}
+	}
+	if d.Statistics != nil {
+		v.ViewCount = int64(d.Statistics.ViewCount)
+		v.LikeCount = int64(d.Statistics.LikeCount)
+		v.CommentCount = int64(d.Statistics.CommentCount)
+	}
+	if d.ContentDetails != nil {
+		duration, err := parseDuration(d.ContentDetails.Duration)
+		if err != nil {
+			c.logger.Debug().Err(err).Str("videoId", d.Id).Msg("video duration")
+		}
+		v.DurationSeconds = int64(duration.Seconds())
+	}
+}
+
+// videoOf returns the video listed as d, with all its details.
+func (c *Client) videoOf(d *youtube.Video) Video {
+	v := Video{VideoId: d.Id}
+	if d.Snippet != nil {
+		v.PublishedAt, _ = time.Parse(time.RFC3339, d.Snippet.PublishedAt)
+		if d.Snippet.Thumbnails != nil && d.Snippet.Thumbnails.Default != nil {
+			v.ThumbnailUrl = d.Snippet.Thumbnails.Default.Url
+		}
+		v.ChannelId = d.Snippet.ChannelId
+		v.ChannelTitle = d.Snippet.ChannelTitle
+		v.LiveBroadcastContent = d.Snippet.LiveBroadcastContent
+	}
+	c.fillDetails(&v, d)
+	return v
+}
+
+// VideosPerCheck is the number of videos CheckAvailability checks per request to the YouTube
+// API, the most it lists by ID at a time. Each request costs a unit of quota, however many
+// parts of videos are listed.
+const VideosPerCheck = 50
+
+// CheckAvailability checks whether videos are still available on YouTube, returning the
+// current versions of those that are, with all their details, and the removal status (see
+// RemovalDeleted and RemovalPrivate) of those that are not, by their IDs.
+func (c *Client) CheckAvailability(ids []string) ([]Video, map[string]string, error) {
+	service, err := c.newService()
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "youtube api service")
+	}
+
+	var available []Video
+	removed := make(map[string]string)
+	for start := 0; start < len(ids); {
+		end := start + VideosPerCheck
+		if end > len(ids) {
+			end = len(ids)
+		}
+		batch := ids[start:end]
+
+		r, err := service.Videos.List(
+			[]string{"snippet", "contentDetails", "statistics", "status"}).
+			Id(batch...).
+			Do()
+		if err != nil {
+			apiError, ok := err.(*googleapi.Error)
+			if ok && apiError.Code == http.StatusForbidden {
+				c.logger.Warn().Int("apiKeyIndex", c.muTokenState.Ptr).Msg("current api key exhausted")
+				if ok := c.useNextToken(false); !ok {
+					return nil, nil, fmt.Errorf("youtube tokens exhausted")
+				}
+				// The failed batch is retried with the next key, and those before it are not
+				// checked again
+				service, err = c.newService()
+				if err != nil {
+					return nil, nil, errors.Wrap(err, "youtube api service")
+				}
+				continue
+			}
+			return nil, nil, errors.Wrap(err, "videos list query")
+		}
+		c.tokenWorked()
+
+		listed := make(map[string]*youtube.Video, len(r.Items))
+		for _, item := range r.Items {
+			listed[item.Id] = item
+		}
+		for _, id := range batch {
+			item, ok := listed[id]
+			switch {
+			case !ok:
+				removed[id] = RemovalDeleted
+			case item.Status != nil && removalStatus(item.Status) != "":
+				removed[id] = removalStatus(item.Status)
+			default:
+				available = append(available, c.videoOf(item))
+			}
+		}
+		start = end
+	}
+
+	c.useNextToken(true)
+	return available, removed, nil
+}
+
+// removalStatus returns the removal status of a video listed with status, if it is removed.
+func removalStatus(status *youtube.VideoStatus) string {
+	switch {
+	case status.UploadStatus == "deleted", status.UploadStatus == "rejected",
+		status.UploadStatus == "failed":
+		return RemovalDeleted
+	case status.PrivacyStatus == "private":
+		return RemovalPrivate
+	}
+	return ""
+}
+
+// newService returns a service calling the API with the API token currently in use.
+func (c *Client) newService() (*youtube.Service, error) {
+	opts := []option.ClientOption{option.WithAPIKey(c.getCurrentToken())}
+	if c.endpoint != "" {
+		opts = append(opts, option.WithEndpoint(c.endpoint))
+	}
+	return youtube.NewService(context.Background(), opts...)
+}
+
 // getCurrentToken returns the API token currently in-use by the client
 func (c *Client) getCurrentToken() string {
 	c.muTokenState.Lock()
@@ -99,6 +282,14 @@ func (c *Client) getCurrentToken() string {
 	return c.tokens[c.muTokenState.Ptr]
 }
 
+// tokenWorked records that the API token in use worked, so that it is not taken for exhausted
+// along with the next should that fail.
+func (c *Client) tokenWorked() {
+	c.muTokenState.Lock()
+	defer c.muTokenState.Unlock()
+	c.muTokenState.lastWorked = true
+}
+
 // useNextToken switches out the API token in-use by the client to bypass token-based rate limiting.
 func (c *Client) useNextToken(currentWorked bool) bool {
 	c.muTokenState.Lock()
@@ -108,7 +299,9 @@ func (c *Client) useNextToken(currentWorked bool) bool {
 		return false
 	}
 	c.muTokenState.Ptr = (c.muTokenState.Ptr + 1) % len(c.tokens)
-	c.muTokenState.lastWorked = false
+	// Tokens are taken for exhausted once two fail in a row, so the token rotated to after
+	// one worked may fail once
+	c.muTokenState.lastWorked = currentWorked
 	c.logger.Debug().Int("index", c.muTokenState.Ptr).Msg("switching youtube api token")
 	return true
 }
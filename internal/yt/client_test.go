@@ -0,0 +1,173 @@
+package yt
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/rs/zerolog"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeAPI serves videos.list, with each of its API keys exhausted after some number of
+// requests. Videos are listed unless their IDs start with "deleted" or "private".
+type fakeAPI struct {
+	mu sync.Mutex
+	// quotas are the number of requests left to each key
+	quotas map[string]int
+	// calls are the key and first and last video ID of each request, in order
+	calls []string
+}
+
+func (f *fakeAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	key := r.URL.Query().Get("key")
+	var ids []string
+	for _, id := range r.URL.Query()["id"] {
+		ids = append(ids, strings.Split(id, ",")...)
+	}
+	f.calls = append(f.calls, fmt.Sprintf("%s %s-%s", key, ids[0], ids[len(ids)-1]))
+	if f.quotas[key] == 0 {
+		w.WriteHeader(http.StatusForbidden)
+		_, _ = fmt.Fprint(w, `{"error": {"code": 403, "message": "quota exceeded"}}`)
+		return
+	}
+	f.quotas[key]--
+
+	type item struct {
+		ID      string            `json:"id"`
+		Snippet map[string]string `json:"snippet"`
+		Status  map[string]string `json:"status"`
+	}
+	var items []item
+	for _, id := range ids {
+		switch {
+		case strings.HasPrefix(id, "deleted"):
+		case strings.HasPrefix(id, "private"):
+			items = append(items, item{ID: id, Status: map[string]string{
+				"uploadStatus": "processed", "privacyStatus": "private",
+			}})
+		default:
+			items = append(items, item{ID: id, Snippet: map[string]string{"title": "Video " + id}})
+		}
+	}
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{"items": items})
+}
+
+// newFakeClient returns a client of a fake API, with keys that each serve some number of
+// requests.
+func newFakeClient(t *testing.T, keys []string, quotas ...int) (*Client, *fakeAPI) {
+	t.Helper()
+	api := &fakeAPI{quotas: map[string]int{}}
+	for i, key := range keys {
+		api.quotas[key] = quotas[i]
+	}
+	srv := httptest.NewServer(api)
+	t.Cleanup(srv.Close)
+
+	c, err := New(keys, WithLogger(zerolog.Nop()), WithEndpoint(srv.URL+"/"))
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	return c, api
+}
+
+// videoIDs returns n video IDs, v000 and on, with every tenth deleted and every
+// fifteenth private.
+func videoIDs(n int) []string {
+	ids := make([]string, n)
+	for i := range ids {
+		switch {
+		case i%10 == 5:
+			ids[i] = fmt.Sprintf("deleted%03d", i)
+		case i%15 == 7:
+			ids[i] = fmt.Sprintf("private%03d", i)
+		default:
+			ids[i] = fmt.Sprintf("v%03d", i)
+		}
+	}
+	return ids
+}
+
+func TestCheckAvailability(t *testing.T) {
+	c, api := newFakeClient(t, []string{"k0"}, 10)
+	ids := videoIDs(60)
+
+	available, unavailable, err := c.CheckAvailability(ids)
+	if err != nil {
+		t.Fatalf("CheckAvailability: %v", err)
+	}
+	if want := []string{"k0 v000-v049", "k0 v050-v059"}; !reflect.DeepEqual(api.calls, want) {
+		t.Errorf("calls = %v, want %v", api.calls, want)
+	}
+	checkResults(t, ids, available, unavailable)
+}
+
+func TestCheckAvailabilityRotatesKeys(t *testing.T) {
+	// The first key runs out partway through, on the second batch
+	c, api := newFakeClient(t, []string{"k0", "k1"}, 1, 10)
+	ids := videoIDs(120)
+
+	available, unavailable, err := c.CheckAvailability(ids)
+	if err != nil {
+		t.Fatalf("CheckAvailability: %v", err)
+	}
+	// The batch failing is retried with the next key, and the checks resume from it
+	want := []string{"k0 v000-v049", "k0 v050-v099", "k1 v050-v099", "k1 v100-v119"}
+	if !reflect.DeepEqual(api.calls, want) {
+		t.Errorf("calls = %v, want %v", api.calls, want)
+	}
+	checkResults(t, ids, available, unavailable)
+
+	// Checks rotate through keys as they succeed
+	api.calls = nil
+	if _, _, err := c.CheckAvailability(ids[:1]); err != nil {
+		t.Fatalf("CheckAvailability: %v", err)
+	}
+	if want := []string{"k0 v000-v000", "k1 v000-v000"}; !reflect.DeepEqual(api.calls, want) {
+		t.Errorf("calls after a rotation = %v, want %v", api.calls, want)
+	}
+}
+
+func TestCheckAvailabilityKeysExhausted(t *testing.T) {
+	c, api := newFakeClient(t, []string{"k0", "k1"}, 1, 0)
+
+	_, _, err := c.CheckAvailability(videoIDs(120))
+	if err == nil {
+		t.Fatalf("CheckAvailability with every key exhausted succeeded")
+	}
+	want := []string{"k0 v000-v049", "k0 v050-v099", "k1 v050-v099"}
+	if !reflect.DeepEqual(api.calls, want) {
+		t.Errorf("calls = %v, want %v", api.calls, want)
+	}
+}
+
+// checkResults checks that the results of checking the availability of ids cover each of
+// them once, as listed by fakeAPI.
+func checkResults(t *testing.T, ids []string, available []Video, unavailable map[string]string) {
+	t.Helper()
+	if n := len(available) + len(unavailable); n != len(ids) {
+		t.Errorf("checked %d videos, want %d", n, len(ids))
+	}
+	seen := map[string]bool{}
+	for _, v := range available {
+		if seen[v.VideoId] || !strings.HasPrefix(v.VideoId, "v") || v.Title != "Video "+v.VideoId {
+			t.Errorf("available video %+v", v)
+		}
+		seen[v.VideoId] = true
+	}
+	for id, status := range unavailable {
+		want := RemovalDeleted
+		if strings.HasPrefix(id, "private") {
+			want = RemovalPrivate
+		}
+		if status != want {
+			t.Errorf("removal status of %s = %q, want %q", id, status, want)
+		}
+	}
+}
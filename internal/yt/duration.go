@@ -0,0 +1,37 @@
+package yt
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// isoDuration matches the subset of ISO 8601 durations used by the YouTube API, eg: PT1H2M3S
+// or P1DT3M.
+var isoDuration = regexp.MustCompile(`^P(?:(\d+)D)?(?:T(?:(\d+)H)?(?:(\d+)M)?(?:(\d+)S)?)?$`)
+
+// parseDuration parses an ISO 8601 video duration as returned by the YouTube API.
+func parseDuration(s string) (time.Duration, error) {
+	m := isoDuration.FindStringSubmatch(s)
+	// Durations have a component at least, and a time component if T is there to separate one
+	if m == nil || s == "P" || strings.HasSuffix(s, "T") {
+		return 0, fmt.Errorf("invalid duration %q", s)
+	}
+
+	units := []time.Duration{24 * time.Hour, time.Hour, time.Minute, time.Second}
+	var d time.Duration
+	for i, unit := range units {
+		if m[i+1] == "" {
+			continue
+		}
+		n, err := strconv.ParseInt(m[i+1], 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid duration %q", s)
+		}
+		d += time.Duration(n) * unit
+	}
+
+	return d, nil
+}
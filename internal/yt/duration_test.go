@@ -0,0 +1,50 @@
+package yt
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseDuration(t *testing.T) {
+	for _, tt := range []struct {
+		in   string
+		want time.Duration
+	}{
+		{"PT1H2M3S", time.Hour + 2*time.Minute + 3*time.Second},
+		{"P1DT3M", 24*time.Hour + 3*time.Minute},
+		{"P0D", 0},
+		{"PT45S", 45 * time.Second},
+		{"PT10M", 10 * time.Minute},
+		{"PT2H", 2 * time.Hour},
+		{"P2D", 48 * time.Hour},
+		{"PT90S", 90 * time.Second},
+	} {
+		got, err := parseDuration(tt.in)
+		if err != nil || got != tt.want {
+			t.Errorf("parseDuration(%q) = %v, %v, want %v", tt.in, got, err, tt.want)
+		}
+	}
+}
+
+func TestParseDurationMalformed(t *testing.T) {
+	for _, in := range []string{
+		"",
+		"P",
+		"PT",
+		"P1DT",
+		"1H2M3S",
+		"PT1H2M3",
+		"PT3S2M",
+		"PT1.5S",
+		"PT-1S",
+		"P1W",
+		"P1Y2M",
+		"pt1h",
+		" PT1H",
+		"PT99999999999999999999S",
+	} {
+		if got, err := parseDuration(in); err == nil {
+			t.Errorf("parseDuration(%q) = %v, want an error", in, got)
+		}
+	}
+}
@@ -5,26 +5,62 @@ import (
 	"time"
 )
 
+// Values of Video.LiveBroadcastContent, as reported by the YouTube API.
+const (
+	BroadcastNone     = "none"
+	BroadcastLive     = "live"
+	BroadcastUpcoming = "upcoming"
+)
+
+// Values of Video.RemovalStatus, telling why a video is no longer available on YouTube.
+const (
+	// RemovalDeleted is the status of videos the YouTube API no longer lists, or lists as
+	// deleted or rejected. The API does not list private videos to anyone but their owners
+	// either, so videos made private are mostly reported as deleted.
+	RemovalDeleted = "deleted"
+	// RemovalPrivate is the status of videos the YouTube API lists as private.
+	RemovalPrivate = "private"
+)
+
+// Video is a YouTube video, as stored. Channel titles and statistics are left out of its JSON,
+// as clients embed them in videos on request.
 type Video struct {
-	VideoId      string `gorm:"unique;not null"`
-	Title        string
-	Description  string
-	PublishedAt  time.Time
-	ThumbnailUrl string
-}
+	// ID identifies the video in the store, and is zero until it is saved.
+	ID uint `gorm:"primaryKey" json:"id"`
+	// CreatedAt is the time the video was first seen, ie: stored.
+	CreatedAt time.Time `json:"first_seen_at"`
+	// UpdatedAt is the time the content of the video was last edited, or first seen.
+	UpdatedAt time.Time `json:"-"`
+	// DeletedAt is the time the video was found to be removed from YouTube, if it was.
+	// Removed videos are soft-deleted, so queries leave them out unless asked otherwise.
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"removed_at"`
+	// RemovalStatus is one of the Removal* values for removed videos, and empty otherwise.
+	RemovalStatus string `json:"removal_status,omitempty"`
 
-func (Video) TableName() string {
-	return "videos"
-}
+	VideoId      string    `gorm:"unique;not null" json:"video_id"`
+	Title        string    `json:"title"`
+	Description  string    `json:"description"`
+	PublishedAt  time.Time `json:"published_at"`
+	ThumbnailUrl string    `json:"thumbnail_url"`
+
+	ChannelId    string `gorm:"index" json:"channel_id"`
+	ChannelTitle string `json:"-"`
+	// LiveBroadcastContent is one of BroadcastNone (ie: an upload), BroadcastLive or
+	// BroadcastUpcoming.
+	LiveBroadcastContent string `json:"live_broadcast_content"`
 
-type VideoFull struct {
-	gorm.Model
-	Video
-	// `tsvector` for postgres native full-text search
-	// @todo include the description column, perhaps with a reduced weight.
-	TSV string `gorm:"->;type:tsvector GENERATED ALWAYS AS (to_tsvector('english', title)) STORED;default:(-)"`
+	// Details below are not part of search results, and are zero-valued until looked up.
+	DurationSeconds int64  `json:"duration_seconds"`
+	ViewCount       int64  `json:"-"`
+	LikeCount       int64  `json:"-"`
+	CommentCount    int64  `json:"-"`
+	DefaultLanguage string `json:"default_language"`
+	CategoryId      string `json:"category_id"`
+
+	// Columns backing full-text search, such as the tsv column of postgres, are particular to
+	// each database, so are left to the migrations of store/migrations.
 }
 
-func (VideoFull) TableName() string {
+func (Video) TableName() string {
 	return "videos"
 }
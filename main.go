@@ -4,10 +4,13 @@ import (
 	"context"
 	"fmt"
 	"github.com/ditsuke/youtube-focus/api"
+	"github.com/ditsuke/youtube-focus/api/cache"
+	"github.com/ditsuke/youtube-focus/api/rpc"
 	"github.com/ditsuke/youtube-focus/config"
 	"github.com/ditsuke/youtube-focus/internal/services"
 	"github.com/ditsuke/youtube-focus/internal/yt"
 	"github.com/ditsuke/youtube-focus/store"
+	"github.com/ditsuke/youtube-focus/store/migrations"
 	_ "github.com/joho/godotenv/autoload"
 	"github.com/rs/zerolog"
 	"github.com/sethvargo/go-envconfig"
@@ -26,10 +29,12 @@ const (
 )
 
 type superCtx struct {
-	ctx    context.Context
-	store  *store.VideoMetaStore
-	logger *zerolog.Logger
-	cfg    config.Config
+	ctx       context.Context
+	store     store.ManagedVideoStore
+	cache     *cache.Cache
+	newVideos *services.Broadcaster[[]yt.Video]
+	logger    *zerolog.Logger
+	cfg       config.Config
 }
 
 func main() {
@@ -39,31 +44,98 @@ func main() {
 	if err := envconfig.Process(context.Background(), &cfg); err != nil {
 		logger.Fatal().Err(err).Msg("config from environment")
 	}
-	logger.Info().Msg(fmt.Sprintf("config=%+v", cfg))
+	logger.Info().Msg(fmt.Sprintf("config=%+v", cfg.Redacted()))
 
 	db, err := cfg.GetDB()
 	if err != nil {
 		logger.Fatal().Err(err).Str("operation", "db-connect").Msg("failed")
 	}
-	videoStore := &store.VideoMetaStore{DB: db}
+
+	// An in-memory database starts out empty each run, so it cannot be migrated beforehand
+	if cfg.InMemory() {
+		if _, err := migrations.Up(db); err != nil {
+			logger.Fatal().Err(err).Str("operation", "db-migrations").Msg("failed")
+		}
+	}
+
+	// Refuse to run against a schema other than the one we expect
+	pending, err := migrations.Pending(db)
+	if err != nil {
+		logger.Fatal().Err(err).Str("operation", "db-migrations").Msg("failed")
+	}
+	if len(pending) > 0 {
+		logger.Fatal().Int("pending", len(pending)).
+			Msg("database is not migrated, run `migrate up` first")
+	}
+	responseCache := cache.New(cfg.APICacheSize, time.Duration(cfg.APICacheMaxAge)*time.Second,
+		time.Duration(cfg.APICacheTTL)*time.Second)
+
+	// Cached responses are stale once videos are edited, as they are once new ones are in
+	onEdit := func([]yt.Video) { responseCache.Purge() }
+
+	// The APIs read from replicas, if any, while videos are always written to the primary
+	readDB, err := cfg.GetReadDB(logger.With().Str(service, "db").Logger())
+	if err != nil {
+		logger.Fatal().Err(err).Str("operation", "db-connect").Msg("failed")
+	}
+
+	var videoStore store.ManagedVideoStore
+	var readVideoStore store.VideoStore
+	if cfg.DBDriver == config.DriverMemory {
+		memoryStore := store.NewMemoryStore()
+		memoryStore.Logger = logger.With().Str(service, "store").Logger()
+		memoryStore.Searches = &store.SavedSearchStore{DB: db}
+		memoryStore.OnEdit = onEdit
+		videoStore, readVideoStore = memoryStore, memoryStore
+	} else {
+		videoStore = &store.VideoMetaStore{
+			Logger:         logger.With().Str(service, "store").Logger(),
+			DB:             db,
+			FuzzyThreshold: cfg.SearchSimilarityThreshold,
+			OnEdit:         onEdit,
+		}
+		readVideoStore = &store.VideoMetaStore{
+			DB:             readDB,
+			FuzzyThreshold: cfg.SearchSimilarityThreshold,
+		}
+	}
+
+	newVideos := services.NewBroadcaster[[]yt.Video]()
 
 	ctx, ctxCancel := context.WithCancel(context.Background())
 	defer ctxCancel()
 
 	spawnBackgroundServices(superCtx{
-		ctx:    ctx,
-		logger: &logger,
-		cfg:    cfg,
-		store:  videoStore,
+		ctx:       ctx,
+		logger:    &logger,
+		cfg:       cfg,
+		store:     videoStore,
+		cache:     responseCache,
+		newVideos: newVideos,
 	})
 
 	server := api.Server{
-		Cfg:    cfg,
-		Logger: logger.With().Str(service, "api-server").Logger(),
+		Cfg:       cfg,
+		Logger:    logger.With().Str(service, "api-server").Logger(),
+		DB:        readDB,
+		Cache:     responseCache,
+		NewVideos: newVideos,
+	}
+	if cfg.DBDriver == config.DriverMemory {
+		server.Videos = videoStore
+	}
+
+	rpcServer := rpc.Server{
+		Cfg:       cfg,
+		Logger:    logger.With().Str(service, "grpc-server").Logger(),
+		DB:        readDB,
+		Store:     readVideoStore,
+		NewVideos: newVideos,
 	}
 
 	logger.Info().Msg("starting server...")
 	go server.StartServer(ctx)
+	go rpcServer.StartServer(ctx)
 
 	sig := make(chan os.Signal, 1)
 	signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
@@ -85,7 +157,8 @@ func main() {
 	}
 }
 
-// spawnBackgroundServices spawns services to fetch and store the latest videos from YouTube.
+// spawnBackgroundServices spawns services to fetch and store the latest videos from YouTube,
+// to verify that those stored are still there, and to prune those past their retention.
 func spawnBackgroundServices(s superCtx) {
 	c := make(chan []yt.Video)
 	ytClient, err := yt.New(s.cfg.YouTubeAPIKeys,
@@ -106,8 +179,59 @@ func spawnBackgroundServices(s superCtx) {
 	persister := services.Persister[yt.Video]{
 		Logger: s.logger.With().Str("comp", "persister").Logger(),
 		Store:  s.store,
+		OnSave: func(saved []yt.Video) {
+			// Cached responses are stale once new videos are in
+			s.cache.Purge()
+			s.newVideos.Publish(saved)
+		},
 	}
 
 	fetcher.Spawn(s.ctx, c)
 	persister.Spawn(s.ctx, c)
+
+	if s.cfg.VerifyInterval > 0 {
+		verifier := services.Verifier[yt.Video]{
+			Logger:    s.logger.With().Str(service, "video-verifier").Logger(),
+			Interval:  time.Duration(s.cfg.VerifyInterval) * time.Second,
+			BatchSize: s.cfg.VerifyBatchSize,
+			BatchFunc: s.store.AvailableIDs,
+			CheckFunc: ytClient.CheckAvailability,
+			// Videos are edited long after the fetcher last sees them, so edits are caught as
+			// they are verified too
+			UpdateFunc: s.store.Refresh,
+			MarkFunc: func(removed map[string]string) (int64, error) {
+				marked, err := s.store.MarkRemoved(removed)
+				if marked > 0 {
+					// Cached responses are stale once videos are removed
+					s.cache.Purge()
+				}
+				return marked, err
+			},
+		}
+		verifier.Spawn(s.ctx)
+	}
+
+	if s.cfg.PruneInterval > 0 {
+		logger := s.logger.With().Str(service, "video-pruner").Logger()
+		policy := store.RetentionPolicy{Days: s.cfg.RetentionDays, Archive: s.cfg.RetentionArchive}
+		pruner := services.Job{
+			Logger:   logger,
+			Interval: time.Duration(s.cfg.PruneInterval) * time.Second,
+			RunFunc: func() error {
+				report, err := s.store.Prune(policy, s.cfg.PruneBatchSize, s.cfg.PruneDryRun)
+				if report.Videos > 0 {
+					logger.Info().Bool("dryRun", report.DryRun).Bool("archived", report.Archived).
+						Int64("videos", report.Videos).
+						Time("oldest", report.Oldest).Time("newest", report.Newest).
+						Msg("pruned videos past their retention")
+					if !report.DryRun {
+						// Cached responses are stale once videos are pruned
+						s.cache.Purge()
+					}
+				}
+				return err
+			},
+		}
+		pruner.Spawn(s.ctx)
+	}
 }
@@ -0,0 +1,96 @@
+package store
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"github.com/rs/zerolog"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"time"
+)
+
+const (
+	// apiKeyPrefix marks secrets as API keys of this service.
+	apiKeyPrefix = "ytf_"
+	// apiKeyBytes is the number of random bytes in a key.
+	apiKeyBytes = 24
+	// apiKeyVisibleChars is the number of leading characters of a key stored in the clear, to
+	// tell keys apart.
+	apiKeyVisibleChars = len(apiKeyPrefix) + 6
+)
+
+// APIKey is a credential for a client of the REST API. Only a hash of the secret key is
+// stored.
+type APIKey struct {
+	gorm.Model
+	Name string
+	// Prefix is the start of the secret key, to help identify it.
+	Prefix string
+	Hash   string `gorm:"uniqueIndex;not null"`
+	// RateLimit is the number of requests per minute allowed with the key. Zero selects the
+	// server default.
+	RateLimit int
+	Role      Role `gorm:"not null;default:reader"`
+	RevokedAt *time.Time
+}
+
+// APIKeyStore is the storage for client API keys.
+type APIKeyStore struct {
+	Logger zerolog.Logger
+	DB     *gorm.DB
+}
+
+// Issue a new API key, saving it to key and returning its secret. The secret cannot be
+// recovered later.
+func (s *APIKeyStore) Issue(key *APIKey) (string, error) {
+	raw := make([]byte, apiKeyBytes)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+
+	secret := apiKeyPrefix + hex.EncodeToString(raw)
+	key.Prefix = secret[:apiKeyVisibleChars]
+	key.Hash = hashAPIKey(secret)
+	if err := s.DB.Create(key).Error; err != nil {
+		return "", wrapErr(err)
+	}
+
+	return secret, nil
+}
+
+// Authenticate returns the unrevoked API key with the passed secret.
+// Returns ErrNotFound if there is no such key.
+func (s *APIKeyStore) Authenticate(secret string) (APIKey, error) {
+	var key APIKey
+	err := s.DB.
+		Where("hash = ? AND revoked_at IS NULL", hashAPIKey(secret)).
+		Take(&key).Error
+	return key, wrapErr(err)
+}
+
+// List all API keys, revoked or not, oldest first.
+func (s *APIKeyStore) List() ([]APIKey, error) {
+	var keys []APIKey
+	err := s.DB.Order("id").Find(&keys).Error
+	return keys, wrapErr(err)
+}
+
+// Revoke an API key by ID, returning the revoked key.
+// Returns ErrNotFound if there is no such unrevoked key.
+func (s *APIKeyStore) Revoke(id uint) (APIKey, error) {
+	var key APIKey
+	result := s.DB.Model(&key).
+		Clauses(clause.Returning{}).
+		Where("id = ? AND revoked_at IS NULL", id).
+		Update("revoked_at", s.DB.NowFunc())
+	if result.Error == nil && result.RowsAffected == 0 {
+		return key, wrapErr(gorm.ErrRecordNotFound)
+	}
+	return key, wrapErr(result.Error)
+}
+
+func hashAPIKey(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,49 @@
+package store
+
+import (
+	"github.com/rs/zerolog"
+	"gorm.io/gorm"
+	"time"
+)
+
+// AuditEntry records a change made through the API.
+type AuditEntry struct {
+	ID        uint `gorm:"primarykey"`
+	CreatedAt time.Time
+	// ActorKeyID is the ID of the API key the change was made with, nil if it was made with
+	// the admin token or without authentication.
+	ActorKeyID *uint
+	// Actor describes who made the change.
+	Actor string
+	// Action is the API operation, eg: "DELETE /v1/admin/keys/{id}".
+	Action string
+	// Target is the path of the resource changed.
+	Target string
+	// Detail is the request payload, if any.
+	Detail string
+	// Status is the HTTP status the change was responded to with.
+	Status int
+}
+
+// AuditStore is the storage for the audit log.
+type AuditStore struct {
+	Logger zerolog.Logger
+	DB     *gorm.DB
+}
+
+// Record an entry in the audit log.
+func (s *AuditStore) Record(entry *AuditEntry) error {
+	return wrapErr(s.DB.Create(entry).Error)
+}
+
+// Retrieve a maximum of limit audit log entries, latest first. Passing the ID of the last
+// entry in a result as beforeID gets the next batch; zero starts from the latest entry.
+func (s *AuditStore) Retrieve(beforeID uint, limit int) ([]AuditEntry, error) {
+	entries := make([]AuditEntry, 0, limit)
+	tx := s.DB.Order("id DESC").Limit(limit)
+	if beforeID > 0 {
+		tx = tx.Where("id < ?", beforeID)
+	}
+	err := tx.Find(&entries).Error
+	return entries, wrapErr(err)
+}
@@ -0,0 +1,146 @@
+package store
+
+import (
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/plugin/dbresolver"
+	"strconv"
+	"strings"
+)
+
+// backend holds the SQL that differs between the databases a VideoMetaStore can run on, as
+// scopes of its queries.
+type backend interface {
+	// contains matches videos whose title or description contain query, ignoring case.
+	contains(query string) func(*gorm.DB) *gorm.DB
+	// naturalMatch matches videos by any of the words of a natural-language query.
+	naturalMatch(words []string) func(*gorm.DB) *gorm.DB
+	// fuzzyMatch matches videos whose title or description nearly contain query.
+	fuzzyMatch(query string) func(*gorm.DB) *gorm.DB
+	// suggestions matches the videos whose titles nearly contain query, ordering them by
+	// similarity.
+	suggestions(query string) func(*gorm.DB) *gorm.DB
+	// withFuzzyThreshold runs fn with a connection on which fuzzyMatch and suggestions match
+	// by similarity of at least threshold.
+	withFuzzyThreshold(db *gorm.DB, threshold float64, fn func(tx *gorm.DB) error) error
+	// dayExpr is the SQL expression of the UTC day videos were published on, as YYYY-MM-DD.
+	dayExpr() string
+}
+
+// backend returns the backend of the database the store runs on.
+func (v *VideoMetaStore) backend() backend {
+	if v.DB.Dialector.Name() == "sqlite" {
+		return sqliteBackend{}
+	}
+	return postgresBackend{}
+}
+
+// postgresBackend searches with native full-text search and pg_trgm trigram similarity.
+type postgresBackend struct{}
+
+func (postgresBackend) contains(query string) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("title ILIKE ? OR description ILIKE ?", "%"+query+"%", "%"+query+"%")
+	}
+}
+
+func (postgresBackend) naturalMatch(words []string) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("tsv @@ to_tsquery('english', ?)", strings.Join(words, "|"))
+	}
+}
+
+func (postgresBackend) fuzzyMatch(query string) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("? <% title OR ? <% description", query, query)
+	}
+}
+
+func (postgresBackend) suggestions(query string) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("? <% title", query).
+			Clauses(clause.OrderBy{Expression: clause.Expr{
+				SQL:  "word_similarity(?, title) DESC",
+				Vars: []interface{}{query},
+			}})
+	}
+}
+
+// withFuzzyThreshold runs fn in a transaction with the pg_trgm word-similarity threshold (used
+// by the <% operator and its GIN index) set to threshold. The transaction reads from a
+// replica, if the DB has any, as transactions are otherwise made on the primary.
+func (postgresBackend) withFuzzyThreshold(db *gorm.DB, threshold float64,
+	fn func(tx *gorm.DB) error,
+) error {
+	return db.Clauses(dbresolver.Read).Transaction(func(tx *gorm.DB) error {
+		err := tx.Exec("SELECT set_config('pg_trgm.word_similarity_threshold', ?, true)",
+			strconv.FormatFloat(threshold, 'f', -1, 64)).Error
+		if err != nil {
+			return err
+		}
+		return fn(tx)
+	})
+}
+
+func (postgresBackend) dayExpr() string {
+	return "to_char(published_at AT TIME ZONE 'UTC', 'YYYY-MM-DD')"
+}
+
+// sqliteBackend searches with the FTS5 index of video titles, videos_fts. SQLite has no
+// trigram similarity, so fuzzy matches are looser substring matches instead.
+type sqliteBackend struct{}
+
+func (sqliteBackend) contains(query string) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		// LIKE ignores the case of ASCII letters in SQLite
+		return db.Where("title LIKE ? OR description LIKE ?", "%"+query+"%", "%"+query+"%")
+	}
+}
+
+func (sqliteBackend) naturalMatch(words []string) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("id IN (SELECT rowid FROM videos_fts WHERE videos_fts MATCH ?)",
+			ftsQuery(words, ""))
+	}
+}
+
+// fuzzyMatch matches videos containing every word of query, in any order.
+func (sqliteBackend) fuzzyMatch(query string) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		for _, word := range strings.Fields(query) {
+			db = db.Where("title LIKE ? OR description LIKE ?", "%"+word+"%", "%"+word+"%")
+		}
+		return db
+	}
+}
+
+// suggestions matches the videos with title words starting as any of the words of query,
+// best ranked first.
+func (sqliteBackend) suggestions(query string) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.
+			Joins("JOIN videos_fts ON videos_fts.rowid = videos.id").
+			Where("videos_fts MATCH ?", ftsQuery(strings.Fields(query), "*")).
+			Order("MIN(videos_fts.rank)")
+	}
+}
+
+func (sqliteBackend) withFuzzyThreshold(db *gorm.DB, _ float64,
+	fn func(tx *gorm.DB) error,
+) error {
+	return fn(db)
+}
+
+func (sqliteBackend) dayExpr() string {
+	return "strftime('%Y-%m-%d', published_at)"
+}
+
+// ftsQuery returns the FTS5 query matching any of words, each quoted so that none is taken
+// for query syntax, and followed by suffix.
+func ftsQuery(words []string, suffix string) string {
+	quoted := make([]string, len(words))
+	for i, w := range words {
+		quoted[i] = `"` + strings.ReplaceAll(w, `"`, `""`) + `"` + suffix
+	}
+	return strings.Join(quoted, " OR ")
+}
@@ -0,0 +1,56 @@
+package store
+
+import (
+	"github.com/ditsuke/youtube-focus/internal/yt"
+	"time"
+)
+
+// Channel is a YouTube channel, as known by its videos.
+type Channel struct {
+	ID    string
+	Title string
+}
+
+// Channels looks up channels by their IDs, in no particular order, titled as in their latest
+// videos. IDs of channels without videos in the store are ignored.
+func (v *VideoMetaStore) Channels(channelIDs []string) ([]Channel, error) {
+	latest := v.DB.Model(&yt.Video{}).
+		Select("channel_id, channel_title, ROW_NUMBER() OVER "+
+			"(PARTITION BY channel_id ORDER BY published_at DESC) AS channel_rank").
+		Where("channel_id IN ?", channelIDs)
+
+	channels := make([]Channel, 0, len(channelIDs))
+	err := v.DB.Table("(?) AS latest", latest).
+		Select("channel_id AS id, channel_title AS title").
+		Where("channel_rank = 1").
+		Scan(&channels).Error
+	return channels, wrapErr(err)
+}
+
+// ChannelVideos retrieves a maximum of limit videos published before some time.Time for each
+// of a number of channels, in one query. Videos are keyed by their channel ID, and sorted by
+// latest first.
+func (v *VideoMetaStore) ChannelVideos(channelIDs []string, publishedBefore time.Time,
+	limit int,
+) (map[string][]yt.Video, error) {
+	ranked := v.query().Model(&yt.Video{}).
+		Select("*, ROW_NUMBER() OVER "+
+			"(PARTITION BY channel_id ORDER BY published_at DESC) AS channel_rank").
+		Where("channel_id IN ?", channelIDs).
+		Where("published_at < ?", publishedBefore.UTC())
+
+	var videos []yt.Video
+	err := v.DB.Unscoped().Table("(?) AS ranked", ranked).
+		Where("channel_rank <= ?", limit).
+		Order(OrderReverseChrono).
+		Find(&videos).Error
+	if err != nil {
+		return nil, wrapErr(err)
+	}
+
+	byChannel := make(map[string][]yt.Video, len(channelIDs))
+	for _, video := range videos {
+		byChannel[video.ChannelId] = append(byChannel[video.ChannelId], video)
+	}
+	return byChannel, nil
+}
@@ -0,0 +1,47 @@
+package dump
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+)
+
+// Checkpoint records the progress of a dump or load, so that it can be resumed once
+// interrupted.
+type Checkpoint struct {
+	// Format is the format of the dump.
+	Format string `json:"format"`
+	// Records is the number of videos dumped or loaded.
+	Records int64 `json:"records"`
+	// LastID is the ID in the store of the last video dumped.
+	LastID uint `json:"last_id,omitempty"`
+	// Offset is the size of the dump once its last video was written out.
+	Offset int64 `json:"offset,omitempty"`
+}
+
+// ReadCheckpoint reads the checkpoint saved at path, which is zero-valued if there is none.
+func ReadCheckpoint(path string) (Checkpoint, error) {
+	var c Checkpoint
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return c, nil
+	}
+	if err != nil {
+		return c, err
+	}
+	return c, json.Unmarshal(data, &c)
+}
+
+// Save saves the checkpoint at path, replacing the last one at once, so that an interrupted
+// save leaves the last in place.
+func (c Checkpoint) Save(path string) error {
+	data, err := json.Marshal(c)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path+".tmp", data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(path+".tmp", path)
+}
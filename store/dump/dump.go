@@ -0,0 +1,319 @@
+// Package dump reads and writes videos in files, to copy a store to another. Files are either
+// NDJSON, a video per line, or columnar: a series of gzip-compressed row groups, each holding
+// the values of its videos column by column, much as Parquet files do. Columnar files are
+// several times smaller, as values of a column compress well together.
+//
+// Both formats hold every stored field of videos, such as the times they were first seen and
+// removed, but not the revisions of their content.
+//
+// Dump and Load copy videos between stores and dumps, checkpointing as they go, so that
+// cmd/dump and cmd/load can resume them once interrupted.
+package dump
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"github.com/ditsuke/youtube-focus/internal/yt"
+	"gorm.io/gorm"
+	"io"
+	"time"
+)
+
+// Formats of dumps.
+const (
+	FormatNDJSON   = "ndjson"
+	FormatColumnar = "columnar"
+)
+
+// RowGroupSize is the number of videos per row group of columnar files, unless flushed early.
+const RowGroupSize = 1000
+
+// Writer writes videos to a dump.
+type Writer interface {
+	Write(v *yt.Video) error
+	// Flush writes out buffered videos, ending the row group of columnar files.
+	Flush() error
+}
+
+// Reader reads videos from a dump, returning io.EOF once there are no more.
+type Reader interface {
+	Read() (yt.Video, error)
+}
+
+// NewWriter returns a Writer of dumps in a format.
+func NewWriter(format string, w io.Writer) (Writer, error) {
+	switch format {
+	case FormatNDJSON:
+		buf := bufio.NewWriter(w)
+		return &ndjsonWriter{buf: buf, enc: json.NewEncoder(buf)}, nil
+	case FormatColumnar:
+		return &columnarWriter{w: w}, nil
+	default:
+		return nil, fmt.Errorf("unknown dump format %q", format)
+	}
+}
+
+// NewReader returns a Reader of dumps in a format.
+func NewReader(format string, r io.Reader) (Reader, error) {
+	switch format {
+	case FormatNDJSON:
+		return &ndjsonReader{dec: json.NewDecoder(r)}, nil
+	case FormatColumnar:
+		return &columnarReader{r: r}, nil
+	default:
+		return nil, fmt.Errorf("unknown dump format %q", format)
+	}
+}
+
+// record is a video as dumped.
+type record struct {
+	ID                   uint       `json:"id"`
+	VideoId              string     `json:"video_id"`
+	Title                string     `json:"title"`
+	Description          string     `json:"description"`
+	PublishedAt          time.Time  `json:"published_at"`
+	ThumbnailUrl         string     `json:"thumbnail_url"`
+	ChannelId            string     `json:"channel_id"`
+	ChannelTitle         string     `json:"channel_title"`
+	LiveBroadcastContent string     `json:"live_broadcast_content"`
+	DurationSeconds      int64      `json:"duration_seconds"`
+	ViewCount            int64      `json:"view_count"`
+	LikeCount            int64      `json:"like_count"`
+	CommentCount         int64      `json:"comment_count"`
+	DefaultLanguage      string     `json:"default_language"`
+	CategoryId           string     `json:"category_id"`
+	FirstSeenAt          time.Time  `json:"first_seen_at"`
+	UpdatedAt            time.Time  `json:"updated_at"`
+	RemovedAt            *time.Time `json:"removed_at"`
+	RemovalStatus        string     `json:"removal_status"`
+}
+
+func recordOf(v *yt.Video) record {
+	r := record{
+		ID:                   v.ID,
+		VideoId:              v.VideoId,
+		Title:                v.Title,
+		Description:          v.Description,
+		PublishedAt:          v.PublishedAt,
+		ThumbnailUrl:         v.ThumbnailUrl,
+		ChannelId:            v.ChannelId,
+		ChannelTitle:         v.ChannelTitle,
+		LiveBroadcastContent: v.LiveBroadcastContent,
+		DurationSeconds:      v.DurationSeconds,
+		ViewCount:            v.ViewCount,
+		LikeCount:            v.LikeCount,
+		CommentCount:         v.CommentCount,
+		DefaultLanguage:      v.DefaultLanguage,
+		CategoryId:           v.CategoryId,
+		FirstSeenAt:          v.CreatedAt,
+		UpdatedAt:            v.UpdatedAt,
+		RemovalStatus:        v.RemovalStatus,
+	}
+	if v.DeletedAt.Valid {
+		removedAt := v.DeletedAt.Time
+		r.RemovedAt = &removedAt
+	}
+	return r
+}
+
+func (r *record) video() yt.Video {
+	v := yt.Video{
+		ID:                   r.ID,
+		VideoId:              r.VideoId,
+		Title:                r.Title,
+		Description:          r.Description,
+		PublishedAt:          r.PublishedAt,
+		ThumbnailUrl:         r.ThumbnailUrl,
+		ChannelId:            r.ChannelId,
+		ChannelTitle:         r.ChannelTitle,
+		LiveBroadcastContent: r.LiveBroadcastContent,
+		DurationSeconds:      r.DurationSeconds,
+		ViewCount:            r.ViewCount,
+		LikeCount:            r.LikeCount,
+		CommentCount:         r.CommentCount,
+		DefaultLanguage:      r.DefaultLanguage,
+		CategoryId:           r.CategoryId,
+		CreatedAt:            r.FirstSeenAt,
+		UpdatedAt:            r.UpdatedAt,
+		RemovalStatus:        r.RemovalStatus,
+	}
+	if r.RemovedAt != nil {
+		v.DeletedAt = gorm.DeletedAt{Time: *r.RemovedAt, Valid: true}
+	}
+	return v
+}
+
+type ndjsonWriter struct {
+	buf *bufio.Writer
+	enc *json.Encoder
+}
+
+func (w *ndjsonWriter) Write(v *yt.Video) error {
+	return w.enc.Encode(recordOf(v))
+}
+
+func (w *ndjsonWriter) Flush() error {
+	return w.buf.Flush()
+}
+
+type ndjsonReader struct {
+	dec *json.Decoder
+}
+
+func (r *ndjsonReader) Read() (yt.Video, error) {
+	var rec record
+	if err := r.dec.Decode(&rec); err != nil {
+		return yt.Video{}, err
+	}
+	return rec.video(), nil
+}
+
+// rowGroup is a row group of a columnar file, holding the values of each column of records
+// by the JSON name of its field.
+type rowGroup struct {
+	Rows    int                        `json:"rows"`
+	Columns map[string]json.RawMessage `json:"columns"`
+}
+
+// column converts a column of records to and from its values.
+type column struct {
+	name   string
+	values func(rows []record) interface{}
+	set    func(rows []record, values json.RawMessage) error
+}
+
+func newColumn[T any](name string, field func(r *record) *T) column {
+	return column{
+		name: name,
+		values: func(rows []record) interface{} {
+			values := make([]T, len(rows))
+			for i := range rows {
+				values[i] = *field(&rows[i])
+			}
+			return values
+		},
+		set: func(rows []record, raw json.RawMessage) error {
+			var values []T
+			if err := json.Unmarshal(raw, &values); err != nil {
+				return fmt.Errorf("column %s: %w", name, err)
+			}
+			if len(values) != len(rows) {
+				return fmt.Errorf("column %s: %d values for %d rows", name, len(values), len(rows))
+			}
+			for i := range rows {
+				*field(&rows[i]) = values[i]
+			}
+			return nil
+		},
+	}
+}
+
+// columns are the columns of columnar files. Readers ignore columns they do not know of, and
+// leave fields of columns missing from a file zero-valued.
+var columns = []column{
+	newColumn("id", func(r *record) *uint { return &r.ID }),
+	newColumn("video_id", func(r *record) *string { return &r.VideoId }),
+	newColumn("title", func(r *record) *string { return &r.Title }),
+	newColumn("description", func(r *record) *string { return &r.Description }),
+	newColumn("published_at", func(r *record) *time.Time { return &r.PublishedAt }),
+	newColumn("thumbnail_url", func(r *record) *string { return &r.ThumbnailUrl }),
+	newColumn("channel_id", func(r *record) *string { return &r.ChannelId }),
+	newColumn("channel_title", func(r *record) *string { return &r.ChannelTitle }),
+	newColumn("live_broadcast_content",
+		func(r *record) *string { return &r.LiveBroadcastContent }),
+	newColumn("duration_seconds", func(r *record) *int64 { return &r.DurationSeconds }),
+	newColumn("view_count", func(r *record) *int64 { return &r.ViewCount }),
+	newColumn("like_count", func(r *record) *int64 { return &r.LikeCount }),
+	newColumn("comment_count", func(r *record) *int64 { return &r.CommentCount }),
+	newColumn("default_language", func(r *record) *string { return &r.DefaultLanguage }),
+	newColumn("category_id", func(r *record) *string { return &r.CategoryId }),
+	newColumn("first_seen_at", func(r *record) *time.Time { return &r.FirstSeenAt }),
+	newColumn("updated_at", func(r *record) *time.Time { return &r.UpdatedAt }),
+	newColumn("removed_at", func(r *record) **time.Time { return &r.RemovedAt }),
+	newColumn("removal_status", func(r *record) *string { return &r.RemovalStatus }),
+}
+
+// columnarWriter writes each row group as a gzip member of its own, so that files can be
+// appended to, and are read back as a single gzip stream.
+type columnarWriter struct {
+	w    io.Writer
+	rows []record
+}
+
+func (w *columnarWriter) Write(v *yt.Video) error {
+	w.rows = append(w.rows, recordOf(v))
+	if len(w.rows) >= RowGroupSize {
+		return w.Flush()
+	}
+	return nil
+}
+
+func (w *columnarWriter) Flush() error {
+	if len(w.rows) == 0 {
+		return nil
+	}
+
+	group := rowGroup{Rows: len(w.rows), Columns: make(map[string]json.RawMessage, len(columns))}
+	for _, c := range columns {
+		values, err := json.Marshal(c.values(w.rows))
+		if err != nil {
+			return err
+		}
+		group.Columns[c.name] = values
+	}
+
+	zw := gzip.NewWriter(w.w)
+	if err := json.NewEncoder(zw).Encode(group); err != nil {
+		return err
+	}
+	if err := zw.Close(); err != nil {
+		return err
+	}
+	w.rows = w.rows[:0]
+	return nil
+}
+
+type columnarReader struct {
+	r   io.Reader
+	dec *json.Decoder
+	// rows is what is left to read of the current row group.
+	rows []record
+}
+
+func (r *columnarReader) Read() (yt.Video, error) {
+	for len(r.rows) == 0 {
+		if err := r.next(); err != nil {
+			return yt.Video{}, err
+		}
+	}
+	v := r.rows[0].video()
+	r.rows = r.rows[1:]
+	return v, nil
+}
+
+// next reads the next row group.
+func (r *columnarReader) next() error {
+	if r.dec == nil {
+		zr, err := gzip.NewReader(r.r)
+		if err != nil {
+			return err
+		}
+		r.dec = json.NewDecoder(zr)
+	}
+
+	var group rowGroup
+	if err := r.dec.Decode(&group); err != nil {
+		return err
+	}
+	r.rows = make([]record, group.Rows)
+	for _, c := range columns {
+		if raw, ok := group.Columns[c.name]; ok {
+			if err := c.set(r.rows, raw); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
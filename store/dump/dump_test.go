@@ -0,0 +1,385 @@
+package dump_test
+
+import (
+	"errors"
+	"fmt"
+	"github.com/ditsuke/youtube-focus/config"
+	"github.com/ditsuke/youtube-focus/internal/yt"
+	"github.com/ditsuke/youtube-focus/store"
+	"github.com/ditsuke/youtube-focus/store/dump"
+	"github.com/ditsuke/youtube-focus/store/migrations"
+	"gorm.io/gorm"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+var formats = []string{dump.FormatNDJSON, dump.FormatColumnar}
+
+// errInterrupted interrupts dumps and loads.
+var errInterrupted = errors.New("interrupted")
+
+// epoch is when the latest of the videos made by videos was published.
+var epoch = time.Date(2022, time.September, 1, 12, 0, 0, 0, time.UTC)
+
+// videos returns n videos, video0 to video<n-1>, published a minute apart from epoch, latest
+// first, with every dumped field set. Every third is removed.
+func videos(n int) []yt.Video {
+	videos := make([]yt.Video, n)
+	for i := range videos {
+		videos[i] = yt.Video{
+			ID:                   uint(i + 1),
+			CreatedAt:            epoch.Add(time.Hour),
+			UpdatedAt:            epoch.Add(2 * time.Hour),
+			VideoId:              fmt.Sprintf("video%d", i),
+			Title:                fmt.Sprintf("Video %d", i),
+			Description:          fmt.Sprintf("The video numbered %d", i),
+			PublishedAt:          epoch.Add(-time.Duration(i) * time.Minute),
+			ThumbnailUrl:         fmt.Sprintf("https://i.ytimg.com/vi/video%d/default.jpg", i),
+			ChannelId:            "channel",
+			ChannelTitle:         "Channel",
+			LiveBroadcastContent: yt.BroadcastNone,
+			DurationSeconds:      int64(60 * i),
+			ViewCount:            int64(100 * i),
+			LikeCount:            int64(10 * i),
+			CommentCount:         int64(i),
+			DefaultLanguage:      "en",
+			CategoryId:           "20",
+		}
+		if i%3 == 2 {
+			videos[i].DeletedAt = gorm.DeletedAt{Time: epoch.Add(3 * time.Hour), Valid: true}
+			videos[i].RemovalStatus = yt.RemovalDeleted
+		}
+	}
+	return videos
+}
+
+// newSQLStore returns a store over an empty in-memory SQLite database.
+func newSQLStore(t *testing.T) *store.VideoMetaStore {
+	t.Helper()
+	db, err := config.Config{DBDriver: config.DriverSQLite, SQLitePath: ":memory:"}.GetDB()
+	if err != nil {
+		t.Fatalf("open the database: %v", err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatalf("open the database: %v", err)
+	}
+	t.Cleanup(func() { _ = sqlDB.Close() })
+	if _, err := migrations.Up(db); err != nil {
+		t.Fatalf("migrate the database: %v", err)
+	}
+	return &store.VideoMetaStore{DB: db}
+}
+
+// newMemoryStore returns a store holding videos.
+func newMemoryStore(t *testing.T, videos []yt.Video) *store.MemoryStore {
+	t.Helper()
+	m := store.NewMemoryStore()
+	if _, err := m.Import(videos); err != nil {
+		t.Fatalf("Import: %v", err)
+	}
+	return m
+}
+
+// readAll reads every video of the dump at path.
+func readAll(t *testing.T, format, path string) []yt.Video {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("open the dump: %v", err)
+	}
+	defer f.Close()
+	r, err := dump.NewReader(format, f)
+	if err != nil {
+		t.Fatalf("NewReader: %v", err)
+	}
+	var read []yt.Video
+	for {
+		v, err := r.Read()
+		if err == io.EOF {
+			return read
+		}
+		if err != nil {
+			t.Fatalf("Read after %d videos: %v", len(read), err)
+		}
+		read = append(read, v)
+	}
+}
+
+// storedIDs returns the video IDs of every video in a store, removed ones included, in the
+// order they were stored.
+func storedIDs(t *testing.T, videos store.VideoStore) []string {
+	t.Helper()
+	var ids []string
+	err := videos.WithFilter(store.Filter{Removed: store.RemovedInclude}).
+		Walk(func(v *yt.Video) error {
+			ids = append(ids, v.VideoId)
+			return nil
+		})
+	if err != nil {
+		t.Fatalf("Walk: %v", err)
+	}
+	return ids
+}
+
+func videoIDs(videos []yt.Video) []string {
+	ids := make([]string, len(videos))
+	for i, v := range videos {
+		ids[i] = v.VideoId
+	}
+	return ids
+}
+
+func TestRoundTrip(t *testing.T) {
+	for _, format := range formats {
+		path := filepath.Join(t.TempDir(), "videos")
+		f, err := os.Create(path)
+		if err != nil {
+			t.Fatalf("create the dump: %v", err)
+		}
+		w, err := dump.NewWriter(format, f)
+		if err != nil {
+			t.Fatalf("NewWriter(%s): %v", format, err)
+		}
+		want := videos(dump.RowGroupSize + 5)
+		for i := range want {
+			if err := w.Write(&want[i]); err != nil {
+				t.Fatalf("Write: %v", err)
+			}
+			// Early flushes end row groups of columnar dumps
+			if i == 2 {
+				if err := w.Flush(); err != nil {
+					t.Fatalf("Flush: %v", err)
+				}
+			}
+		}
+		if err := w.Flush(); err != nil {
+			t.Fatalf("Flush: %v", err)
+		}
+		f.Close()
+
+		if got := readAll(t, format, path); !reflect.DeepEqual(got, want) {
+			t.Errorf("%s dump read back %d videos differing from the %d written",
+				format, len(got), len(want))
+		}
+	}
+}
+
+func TestUnknownFormat(t *testing.T) {
+	if _, err := dump.NewWriter("parquet", io.Discard); err == nil {
+		t.Errorf("NewWriter(parquet) succeeded")
+	}
+	if _, err := dump.NewReader("parquet", nil); err == nil {
+		t.Errorf("NewReader(parquet) succeeded")
+	}
+}
+
+func TestOpenTruncatesToOffset(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "videos")
+	if err := os.WriteFile(path, []byte("checkpointed, not"), 0o644); err != nil {
+		t.Fatalf("write the dump: %v", err)
+	}
+
+	f, err := dump.Open(path, int64(len("checkpointed")))
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	if _, err := f.WriteString(", resumed"); err != nil {
+		t.Fatalf("write to the dump: %v", err)
+	}
+	f.Close()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read the dump: %v", err)
+	}
+	if got, want := string(data), "checkpointed, resumed"; got != want {
+		t.Errorf("dump = %q, want %q", got, want)
+	}
+}
+
+func TestResume(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "videos.checkpoint")
+	cp, err := dump.Resume(path, dump.FormatColumnar)
+	if err != nil || cp != (dump.Checkpoint{Format: dump.FormatColumnar}) {
+		t.Errorf("Resume without a checkpoint = %+v, %v, want a fresh columnar one", cp, err)
+	}
+
+	saved := dump.Checkpoint{Format: dump.FormatColumnar, Records: 10, LastID: 12, Offset: 345}
+	if err := saved.Save(path); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	if cp, err = dump.Resume(path, dump.FormatColumnar); err != nil || cp != saved {
+		t.Errorf("Resume = %+v, %v, want %+v", cp, err, saved)
+	}
+	if _, err = dump.Resume(path, dump.FormatNDJSON); err == nil {
+		t.Errorf("Resume of a columnar checkpoint as ndjson succeeded")
+	}
+}
+
+func TestDumpResumesFromCheckpoint(t *testing.T) {
+	for _, format := range formats {
+		want := videos(2*dump.RowGroupSize + 5)
+		src := newMemoryStore(t, want)
+		path := filepath.Join(t.TempDir(), "videos")
+
+		// Interrupted past the first checkpoint, and the partial row group written since
+		out, err := dump.Open(path, 0)
+		if err != nil {
+			t.Fatalf("Open: %v", err)
+		}
+		var saved dump.Checkpoint
+		cp := dump.Checkpoint{Format: format}
+		err = dump.Dump(src, out, &cp, func(cp dump.Checkpoint) error {
+			if saved.Records > 0 {
+				return errInterrupted
+			}
+			saved = cp
+			return nil
+		})
+		if !errors.Is(err, errInterrupted) {
+			t.Fatalf("Dump = %v, want it interrupted", err)
+		}
+		if _, err := out.WriteString("half a row group"); err != nil {
+			t.Fatalf("write to the dump: %v", err)
+		}
+		out.Close()
+		if saved.Records != dump.RowGroupSize || saved.LastID != want[saved.Records-1].ID {
+			t.Fatalf("checkpoint = %+v, want it after the first row group", saved)
+		}
+
+		out, err = dump.Open(path, saved.Offset)
+		if err != nil {
+			t.Fatalf("Open: %v", err)
+		}
+		cp = saved
+		err = dump.Dump(src, out, &cp, func(dump.Checkpoint) error { return nil })
+		out.Close()
+		if err != nil {
+			t.Fatalf("resumed Dump: %v", err)
+		}
+		if cp.Records != int64(len(want)) {
+			t.Errorf("resumed Dump recorded %d videos, want %d", cp.Records, len(want))
+		}
+
+		// Removed videos included, each once
+		got := readAll(t, format, path)
+		if !reflect.DeepEqual(videoIDs(got), videoIDs(want)) {
+			t.Errorf("resumed %s dump holds %d videos, want the %d stored once each",
+				format, len(got), len(want))
+		}
+	}
+}
+
+// dumpFile dumps videos to a file, returning its path.
+func dumpFile(t *testing.T, format string, videos []yt.Video) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "videos")
+	out, err := dump.Open(path, 0)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer out.Close()
+	cp := dump.Checkpoint{Format: format}
+	src := newMemoryStore(t, videos)
+	if err := dump.Dump(src, out, &cp, func(dump.Checkpoint) error { return nil }); err != nil {
+		t.Fatalf("Dump: %v", err)
+	}
+	return path
+}
+
+// load loads the dump at path into a store, returning the number of videos stored.
+func load(t *testing.T, dst store.ManagedVideoStore, format, path string, cp *dump.Checkpoint,
+	checkpoint func(dump.Checkpoint, int64) error,
+) (int64, error) {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("open the dump: %v", err)
+	}
+	defer f.Close()
+	r, err := dump.NewReader(format, f)
+	if err != nil {
+		t.Fatalf("NewReader: %v", err)
+	}
+	return dump.Load(dst, r, 2, cp, checkpoint)
+}
+
+func TestLoadResumesFromCheckpoint(t *testing.T) {
+	for _, format := range formats {
+		t.Run(format, func(t *testing.T) {
+			want := videos(7)
+			path := dumpFile(t, format, want)
+			dst := newSQLStore(t)
+
+			// Interrupted after loading a second batch, before checkpointing it
+			var saved dump.Checkpoint
+			cp := dump.Checkpoint{Format: format}
+			_, err := load(t, dst, format, path, &cp, func(cp dump.Checkpoint, _ int64) error {
+				if saved.Records > 0 {
+					return errInterrupted
+				}
+				saved = cp
+				return nil
+			})
+			if !errors.Is(err, errInterrupted) {
+				t.Fatalf("Load = %v, want it interrupted", err)
+			}
+			if saved.Records != 2 {
+				t.Fatalf("checkpoint = %+v, want it after the first batch", saved)
+			}
+
+			// The batch loaded since the checkpoint is skipped as stored
+			cp = saved
+			stored, err := load(t, dst, format, path, &cp,
+				func(dump.Checkpoint, int64) error { return nil })
+			if err != nil {
+				t.Fatalf("resumed Load: %v", err)
+			}
+			if stored != 3 || cp.Records != 7 {
+				t.Errorf("resumed Load stored %d, and recorded %d, want 3 and 7",
+					stored, cp.Records)
+			}
+			if got := storedIDs(t, dst); !reflect.DeepEqual(got, videoIDs(want)) {
+				t.Errorf("loaded %v, want %v", got, videoIDs(want))
+			}
+
+			var video yt.Video
+			err = dst.DB.Unscoped().Where("video_id = ?", "video2").Take(&video).Error
+			if err != nil {
+				t.Fatalf("read a removed video: %v", err)
+			}
+			if !video.DeletedAt.Valid || video.RemovalStatus != yt.RemovalDeleted ||
+				!video.CreatedAt.Equal(want[2].CreatedAt) {
+				t.Errorf("loaded removed video = %+v, want it as dumped", video)
+			}
+		})
+	}
+}
+
+func TestLoadIsIdempotent(t *testing.T) {
+	want := videos(5)
+	path := dumpFile(t, dump.FormatNDJSON, want)
+	dst := newSQLStore(t)
+	noCheckpoint := func(dump.Checkpoint, int64) error { return nil }
+
+	cp := dump.Checkpoint{Format: dump.FormatNDJSON}
+	stored, err := load(t, dst, dump.FormatNDJSON, path, &cp, noCheckpoint)
+	if err != nil || stored != 5 {
+		t.Fatalf("Load = %d, %v, want 5 stored", stored, err)
+	}
+
+	cp = dump.Checkpoint{Format: dump.FormatNDJSON}
+	stored, err = load(t, dst, dump.FormatNDJSON, path, &cp, noCheckpoint)
+	if err != nil || stored != 0 || cp.Records != 5 {
+		t.Errorf("Load again = %d, %v, having read %d, want 0 stored of 5",
+			stored, err, cp.Records)
+	}
+	if got := storedIDs(t, dst); !reflect.DeepEqual(got, videoIDs(want)) {
+		t.Errorf("loaded %v, want %v", got, videoIDs(want))
+	}
+}
@@ -0,0 +1,138 @@
+package dump
+
+import (
+	"fmt"
+	"github.com/ditsuke/youtube-focus/internal/yt"
+	"github.com/ditsuke/youtube-focus/store"
+	"io"
+	"os"
+)
+
+// Resume reads the checkpoint saved at path to resume a dump or load in a format from,
+// failing if it was of another format. The checkpoint is zero-valued if there is none.
+func Resume(path, format string) (Checkpoint, error) {
+	cp, err := ReadCheckpoint(path)
+	if err != nil {
+		return cp, err
+	}
+	if cp.Format == "" {
+		cp.Format = format
+	}
+	if cp.Format != format {
+		return cp, fmt.Errorf("cannot resume %s as %s", cp.Format, format)
+	}
+	return cp, nil
+}
+
+// Open opens the dump at path to write to from offset, the size of the dump as of its last
+// checkpoint. Whatever was written past offset was not checkpointed, and is truncated.
+func Open(path string, offset int64) (*os.File, error) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	if err := f.Truncate(offset); err != nil {
+		f.Close()
+		return nil, err
+	}
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return f, nil
+}
+
+// Dump writes the videos of a store after cp.LastID to out in cp.Format, removed videos
+// included, in order of ID. out holds the cp.Offset bytes dumped before. Every RowGroupSize
+// videos, and once done, the videos written so far are flushed out, and cp is updated and
+// passed to checkpoint.
+func Dump(videos store.VideoStore, out io.Writer, cp *Checkpoint,
+	checkpoint func(Checkpoint) error,
+) error {
+	counter := &countingWriter{w: out, n: cp.Offset}
+	w, err := NewWriter(cp.Format, counter)
+	if err != nil {
+		return err
+	}
+
+	flush := func() error {
+		if err := w.Flush(); err != nil {
+			return err
+		}
+		cp.Offset = counter.n
+		return checkpoint(*cp)
+	}
+
+	videos = videos.WithFilter(store.Filter{Removed: store.RemovedInclude})
+	err = videos.WalkAfter(cp.LastID, func(v *yt.Video) error {
+		if err := w.Write(v); err != nil {
+			return err
+		}
+		cp.Records++
+		cp.LastID = v.ID
+		if cp.Records%RowGroupSize == 0 {
+			return flush()
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return flush()
+}
+
+// Load imports the videos read from a dump into a store, in batches of batchSize, skipping
+// the cp.Records videos loaded before. Videos already stored are skipped, by their video IDs.
+// After each batch, cp is updated and passed to checkpoint along with the number of videos
+// stored so far, which is returned once done.
+func Load(videos store.ManagedVideoStore, r Reader, batchSize int, cp *Checkpoint,
+	checkpoint func(cp Checkpoint, stored int64) error,
+) (int64, error) {
+	for i := int64(0); i < cp.Records; i++ {
+		if _, err := r.Read(); err != nil {
+			return 0, fmt.Errorf("skipping %d loaded videos: %w", cp.Records, err)
+		}
+	}
+
+	var stored int64
+	batch := make([]yt.Video, 0, batchSize)
+	load := func() error {
+		n, err := videos.Import(batch)
+		if err != nil {
+			return err
+		}
+		stored += n
+		cp.Records += int64(len(batch))
+		batch = batch[:0]
+		return checkpoint(*cp, stored)
+	}
+
+	for {
+		v, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return stored, fmt.Errorf("reading video %d: %w", cp.Records+int64(len(batch))+1, err)
+		}
+		batch = append(batch, v)
+		if len(batch) == batchSize {
+			if err := load(); err != nil {
+				return stored, err
+			}
+		}
+	}
+	return stored, load()
+}
+
+// countingWriter counts the bytes written through it.
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,108 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"github.com/glebarez/go-sqlite"
+	"github.com/jackc/pgconn"
+	"gorm.io/gorm"
+	"net"
+	"strings"
+)
+
+// The kinds of errors the stores return, along with the errors they stem from. Test for them
+// with errors.Is.
+var (
+	// ErrNotFound is returned when there is no such record.
+	ErrNotFound = errors.New("not found")
+	// ErrInvalidQuery is returned for queries that cannot be run, eg: searches for no words.
+	ErrInvalidQuery = errors.New("invalid query")
+	// ErrUnavailable is returned when the database cannot be reached, or is too busy to
+	// answer. Trying again later may succeed.
+	ErrUnavailable = errors.New("store unavailable")
+)
+
+// Error is an error of one of the kinds above. It wraps the error it stems from, so that
+// errors.Is matches both, eg: ErrNotFound and gorm.ErrRecordNotFound.
+type Error struct {
+	Kind error
+	Err  error
+}
+
+func (e *Error) Error() string {
+	return e.Kind.Error() + ": " + e.Err.Error()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+func (e *Error) Is(target error) bool {
+	return target == e.Kind
+}
+
+// wrapErr classifies an error of the database by its kind, if it is of one. Other errors are
+// returned as they are.
+func wrapErr(err error) error {
+	var storeErr *Error
+	if err == nil || errors.As(err, &storeErr) {
+		return err
+	}
+	if kind := errKind(err); kind != nil {
+		return &Error{Kind: kind, Err: err}
+	}
+	return err
+}
+
+// IsUnavailable reports whether an error of the database means it could not be reached, or
+// was too busy to answer, as ErrUnavailable does for errors of the stores.
+func IsUnavailable(err error) bool {
+	return errors.Is(wrapErr(err), ErrUnavailable)
+}
+
+func errKind(err error) error {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrNotFound
+	}
+
+	// Connections that failed, or timed out
+	var netErr net.Error
+	if errors.Is(err, driver.ErrBadConn) || errors.Is(err, sql.ErrConnDone) ||
+		errors.Is(err, context.DeadlineExceeded) || errors.As(err, &netErr) ||
+		pgconn.Timeout(err) {
+		return ErrUnavailable
+	}
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		switch {
+		// Connection exceptions, insufficient resources, and operator intervention such as
+		// shutdowns
+		case strings.HasPrefix(pgErr.Code, "08"), strings.HasPrefix(pgErr.Code, "53"),
+			strings.HasPrefix(pgErr.Code, "57P"):
+			return ErrUnavailable
+		// Data exceptions, and syntax errors such as those of malformed tsqueries
+		case strings.HasPrefix(pgErr.Code, "22"), pgErr.Code == "42601":
+			return ErrInvalidQuery
+		}
+	}
+
+	var sqliteErr *sqlite.Error
+	if errors.As(err, &sqliteErr) {
+		// The primary result code is the low byte of extended ones
+		switch sqliteErr.Code() & 0xff {
+		case sqliteBusy, sqliteLocked, sqliteCantOpen:
+			return ErrUnavailable
+		}
+	}
+	return nil
+}
+
+// SQLite result codes, see https://www.sqlite.org/rescode.html
+const (
+	sqliteBusy     = 5
+	sqliteLocked   = 6
+	sqliteCantOpen = 14
+)
@@ -0,0 +1,158 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+	"github.com/ditsuke/youtube-focus/internal/yt"
+	"gorm.io/gorm"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Facet is a dimension by which search results can be counted.
+type Facet string
+
+const (
+	FacetChannel  Facet = "channel"
+	FacetDay      Facet = "day"
+	FacetCategory Facet = "category"
+	FacetDuration Facet = "duration"
+	// FacetWatch counts videos by the saved searches that match them.
+	FacetWatch Facet = "watch"
+)
+
+// FacetCount is the number of videos sharing a value of some Facet.
+type FacetCount struct {
+	Value string `json:"value"`
+	// Label is a human-readable name for the value, where one is available.
+	Label string `json:"label,omitempty"`
+	Count int64  `json:"count"`
+}
+
+// facetExpr holds the SQL to group videos by a facet.
+type facetExpr struct {
+	value string
+	label string
+}
+
+// Duration buckets follow YouTube's own: short is under 4 minutes, long is over 20.
+const durationBucketExpr = `CASE
+	WHEN duration_seconds = 0 THEN 'unknown'
+	WHEN duration_seconds < 240 THEN 'short'
+	WHEN duration_seconds <= 1200 THEN 'medium'
+	ELSE 'long' END`
+
+var facetExprs = map[Facet]facetExpr{
+	FacetChannel: {value: "channel_id", label: "MAX(channel_title)"},
+	// The day is the backend's own expression
+	FacetDay:      {},
+	FacetCategory: {value: "category_id"},
+	FacetDuration: {value: durationBucketExpr},
+	// Watches are counted one saved search at a time
+	FacetWatch: {},
+}
+
+// IsValid reports whether f is a supported facet.
+func (f Facet) IsValid() bool {
+	_, ok := facetExprs[f]
+	return ok
+}
+
+// NaturalSearchFacets counts the videos matched by a NaturalSearch for query by each of the
+// passed facets. A maximum of limit values are counted per facet, most frequent first.
+func (v *VideoMetaStore) NaturalSearchFacets(query string, facets []Facet,
+	limit int,
+) (map[Facet][]FacetCount, error) {
+	counts := make(map[Facet][]FacetCount, len(facets))
+	for _, f := range facets {
+		q, err := v.naturalSearchQuery(query)
+		if err != nil {
+			return nil, err
+		}
+		var c []FacetCount
+		if f == FacetWatch {
+			c, err = v.countWatches(query, limit)
+		} else {
+			c, err = countFacet(q, v.backend(), f, limit)
+		}
+		if err != nil {
+			return nil, wrapErr(err)
+		}
+		counts[f] = c
+	}
+
+	return counts, nil
+}
+
+// countFacet groups the videos matched by tx by the facet f and counts them.
+func countFacet(tx *gorm.DB, b backend, f Facet, limit int) ([]FacetCount, error) {
+	expr, ok := facetExprs[f]
+	if !ok {
+		return nil, fmt.Errorf("unknown facet %q", f)
+	}
+	if f == FacetDay {
+		expr.value = b.dayExpr()
+	}
+
+	sel := expr.value + " AS value, COUNT(*) AS count"
+	if expr.label != "" {
+		sel += ", " + expr.label + " AS label"
+	}
+
+	counts := make([]FacetCount, 0, limit)
+	err := tx.
+		Model(&yt.Video{}).
+		Select(sel).
+		Group("value").
+		Order("count DESC, value").
+		Limit(limit).
+		Scan(&counts).Error
+
+	return counts, err
+}
+
+// countWatches counts the videos matched by a NaturalSearch for query by the saved searches
+// that match them, regardless of when they were first seen. Values are the IDs of the saved
+// searches, labelled with their names. Saved searches that cannot be run match none.
+func (v *VideoMetaStore) countWatches(query string, limit int) ([]FacetCount, error) {
+	var searches []SavedSearch
+	if err := v.DB.Order("id").Find(&searches).Error; err != nil {
+		return nil, err
+	}
+
+	b := v.backend()
+	words := strings.Fields(query)
+	counts := make([]FacetCount, 0, limit)
+	for _, search := range searches {
+		var count int64
+		err := v.withMatching(search, func(tx, matches *gorm.DB) error {
+			return tx.Model(&yt.Video{}).
+				Scopes(v.filter.scope, b.naturalMatch(words)).
+				Where("id IN (?)", matches.Select("id")).
+				Count(&count).Error
+		})
+		if errors.Is(err, ErrInvalidQuery) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if count > 0 {
+			counts = append(counts, FacetCount{
+				Value: strconv.FormatUint(uint64(search.ID), 10),
+				Label: search.Name,
+				Count: count,
+			})
+		}
+	}
+
+	// As countFacet orders them, but by ID
+	sort.SliceStable(counts, func(i, j int) bool {
+		return counts[i].Count > counts[j].Count
+	})
+	if len(counts) > limit {
+		counts = counts[:limit]
+	}
+	return counts, nil
+}
@@ -0,0 +1,159 @@
+package store
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"github.com/ditsuke/youtube-focus/internal/yt"
+	"gorm.io/gorm"
+	"strings"
+	"time"
+)
+
+// Values of Filter.Removed.
+const (
+	// RemovedExclude leaves out videos removed from YouTube, as filters do by default.
+	RemovedExclude = "exclude"
+	// RemovedInclude matches removed videos along with the rest.
+	RemovedInclude = "include"
+	// RemovedOnly matches removed videos alone.
+	RemovedOnly = "only"
+)
+
+// Filter narrows down the videos matched by VideoMetaStore and MemoryStore queries.
+// Zero-valued fields do not filter.
+type Filter struct {
+	PublishedAfter  time.Time `json:"published_after,omitempty"`
+	PublishedBefore time.Time `json:"published_before,omitempty"`
+
+	// FirstSeenAfter and FirstSeenBefore filter by the time videos were first stored.
+	FirstSeenAfter  time.Time `json:"first_seen_after,omitempty"`
+	FirstSeenBefore time.Time `json:"first_seen_before,omitempty"`
+
+	ChannelId string `json:"channel_id,omitempty"`
+
+	MinDuration time.Duration `json:"min_duration,omitempty"`
+	MaxDuration time.Duration `json:"max_duration,omitempty"`
+
+	MinViews int64 `json:"min_views,omitempty"`
+	MaxViews int64 `json:"max_views,omitempty"`
+
+	// LiveBroadcastContent is one of the yt.Broadcast* values.
+	LiveBroadcastContent string `json:"live_broadcast_content,omitempty"`
+
+	// Language matches a language tag along with its regional variants, eg: "en" also
+	// matches "en-US".
+	Language   string `json:"language,omitempty"`
+	CategoryId string `json:"category_id,omitempty"`
+
+	// Removed is one of the Removed* values, and RemovedExclude if empty.
+	Removed string `json:"removed,omitempty"`
+}
+
+// Value stores the filter as JSON, eg: in the filter column of saved searches. Filters are
+// stored this way rather than with gorm's JSON serializer, which does not serialize zero
+// values.
+func (f Filter) Value() (driver.Value, error) {
+	data, err := json.Marshal(f)
+	return string(data), err
+}
+
+// Scan reads a filter stored by Value.
+func (f *Filter) Scan(src interface{}) error {
+	switch src := src.(type) {
+	case nil:
+		*f = Filter{}
+		return nil
+	case string:
+		return json.Unmarshal([]byte(src), f)
+	case []byte:
+		return json.Unmarshal(src, f)
+	default:
+		return fmt.Errorf("cannot scan %T into a filter", src)
+	}
+}
+
+// scope applies the filter to a query. Times are compared in UTC, which they are stored in,
+// as SQLite compares them as text.
+func (f Filter) scope(db *gorm.DB) *gorm.DB {
+	if !f.PublishedAfter.IsZero() {
+		db = db.Where("published_at > ?", f.PublishedAfter.UTC())
+	}
+	if !f.PublishedBefore.IsZero() {
+		db = db.Where("published_at < ?", f.PublishedBefore.UTC())
+	}
+	if !f.FirstSeenAfter.IsZero() {
+		db = db.Where("created_at > ?", f.FirstSeenAfter.UTC())
+	}
+	if !f.FirstSeenBefore.IsZero() {
+		db = db.Where("created_at <= ?", f.FirstSeenBefore.UTC())
+	}
+	if f.ChannelId != "" {
+		db = db.Where("channel_id = ?", f.ChannelId)
+	}
+	if f.MinDuration > 0 {
+		db = db.Where("duration_seconds >= ?", int64(f.MinDuration.Seconds()))
+	}
+	if f.MaxDuration > 0 {
+		db = db.Where("duration_seconds <= ?", int64(f.MaxDuration.Seconds()))
+	}
+	if f.MinViews > 0 {
+		db = db.Where("view_count >= ?", f.MinViews)
+	}
+	if f.MaxViews > 0 {
+		db = db.Where("view_count <= ?", f.MaxViews)
+	}
+	if f.LiveBroadcastContent != "" {
+		db = db.Where("live_broadcast_content = ?", f.LiveBroadcastContent)
+	}
+	if f.Language != "" {
+		db = db.Where("default_language = ? OR default_language LIKE ?",
+			f.Language, f.Language+"-%")
+	}
+	if f.CategoryId != "" {
+		db = db.Where("category_id = ?", f.CategoryId)
+	}
+	switch f.Removed {
+	case RemovedInclude:
+		db = db.Unscoped()
+	case RemovedOnly:
+		db = db.Unscoped().Where("deleted_at IS NOT NULL")
+	}
+	return db
+}
+
+// Match reports whether a video passes the filter, as scope does for queries.
+func (f Filter) Match(v yt.Video) bool {
+	switch {
+	case !f.PublishedAfter.IsZero() && !v.PublishedAt.After(f.PublishedAfter):
+		return false
+	case !f.PublishedBefore.IsZero() && !v.PublishedAt.Before(f.PublishedBefore):
+		return false
+	case !f.FirstSeenAfter.IsZero() && !v.CreatedAt.After(f.FirstSeenAfter):
+		return false
+	case !f.FirstSeenBefore.IsZero() && v.CreatedAt.After(f.FirstSeenBefore):
+		return false
+	case f.ChannelId != "" && v.ChannelId != f.ChannelId:
+		return false
+	case f.MinDuration > 0 && v.DurationSeconds < int64(f.MinDuration.Seconds()):
+		return false
+	case f.MaxDuration > 0 && v.DurationSeconds > int64(f.MaxDuration.Seconds()):
+		return false
+	case f.MinViews > 0 && v.ViewCount < f.MinViews:
+		return false
+	case f.MaxViews > 0 && v.ViewCount > f.MaxViews:
+		return false
+	case f.LiveBroadcastContent != "" && v.LiveBroadcastContent != f.LiveBroadcastContent:
+		return false
+	case f.Language != "" && v.DefaultLanguage != f.Language &&
+		!strings.HasPrefix(v.DefaultLanguage, f.Language+"-"):
+		return false
+	case f.CategoryId != "" && v.CategoryId != f.CategoryId:
+		return false
+	case f.Removed == RemovedOnly && !v.DeletedAt.Valid:
+		return false
+	case f.Removed != RemovedInclude && f.Removed != RemovedOnly && v.DeletedAt.Valid:
+		return false
+	}
+	return true
+}
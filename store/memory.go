@@ -0,0 +1,796 @@
+package store
+
+import (
+	"fmt"
+	"github.com/ditsuke/youtube-focus/internal/yt"
+	"github.com/rs/zerolog"
+	"gorm.io/gorm"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+)
+
+// MemoryStore is a video store kept in memory, with the ordering, pagination and search
+// semantics of VideoMetaStore. It is meant for tests, and deployments that need not keep
+// videos, which do without a database.
+//
+// Searches approximate those of the databases: natural-language searches match the words of
+// titles as they are, without stemming, and fuzzy searches match videos containing every word
+// of the query, as on SQLite. Saved searches are those listed by Searches, and none match
+// videos without it.
+type MemoryStore struct {
+	Logger zerolog.Logger
+
+	// Searches, if set, lists the saved searches matched against videos, eg: for their
+	// retention and the watch facet.
+	Searches SavedSearchLister
+
+	// OnEdit, if set, is called with the stored videos whose content a Save updated.
+	OnEdit func(edited []yt.Video)
+
+	// data is shared by filtered copies of the store.
+	data   *memoryData
+	filter Filter
+}
+
+// SavedSearchLister lists saved searches, as SavedSearchStore does.
+type SavedSearchLister interface {
+	List() ([]SavedSearch, error)
+}
+
+// memoryData is the content of a MemoryStore.
+type memoryData struct {
+	mu sync.RWMutex
+
+	// videos are in the order they were stored, and so by ID, as are the archived ones.
+	videos    []yt.Video
+	archived  []yt.Video
+	revisions map[string][]VideoRevision
+	stats     map[string][]VideoStats
+
+	lastID       uint
+	lastRecordID uint
+}
+
+// interface compliance constraint for MemoryStore
+var _ ManagedVideoStore = &MemoryStore{}
+
+// NewMemoryStore returns an empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		data: &memoryData{
+			revisions: map[string][]VideoRevision{},
+			stats:     map[string][]VideoStats{},
+		},
+	}
+}
+
+// WithFilter returns a copy of the store whose queries only match videos passing the filter.
+func (m *MemoryStore) WithFilter(filter Filter) VideoStore {
+	filtered := *m
+	filtered.filter = filter
+	return &filtered
+}
+
+// Save records to the store, returning those that were not already stored, with their IDs and
+// the time they were first seen stamped. Stored videos whose title, description or thumbnail
+// changed are updated, their prior version kept as a VideoRevision, and passed to OnEdit, and
+// so are their statistics, kept as VideoStats, as are those of new videos.
+func (m *MemoryStore) Save(records []yt.Video) ([]yt.Video, error) {
+	return m.save(records, true), nil
+}
+
+// Refresh updates the stored videos among records as Save does, but stores none that are not
+// already.
+func (m *MemoryStore) Refresh(records []yt.Video) error {
+	m.save(records, false)
+	return nil
+}
+
+// save saves records, inserting those new to the store if insert is set.
+func (m *MemoryStore) save(records []yt.Video, insert bool) []yt.Video {
+	if len(records) == 0 {
+		return nil
+	}
+
+	d := m.data
+	d.mu.Lock()
+	stored := d.byVideoID()
+	now := time.Now().UTC()
+	var saved, edited []yt.Video
+	isNew := map[string]bool{}
+	for _, record := range records {
+		if prev, ok := stored[record.VideoId]; ok {
+			if prev.DeletedAt.Valid {
+				continue
+			}
+			if hasNewStatistics(*prev, record) {
+				prev.ViewCount = record.ViewCount
+				prev.LikeCount = record.LikeCount
+				prev.CommentCount = record.CommentCount
+				d.addStats(statsOf(record, now))
+			}
+			if isEdited(*prev, record) {
+				d.lastRecordID++
+				revision := revisionOf(*prev, now)
+				revision.ID = d.lastRecordID
+				d.revisions[prev.VideoId] = append(d.revisions[prev.VideoId], revision)
+
+				prev.Title = record.Title
+				prev.Description = record.Description
+				prev.ThumbnailUrl = record.ThumbnailUrl
+				prev.UpdatedAt = now
+				edited = append(edited, *prev)
+			}
+			continue
+		}
+		// Batches may repeat a video
+		if !insert || isNew[record.VideoId] {
+			continue
+		}
+		isNew[record.VideoId] = true
+
+		d.lastID++
+		record.ID = d.lastID
+		record.CreatedAt, record.UpdatedAt = now, now
+		record.PublishedAt = record.PublishedAt.UTC()
+		if hasNewStatistics(yt.Video{}, record) {
+			d.addStats(statsOf(record, now))
+		}
+		saved = append(saved, record)
+	}
+	d.videos = append(d.videos, saved...)
+	d.mu.Unlock()
+
+	// OnEdit may use the store
+	if len(edited) > 0 && m.OnEdit != nil {
+		m.OnEdit(edited)
+	}
+	return saved
+}
+
+// Import stores videos as they are, such as those of another store, along with the times they
+// were first seen, edited and removed, and returns the number stored. Videos already stored
+// are skipped, so imports can safely be repeated. IDs are assigned anew.
+func (m *MemoryStore) Import(videos []yt.Video) (int64, error) {
+	d := m.data
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	stored := d.byVideoID()
+	var imported int64
+	for _, video := range videos {
+		if _, ok := stored[video.VideoId]; ok {
+			continue
+		}
+		d.lastID++
+		video.ID = d.lastID
+		video.PublishedAt = video.PublishedAt.UTC()
+		video.CreatedAt = video.CreatedAt.UTC()
+		video.UpdatedAt = video.UpdatedAt.UTC()
+		video.DeletedAt.Time = video.DeletedAt.Time.UTC()
+		d.videos = append(d.videos, video)
+		stored[video.VideoId] = &d.videos[len(d.videos)-1]
+		imported++
+	}
+	return imported, nil
+}
+
+// byVideoID indexes the stored videos by their YouTube IDs. The caller must hold the lock, and
+// the index is only good until videos are next added.
+func (d *memoryData) byVideoID() map[string]*yt.Video {
+	stored := make(map[string]*yt.Video, len(d.videos))
+	for i := range d.videos {
+		stored[d.videos[i].VideoId] = &d.videos[i]
+	}
+	return stored
+}
+
+// AvailableIDs returns the YouTube IDs of up to limit videos not known to be removed from
+// YouTube, stored after the video of ID after, along with the ID of the last of them. Passing
+// that ID back in pages through the store, which starts over from an ID of zero.
+func (m *MemoryStore) AvailableIDs(after uint, limit int) ([]string, uint, error) {
+	d := m.data
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	ids := []string{}
+	var last uint
+	for _, v := range d.videos {
+		if len(ids) == limit {
+			break
+		}
+		if v.ID > after && !v.DeletedAt.Valid {
+			ids = append(ids, v.VideoId)
+			last = v.ID
+		}
+	}
+	return ids, last, nil
+}
+
+// MarkRemoved records videos as removed from YouTube, mapping their YouTube IDs to one of the
+// yt.Removal* statuses, and returns the number of videos marked. Removed videos are
+// soft-deleted at the time they are marked, and those already removed are left as they are.
+func (m *MemoryStore) MarkRemoved(statuses map[string]string) (int64, error) {
+	d := m.data
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	now := time.Now().UTC()
+	var marked int64
+	for i := range d.videos {
+		v := &d.videos[i]
+		status, ok := statuses[v.VideoId]
+		if !ok || v.DeletedAt.Valid {
+			continue
+		}
+		v.RemovalStatus = status
+		v.DeletedAt = gorm.DeletedAt{Time: now, Valid: true}
+		marked++
+	}
+	return marked, nil
+}
+
+// Prune removes the videos kept past their retention under a policy, as VideoMetaStore.Prune
+// does, in a single batch. Archived videos are kept in memory, with their revisions and
+// statistics, but are no longer served.
+func (m *MemoryStore) Prune(policy RetentionPolicy, _ int, dryRun bool) (PruneReport, error) {
+	report := PruneReport{DryRun: dryRun, Archived: policy.Archive}
+	matchers, err := m.searchMatchers()
+	if err != nil {
+		return report, err
+	}
+
+	d := m.data
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	now := time.Now().UTC()
+	kept := d.videos[:0:0]
+	for _, v := range d.videos {
+		r, matched := time.Duration(0), false
+		for _, sm := range matchers {
+			if !sm.match(v) {
+				continue
+			}
+			s := policy.retention(&sm.search)
+			switch {
+			case !matched:
+				r = s
+			case r == 0 || s == 0:
+				r = 0
+			case s > r:
+				r = s
+			}
+			matched = true
+		}
+		if !matched {
+			r = policy.retention(nil)
+		}
+		if r == 0 || !v.PublishedAt.Before(now.Add(-r)) {
+			kept = append(kept, v)
+			continue
+		}
+
+		report.Videos++
+		if report.Oldest.IsZero() || v.PublishedAt.Before(report.Oldest) {
+			report.Oldest = v.PublishedAt
+		}
+		if v.PublishedAt.After(report.Newest) {
+			report.Newest = v.PublishedAt
+		}
+		if dryRun {
+			continue
+		}
+		if policy.Archive {
+			d.archived = append(d.archived, v)
+		} else {
+			delete(d.revisions, v.VideoId)
+			delete(d.stats, v.VideoId)
+		}
+	}
+	if !dryRun {
+		d.videos = kept
+	}
+	return report, nil
+}
+
+// addStats records the statistics of a video. The caller must hold the lock.
+func (d *memoryData) addStats(s VideoStats) {
+	d.lastRecordID++
+	s.ID = d.lastRecordID
+	d.stats[s.VideoId] = append(d.stats[s.VideoId], s)
+}
+
+// Retrieve is like VideoMetaStore.Retrieve: it retrieves a maximum of limit videos published
+// before publishedBefore, latest first.
+func (m *MemoryStore) Retrieve(publishedBefore time.Time, limit int) ([]yt.Video, error) {
+	return m.find(limit, func(v yt.Video) bool {
+		return v.PublishedAt.Before(publishedBefore)
+	}), nil
+}
+
+// Search is like VideoMetaStore.Search: it retrieves a maximum of limit videos published
+// before or at publishedBefore whose title or description contain query, ignoring case,
+// latest first.
+func (m *MemoryStore) Search(query string, publishedBefore time.Time, limit int,
+) ([]yt.Video, error) {
+	return m.find(limit, func(v yt.Video) bool {
+		return !v.PublishedAt.After(publishedBefore) && containsText(v, query)
+	}), nil
+}
+
+// NaturalSearch is like VideoMetaStore.NaturalSearch: it retrieves a maximum of limit videos
+// with any of the words of query in their titles, latest first. Returns ErrInvalidQuery if the
+// query has no words.
+func (m *MemoryStore) NaturalSearch(query string, limit int) ([]yt.Video, error) {
+	match, err := naturalMatcher(query)
+	if err != nil {
+		return nil, err
+	}
+	return m.find(limit, match), nil
+}
+
+// NaturalSearchFacets is like VideoMetaStore.NaturalSearchFacets: it counts the videos matched
+// by a NaturalSearch for query by each of the passed facets, a maximum of limit values per
+// facet, most frequent first.
+func (m *MemoryStore) NaturalSearchFacets(query string, facets []Facet, limit int,
+) (map[Facet][]FacetCount, error) {
+	match, err := naturalMatcher(query)
+	if err != nil {
+		return nil, err
+	}
+	videos := m.find(-1, match)
+
+	counts := make(map[Facet][]FacetCount, len(facets))
+	for _, f := range facets {
+		switch {
+		case !f.IsValid():
+			return nil, fmt.Errorf("unknown facet %q", f)
+		case f == FacetWatch:
+			if counts[f], err = m.countWatches(videos, limit); err != nil {
+				return nil, err
+			}
+		default:
+			counts[f] = countFacetOf(videos, f, limit)
+		}
+	}
+	return counts, nil
+}
+
+// countWatches counts videos by the saved searches that match them, as
+// VideoMetaStore.countWatches does.
+func (m *MemoryStore) countWatches(videos []yt.Video, limit int) ([]FacetCount, error) {
+	matchers, err := m.searchMatchers()
+	if err != nil {
+		return nil, err
+	}
+
+	counts := make([]FacetCount, 0, limit)
+	for _, sm := range matchers {
+		var count int64
+		for _, v := range videos {
+			if sm.match(v) {
+				count++
+			}
+		}
+		if count > 0 {
+			counts = append(counts, FacetCount{
+				Value: strconv.FormatUint(uint64(sm.search.ID), 10),
+				Label: sm.search.Name,
+				Count: count,
+			})
+		}
+	}
+
+	sort.SliceStable(counts, func(i, j int) bool {
+		return counts[i].Count > counts[j].Count
+	})
+	if len(counts) > limit {
+		counts = counts[:limit]
+	}
+	return counts, nil
+}
+
+// countFacetOf groups videos by the facet f, other than FacetWatch, and counts them, as
+// countFacet does.
+func countFacetOf(videos []yt.Video, f Facet, limit int) []FacetCount {
+	counts := make([]FacetCount, 0, limit)
+
+	index := map[string]int{}
+	for _, v := range videos {
+		var value, label string
+		switch f {
+		case FacetChannel:
+			value, label = v.ChannelId, v.ChannelTitle
+		case FacetDay:
+			value = v.PublishedAt.UTC().Format("2006-01-02")
+		case FacetCategory:
+			value = v.CategoryId
+		case FacetDuration:
+			value = durationBucket(v.DurationSeconds)
+		}
+
+		i, ok := index[value]
+		if !ok {
+			i = len(counts)
+			index[value] = i
+			counts = append(counts, FacetCount{Value: value})
+		}
+		counts[i].Count++
+		// As MAX(channel_title)
+		if label > counts[i].Label {
+			counts[i].Label = label
+		}
+	}
+
+	sort.Slice(counts, func(i, j int) bool {
+		if counts[i].Count != counts[j].Count {
+			return counts[i].Count > counts[j].Count
+		}
+		return counts[i].Value < counts[j].Value
+	})
+	if len(counts) > limit {
+		counts = counts[:limit]
+	}
+	return counts
+}
+
+// durationBucket is the bucket of durationBucketExpr a video of some duration falls in.
+func durationBucket(seconds int64) string {
+	switch {
+	case seconds == 0:
+		return "unknown"
+	case seconds < 240:
+		return "short"
+	case seconds <= 1200:
+		return "medium"
+	default:
+		return "long"
+	}
+}
+
+// FuzzySearch is like VideoMetaStore.FuzzySearch on SQLite: it retrieves a maximum of limit
+// videos published before or at publishedBefore whose title or description contain every word
+// of query, ignoring case, latest first.
+func (m *MemoryStore) FuzzySearch(query string, publishedBefore time.Time, limit int,
+) ([]yt.Video, error) {
+	match := fuzzyMatcher(query)
+	return m.find(limit, func(v yt.Video) bool {
+		return !v.PublishedAt.After(publishedBefore) && match(v)
+	}), nil
+}
+
+// fuzzyMatcher returns the match of videos whose title or description contain every word of
+// a query, ignoring case.
+func fuzzyMatcher(query string) func(yt.Video) bool {
+	words := strings.Fields(query)
+	return func(v yt.Video) bool {
+		for _, w := range words {
+			if !containsText(v, w) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// Suggest is like VideoMetaStore.Suggest: it returns a maximum of limit distinct titles with
+// words starting as any of the words of query, those with the most such words first.
+func (m *MemoryStore) Suggest(query string, limit int) ([]string, error) {
+	titles := make([]string, 0, limit)
+	prefixes := titleWords(query)
+	if len(prefixes) == 0 {
+		return titles, nil
+	}
+
+	matches := map[string]int{}
+	for _, v := range m.find(-1, func(yt.Video) bool { return true }) {
+		if _, ok := matches[v.Title]; ok {
+			continue
+		}
+		n := 0
+		for _, w := range titleWords(v.Title) {
+			for _, p := range prefixes {
+				if strings.HasPrefix(w, p) {
+					n++
+					break
+				}
+			}
+		}
+		matches[v.Title] = n
+		if n > 0 {
+			titles = append(titles, v.Title)
+		}
+	}
+
+	// Ties are left latest first
+	sort.SliceStable(titles, func(i, j int) bool {
+		return matches[titles[i]] > matches[titles[j]]
+	})
+	if len(titles) > limit {
+		titles = titles[:limit]
+	}
+	return titles, nil
+}
+
+// SavedSearchResults is like VideoMetaStore.SavedSearchResults: it runs a saved search for
+// the videos first seen between its last two checks, published before publishedBefore,
+// latest first.
+func (m *MemoryStore) SavedSearchResults(search SavedSearch, publishedBefore time.Time,
+	limit int,
+) ([]yt.Video, error) {
+	return savedSearchResults(m, search, publishedBefore, limit)
+}
+
+// Get retrieves a video by its YouTube ID, even if it was removed from YouTube. Returns
+// ErrNotFound if there is no such video.
+func (m *MemoryStore) Get(videoID string) (yt.Video, error) {
+	d := m.data
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	for _, v := range d.videos {
+		if v.VideoId == videoID {
+			return v, nil
+		}
+	}
+	return yt.Video{}, wrapErr(gorm.ErrRecordNotFound)
+}
+
+// Lookup videos by their YouTube IDs, in no particular order, including those removed from
+// YouTube. IDs of videos not in the store are ignored.
+func (m *MemoryStore) Lookup(videoIDs []string) ([]yt.Video, error) {
+	d := m.data
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	wanted := make(map[string]bool, len(videoIDs))
+	for _, id := range videoIDs {
+		wanted[id] = true
+	}
+	videos := []yt.Video{}
+	for _, v := range d.videos {
+		if wanted[v.VideoId] {
+			videos = append(videos, v)
+		}
+	}
+	return videos, nil
+}
+
+// Revisions returns the prior versions of a video, by its YouTube ID, oldest first. Videos
+// that were never edited have none.
+func (m *MemoryStore) Revisions(videoID string) ([]VideoRevision, error) {
+	d := m.data
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	return append([]VideoRevision{}, d.revisions[videoID]...), nil
+}
+
+// StatsHistory returns the statistics recorded for videos, by their YouTube IDs, oldest first.
+// Videos with none recorded are left out.
+func (m *MemoryStore) StatsHistory(videoIDs []string) (map[string][]VideoStats, error) {
+	d := m.data
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	history := make(map[string][]VideoStats, len(videoIDs))
+	for _, id := range videoIDs {
+		if stats := d.stats[id]; len(stats) > 0 {
+			history[id] = append([]VideoStats(nil), stats...)
+		}
+	}
+	return history, nil
+}
+
+// MatchingSearches returns the saved searches listed by Searches that match videos, by their
+// IDs in the store, regardless of when the videos were first seen. Videos matching none are
+// left out, as are saved searches that cannot be run.
+func (m *MemoryStore) MatchingSearches(ids []uint) (map[uint][]SavedSearch, error) {
+	matchers, err := m.searchMatchers()
+	if err != nil {
+		return nil, err
+	}
+	wanted := make(map[uint]bool, len(ids))
+	for _, id := range ids {
+		wanted[id] = true
+	}
+
+	d := m.data
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	matches := make(map[uint][]SavedSearch, len(ids))
+	for _, sm := range matchers {
+		for _, v := range d.videos {
+			if wanted[v.ID] && sm.match(v) {
+				matches[v.ID] = append(matches[v.ID], sm.search)
+			}
+		}
+	}
+	return matches, nil
+}
+
+// searchMatcher matches the videos of a saved search.
+type searchMatcher struct {
+	search SavedSearch
+	match  func(yt.Video) bool
+}
+
+// searchMatchers returns the matchers of the saved searches listed by Searches, which match
+// videos regardless of when they were first seen, or whether they were removed from YouTube.
+// Searches that cannot be run are logged and left out.
+func (m *MemoryStore) searchMatchers() ([]searchMatcher, error) {
+	if m.Searches == nil {
+		return nil, nil
+	}
+	searches, err := m.Searches.List()
+	if err != nil {
+		return nil, err
+	}
+
+	matchers := make([]searchMatcher, 0, len(searches))
+	for _, search := range searches {
+		f := search.Filter
+		f.Removed = RemovedInclude
+
+		var match func(yt.Video) bool
+		switch search.Mode {
+		case ModeNatural:
+			match, err = naturalMatcher(search.Query)
+			if err != nil {
+				m.Logger.Warn().Err(err).Uint("saved_search", search.ID).
+					Msg("skipping saved search")
+				continue
+			}
+		case ModeFuzzy:
+			match = fuzzyMatcher(search.Query)
+		default:
+			query := search.Query
+			match = func(v yt.Video) bool { return containsText(v, query) }
+		}
+		matchers = append(matchers, searchMatcher{
+			search: search,
+			match:  func(v yt.Video) bool { return f.Match(v) && match(v) },
+		})
+	}
+	return matchers, nil
+}
+
+// Channels looks up channels by their IDs, in no particular order, titled as in their latest
+// videos. IDs of channels without videos in the store are ignored.
+func (m *MemoryStore) Channels(channelIDs []string) ([]Channel, error) {
+	wanted := make(map[string]bool, len(channelIDs))
+	for _, id := range channelIDs {
+		wanted[id] = true
+	}
+
+	channels := make([]Channel, 0, len(channelIDs))
+	seen := map[string]bool{}
+	// Regardless of the store's filter, as VideoMetaStore.Channels
+	latest := (&MemoryStore{data: m.data}).find(-1, func(v yt.Video) bool {
+		return wanted[v.ChannelId]
+	})
+	for _, v := range latest {
+		if !seen[v.ChannelId] {
+			seen[v.ChannelId] = true
+			channels = append(channels, Channel{ID: v.ChannelId, Title: v.ChannelTitle})
+		}
+	}
+	return channels, nil
+}
+
+// ChannelVideos retrieves a maximum of limit videos published before some time.Time for each
+// of a number of channels. Videos are keyed by their channel ID, and sorted by latest first.
+func (m *MemoryStore) ChannelVideos(channelIDs []string, publishedBefore time.Time,
+	limit int,
+) (map[string][]yt.Video, error) {
+	wanted := make(map[string]bool, len(channelIDs))
+	for _, id := range channelIDs {
+		wanted[id] = true
+	}
+
+	byChannel := make(map[string][]yt.Video, len(channelIDs))
+	videos := m.find(-1, func(v yt.Video) bool {
+		return wanted[v.ChannelId] && v.PublishedAt.Before(publishedBefore)
+	})
+	for _, v := range videos {
+		if len(byChannel[v.ChannelId]) < limit {
+			byChannel[v.ChannelId] = append(byChannel[v.ChannelId], v)
+		}
+	}
+	return byChannel, nil
+}
+
+// Walk calls fn with each video in the store, in the order they were stored. Walking stops at
+// the first error returned by fn.
+func (m *MemoryStore) Walk(fn func(*yt.Video) error) error {
+	return m.WalkAfter(0, fn)
+}
+
+// WalkAfter is like Walk, but starts after the video of ID id, eg: to resume a walk that
+// stopped there.
+func (m *MemoryStore) WalkAfter(id uint, fn func(*yt.Video) error) error {
+	d := m.data
+	d.mu.RLock()
+	var videos []yt.Video
+	for _, v := range d.videos {
+		if v.ID > id && m.filter.Match(v) {
+			videos = append(videos, v)
+		}
+	}
+	// fn may use the store
+	d.mu.RUnlock()
+
+	for i := range videos {
+		if err := fn(&videos[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// find returns a maximum of limit videos passing the store's filter and match, latest first.
+// A negative limit returns all of them.
+func (m *MemoryStore) find(limit int, match func(yt.Video) bool) []yt.Video {
+	d := m.data
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	videos := []yt.Video{}
+	for _, v := range d.videos {
+		if m.filter.Match(v) && match(v) {
+			videos = append(videos, v)
+		}
+	}
+	// Ties are broken by ID, latest first, which databases leave unspecified
+	sort.Slice(videos, func(i, j int) bool {
+		if !videos[i].PublishedAt.Equal(videos[j].PublishedAt) {
+			return videos[i].PublishedAt.After(videos[j].PublishedAt)
+		}
+		return videos[i].ID > videos[j].ID
+	})
+	if limit >= 0 && len(videos) > limit {
+		videos = videos[:limit]
+	}
+	return videos
+}
+
+// containsText reports whether the title or description of a video contain text, ignoring
+// case.
+func containsText(v yt.Video, text string) bool {
+	text = strings.ToLower(text)
+	return strings.Contains(strings.ToLower(v.Title), text) ||
+		strings.Contains(strings.ToLower(v.Description), text)
+}
+
+// naturalMatcher returns the match of videos with any of the words of a natural-language query
+// in their titles, failing with ErrInvalidQuery if it has no words.
+func naturalMatcher(query string) (func(yt.Video) bool, error) {
+	if len(strings.Fields(query)) == 0 {
+		return nil, fmt.Errorf("%w: no words to search for", ErrInvalidQuery)
+	}
+	words := map[string]bool{}
+	for _, w := range titleWords(query) {
+		words[w] = true
+	}
+	return func(v yt.Video) bool {
+		for _, w := range titleWords(v.Title) {
+			if words[w] {
+				return true
+			}
+		}
+		return false
+	}, nil
+}
+
+// titleWords splits text into its words, in lower case, as the full-text indexes of titles do.
+func titleWords(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
@@ -0,0 +1,121 @@
+package store_test
+
+import (
+	"github.com/ditsuke/youtube-focus/internal/yt"
+	"github.com/ditsuke/youtube-focus/store"
+	"github.com/ditsuke/youtube-focus/store/storetest"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestMemoryStore(t *testing.T) {
+	storetest.Run(t, func(t *testing.T) storetest.Store {
+		return store.NewMemoryStore()
+	})
+}
+
+// newMemoryStoreWithSearches returns a MemoryStore matching the saved searches of a database,
+// and the store of those searches.
+func newMemoryStoreWithSearches(t *testing.T) (*store.MemoryStore, *store.SavedSearchStore) {
+	t.Helper()
+	searches := &store.SavedSearchStore{DB: newTestDB(t)}
+	m := store.NewMemoryStore()
+	m.Searches = searches
+	return m, searches
+}
+
+func createSearches(t *testing.T, searches *store.SavedSearchStore, saved ...store.SavedSearch) {
+	t.Helper()
+	for i := range saved {
+		if err := searches.Create(&saved[i]); err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+	}
+}
+
+func TestMemoryStorePruneSavedSearchRetention(t *testing.T) {
+	m, searches := newMemoryStoreWithSearches(t)
+	saveAged(t, m, map[string]int{"cat old": 40, "dog old": 40, "bird recent": 10})
+	createSearches(t, searches,
+		store.SavedSearch{Name: "cats", Query: "cat", Mode: store.ModeLike, RetentionDays: 60},
+		store.SavedSearch{Name: "birds", Query: "bird", Mode: store.ModeFuzzy, RetentionDays: 5},
+		// Cannot be run, and so matches nothing
+		store.SavedSearch{Name: "blank", Query: " ", Mode: store.ModeNatural, RetentionDays: 90},
+	)
+
+	report, err := m.Prune(store.RetentionPolicy{Days: 30, Archive: true}, 10, false)
+	if err != nil || report.Videos != 2 || !report.Archived {
+		t.Fatalf("Prune = %+v, %v, want 2 videos archived", report, err)
+	}
+	var ids []string
+	err = m.Walk(func(v *yt.Video) error {
+		ids = append(ids, v.VideoId)
+		return nil
+	})
+	if err != nil || !reflect.DeepEqual(ids, []string{"cat old"}) {
+		t.Errorf("videos after Prune = %v, %v, want [cat old]", ids, err)
+	}
+}
+
+func TestMemoryStoreMatchingSearches(t *testing.T) {
+	m, searches := newMemoryStoreWithSearches(t)
+	saved, err := m.Save([]yt.Video{
+		{VideoId: "a", Title: "Cats and dogs"},
+		{VideoId: "b", Title: "Dogs"},
+		{VideoId: "c", Title: "Birds"},
+	})
+	if err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	createSearches(t, searches,
+		store.SavedSearch{Name: "cats", Query: "cat", Mode: store.ModeLike},
+		store.SavedSearch{Name: "dogs", Query: "dogs", Mode: store.ModeNatural},
+	)
+
+	matches, err := m.MatchingSearches([]uint{saved[0].ID, saved[1].ID, saved[2].ID})
+	if err != nil {
+		t.Fatalf("MatchingSearches: %v", err)
+	}
+	got := map[string][]string{}
+	for i, v := range saved {
+		for _, s := range matches[v.ID] {
+			got[saved[i].VideoId] = append(got[saved[i].VideoId], s.Name)
+		}
+	}
+	want := map[string][]string{"a": {"cats", "dogs"}, "b": {"dogs"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("MatchingSearches = %v, want %v", got, want)
+	}
+
+	facets, err := m.NaturalSearchFacets("dogs", []store.Facet{store.FacetWatch}, 10)
+	if err != nil {
+		t.Fatalf("NaturalSearchFacets: %v", err)
+	}
+	watches := facets[store.FacetWatch]
+	sort.Slice(watches, func(i, j int) bool { return watches[i].Label < watches[j].Label })
+	if len(watches) != 2 || watches[0].Label != "cats" || watches[0].Count != 1 ||
+		watches[1].Label != "dogs" || watches[1].Count != 2 {
+		t.Errorf("watch facet = %+v, want cats counting 1 and dogs 2", watches)
+	}
+}
+
+func TestMemoryStoreOnEdit(t *testing.T) {
+	m := store.NewMemoryStore()
+	var edited []string
+	m.OnEdit = func(videos []yt.Video) {
+		for _, v := range videos {
+			edited = append(edited, v.VideoId)
+		}
+	}
+	if _, err := m.Save([]yt.Video{{VideoId: "a", Title: "A"}, {VideoId: "b"}}); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	err := m.Refresh([]yt.Video{{VideoId: "a", Title: "A, retitled"}, {VideoId: "b"}})
+	if err != nil {
+		t.Fatalf("Refresh: %v", err)
+	}
+	if !reflect.DeepEqual(edited, []string{"a"}) {
+		t.Errorf("OnEdit called with %v, want [a]", edited)
+	}
+}
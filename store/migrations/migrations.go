@@ -0,0 +1,265 @@
+// Package migrations versions the database schema. Each migration is a pair of SQL files,
+// <version>_<name>.up.sql and <version>_<name>.down.sql, applying and reverting it, in the
+// directory of the database it is written for: postgres or sqlite. Applied migrations are
+// tracked in the schema_migrations table.
+//
+// The first migrations create the tables as they were once created by gorm's AutoMigrate, if
+// they do not exist, so that databases prepared that way are adopted. Migration 0010 then
+// adds any columns adopted tables lack on postgres; SQLite cannot add columns only if they
+// are missing, so it fails on tables lacking any instead.
+//
+// Migrations are never edited once released, as databases that applied them would not pick
+// up the edit: changes to the schema are new migrations.
+package migrations
+
+import (
+	"embed"
+	"fmt"
+	"gorm.io/gorm"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// lockID identifies the advisory lock held while migrating a postgres database, so that
+// concurrent migrators take turns. SQLite serializes writers on its own.
+const lockID = 7_210_482_301
+
+// createSchemaMigrations creates the schema_migrations table, given the type of timestamps.
+const createSchemaMigrations = `CREATE TABLE IF NOT EXISTS schema_migrations (
+    version    bigint PRIMARY KEY,
+    name       text NOT NULL,
+    applied_at %s NOT NULL
+)`
+
+//go:embed postgres/*.sql sqlite/*.sql
+var files embed.FS
+
+// Migration is a versioned change to the schema.
+type Migration struct {
+	Version int
+	Name    string
+
+	up, down string
+}
+
+func (m Migration) String() string {
+	return fmt.Sprintf("%04d_%s", m.Version, m.Name)
+}
+
+// SchemaMigration records an applied migration.
+type SchemaMigration struct {
+	Version   int `gorm:"primaryKey"`
+	Name      string
+	AppliedAt time.Time
+}
+
+// Status is a migration along with when it was applied, if it was.
+type Status struct {
+	Migration
+	AppliedAt *time.Time
+}
+
+// All returns every migration for the database db, in order of version.
+func All(db *gorm.DB) ([]Migration, error) {
+	dir := db.Dialector.Name()
+	entries, err := fs.ReadDir(files, dir)
+	if err != nil {
+		return nil, fmt.Errorf("no migrations for %s databases", dir)
+	}
+
+	byVersion := map[int]*Migration{}
+	for _, e := range entries {
+		base, direction, ok := cutSuffixes(e.Name(), ".up.sql", ".down.sql")
+		if !ok {
+			return nil, fmt.Errorf("migration %s: not an .up.sql or .down.sql file", e.Name())
+		}
+		v, name, ok := strings.Cut(base, "_")
+		version, err := strconv.Atoi(v)
+		if !ok || err != nil || version <= 0 {
+			return nil, fmt.Errorf("migration %s: no version", e.Name())
+		}
+
+		sql, err := files.ReadFile(path.Join(dir, e.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: name}
+			byVersion[version] = m
+		}
+		if m.Name != name {
+			return nil, fmt.Errorf("migration %04d: named both %s and %s", version, m.Name, name)
+		}
+		if direction == ".up.sql" {
+			m.up = string(sql)
+		} else {
+			m.down = string(sql)
+		}
+	}
+
+	all := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.up == "" || m.down == "" {
+			return nil, fmt.Errorf("migration %s: missing its up or down", m)
+		}
+		all = append(all, *m)
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].Version < all[j].Version })
+	return all, nil
+}
+
+// List returns the status of every migration, in order of version.
+func List(db *gorm.DB) ([]Status, error) {
+	all, err := All(db)
+	if err != nil {
+		return nil, err
+	}
+	applied, err := appliedMigrations(db)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]Status, len(all))
+	for i, m := range all {
+		statuses[i].Migration = m
+		if a, ok := applied[m.Version]; ok {
+			appliedAt := a.AppliedAt
+			statuses[i].AppliedAt = &appliedAt
+		}
+	}
+	return statuses, nil
+}
+
+// Pending returns the migrations yet to be applied, in order of version.
+func Pending(db *gorm.DB) ([]Migration, error) {
+	statuses, err := List(db)
+	if err != nil {
+		return nil, err
+	}
+
+	var pending []Migration
+	for _, s := range statuses {
+		if s.AppliedAt == nil {
+			pending = append(pending, s.Migration)
+		}
+	}
+	return pending, nil
+}
+
+// Up applies every pending migration in order, each in its own transaction, returning those
+// applied. It stops at the first migration to fail.
+func Up(db *gorm.DB) ([]Migration, error) {
+	timestamp := "timestamptz"
+	if db.Dialector.Name() == "sqlite" {
+		timestamp = "datetime"
+	}
+	if err := db.Exec(fmt.Sprintf(createSchemaMigrations, timestamp)).Error; err != nil {
+		return nil, err
+	}
+	pending, err := Pending(db)
+	if err != nil {
+		return nil, err
+	}
+
+	var applied []Migration
+	for _, m := range pending {
+		err := migrate(db, m, func(tx *gorm.DB, done bool) error {
+			if done {
+				return nil
+			}
+			if err := tx.Exec(m.up).Error; err != nil {
+				return err
+			}
+			record := SchemaMigration{Version: m.Version, Name: m.Name, AppliedAt: tx.NowFunc()}
+			return tx.Create(&record).Error
+		})
+		if err != nil {
+			return applied, fmt.Errorf("migration %s: %w", m, err)
+		}
+		applied = append(applied, m)
+	}
+	return applied, nil
+}
+
+// Down reverts up to steps of the applied migrations, latest first, each in its own
+// transaction, returning those reverted.
+func Down(db *gorm.DB, steps int) ([]Migration, error) {
+	statuses, err := List(db)
+	if err != nil {
+		return nil, err
+	}
+
+	var reverted []Migration
+	for i := len(statuses) - 1; i >= 0 && len(reverted) < steps; i-- {
+		m := statuses[i].Migration
+		if statuses[i].AppliedAt == nil {
+			continue
+		}
+		err := migrate(db, m, func(tx *gorm.DB, done bool) error {
+			if !done {
+				return nil
+			}
+			if err := tx.Exec(m.down).Error; err != nil {
+				return err
+			}
+			return tx.Delete(&SchemaMigration{}, m.Version).Error
+		})
+		if err != nil {
+			return reverted, fmt.Errorf("migration %s: %w", m, err)
+		}
+		reverted = append(reverted, m)
+	}
+	return reverted, nil
+}
+
+// migrate calls fn in a transaction holding the migration lock, passing whether m is applied
+// as of then, as another migrator may have got to it first.
+func migrate(db *gorm.DB, m Migration, fn func(tx *gorm.DB, done bool) error) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if tx.Dialector.Name() == "postgres" {
+			if err := tx.Exec("SELECT pg_advisory_xact_lock(?)", lockID).Error; err != nil {
+				return err
+			}
+		}
+		var count int64
+		err := tx.Model(&SchemaMigration{}).Where("version = ?", m.Version).Count(&count).Error
+		if err != nil {
+			return err
+		}
+		return fn(tx, count > 0)
+	})
+}
+
+// appliedMigrations returns the applied migrations by version, none if the schema_migrations
+// table has yet to be created.
+func appliedMigrations(db *gorm.DB) (map[int]SchemaMigration, error) {
+	applied := map[int]SchemaMigration{}
+	if !db.Migrator().HasTable(&SchemaMigration{}) {
+		return applied, nil
+	}
+
+	var records []SchemaMigration
+	if err := db.Find(&records).Error; err != nil {
+		return nil, err
+	}
+	for _, r := range records {
+		applied[r.Version] = r
+	}
+	return applied, nil
+}
+
+// cutSuffixes cuts the first of suffixes that s ends with from it, returning which.
+func cutSuffixes(s string, suffixes ...string) (string, string, bool) {
+	for _, suffix := range suffixes {
+		if strings.HasSuffix(s, suffix) {
+			return strings.TrimSuffix(s, suffix), suffix, true
+		}
+	}
+	return s, "", false
+}
@@ -0,0 +1,152 @@
+package migrations_test
+
+import (
+	"github.com/ditsuke/youtube-focus/store/migrations"
+	"gorm.io/gorm"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// tables are the tables the migrations create.
+var tables = []string{
+	"videos", "saved_searches", "api_keys", "audit_entries", "video_revisions",
+	"videos_archive", "video_stats",
+}
+
+// versions returns the versions of ms, in order.
+func versions(ms []migrations.Migration) []int {
+	vs := []int{}
+	for _, m := range ms {
+		vs = append(vs, m.Version)
+	}
+	return vs
+}
+
+func expectTables(t *testing.T, db *gorm.DB, exist bool) {
+	t.Helper()
+	for _, table := range tables {
+		if db.Migrator().HasTable(table) != exist {
+			t.Errorf("table %s exists = %v, want %v", table, !exist, exist)
+		}
+	}
+}
+
+func TestUpDownUp(t *testing.T) {
+	db := newTestDB(t)
+	all, err := migrations.All(db)
+	if err != nil {
+		t.Fatalf("All: %v", err)
+	}
+	reversed := make([]int, len(all))
+	for i, m := range all {
+		if i > 0 && m.Version <= all[i-1].Version {
+			t.Fatalf("All = %v, want them in order of version", all)
+		}
+		reversed[len(all)-1-i] = m.Version
+	}
+
+	applied, err := migrations.Up(db)
+	if err != nil {
+		t.Fatalf("Up: %v", err)
+	}
+	if got, want := versions(applied), versions(all); !reflect.DeepEqual(got, want) {
+		t.Errorf("Up applied %v, want %v", got, want)
+	}
+	expectTables(t, db, true)
+
+	statuses, err := migrations.List(db)
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	for _, s := range statuses {
+		if s.AppliedAt == nil {
+			t.Errorf("List has %s unapplied after Up", s.Migration)
+		}
+	}
+	if applied, err := migrations.Up(db); err != nil || len(applied) != 0 {
+		t.Errorf("Up again = %v, %v, want nothing applied", applied, err)
+	}
+
+	reverted, err := migrations.Down(db, len(all))
+	if err != nil {
+		t.Fatalf("Down: %v", err)
+	}
+	if got := versions(reverted); !reflect.DeepEqual(got, reversed) {
+		t.Errorf("Down reverted %v, want %v", got, reversed)
+	}
+	expectTables(t, db, false)
+	pending, err := migrations.Pending(db)
+	if err != nil {
+		t.Fatalf("Pending: %v", err)
+	}
+	if got, want := versions(pending), versions(all); !reflect.DeepEqual(got, want) {
+		t.Errorf("Pending after Down = %v, want %v", got, want)
+	}
+
+	if applied, err = migrations.Up(db); err != nil {
+		t.Fatalf("Up after Down: %v", err)
+	}
+	if got, want := versions(applied), versions(all); !reflect.DeepEqual(got, want) {
+		t.Errorf("Up after Down applied %v, want %v", got, want)
+	}
+	expectTables(t, db, true)
+}
+
+func TestPendingAfterPartialApply(t *testing.T) {
+	db := newTestDB(t)
+	pending, err := migrations.Pending(db)
+	if err != nil {
+		t.Fatalf("Pending: %v", err)
+	}
+	all := versions(pending)
+	if _, err := migrations.Up(db); err != nil {
+		t.Fatalf("Up: %v", err)
+	}
+
+	if _, err := migrations.Down(db, 3); err != nil {
+		t.Fatalf("Down: %v", err)
+	}
+	pending, err = migrations.Pending(db)
+	if err != nil {
+		t.Fatalf("Pending: %v", err)
+	}
+	if got, want := versions(pending), all[len(all)-3:]; !reflect.DeepEqual(got, want) {
+		t.Errorf("Pending after reverting 3 = %v, want %v", got, want)
+	}
+
+	statuses, err := migrations.List(db)
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	for i, s := range statuses {
+		if applied := s.AppliedAt != nil; applied != (i < len(all)-3) {
+			t.Errorf("List has %s applied = %v", s.Migration, applied)
+		}
+	}
+
+	applied, err := migrations.Up(db)
+	if err != nil {
+		t.Fatalf("Up: %v", err)
+	}
+	if got, want := versions(applied), all[len(all)-3:]; !reflect.DeepEqual(got, want) {
+		t.Errorf("Up applied %v, want %v", got, want)
+	}
+}
+
+func TestUpRefusesIncompleteAdoptedTables(t *testing.T) {
+	db := newTestDB(t)
+	// As if created by AutoMigrate from a model lacking prev_checked_at
+	err := db.Exec(`CREATE TABLE saved_searches (
+		id integer PRIMARY KEY, created_at datetime, updated_at datetime, deleted_at datetime,
+		name text, query text NOT NULL, mode text, filter text, checked_at datetime
+	)`).Error
+	if err != nil {
+		t.Fatalf("create the table: %v", err)
+	}
+
+	_, err = migrations.Up(db)
+	if err == nil || !strings.Contains(err.Error(), "adopt_automigrated_columns") {
+		t.Errorf("Up with a table lacking a column = %v, want the adoption to fail", err)
+	}
+}
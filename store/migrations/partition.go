@@ -0,0 +1,177 @@
+package migrations
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"gorm.io/gorm"
+	"strings"
+)
+
+// partitionedVideos creates the videos table partitioned by publish time, with the columns
+// of the unpartitioned table it replaces.
+const partitionedVideos = `CREATE TABLE videos (
+    LIKE videos_unpartitioned INCLUDING DEFAULTS INCLUDING GENERATED
+) PARTITION BY RANGE (published_at)`
+
+// partitionedVideosKeys are the keys and indexes of the partitioned videos table. Postgres
+// only enforces unique keys that include the partition key, so videos are unique by their
+// (video_id, published_at), and the indexes are those the migrations create on the
+// unpartitioned table.
+var partitionedVideosKeys = []string{
+	`ALTER TABLE videos ADD PRIMARY KEY (id, published_at)`,
+	`ALTER TABLE videos ADD CONSTRAINT videos_video_id_published_at_key
+    UNIQUE (video_id, published_at)`,
+	`CREATE INDEX idx_videos_deleted_at ON videos (deleted_at)`,
+	`CREATE INDEX idx_videos_channel_id ON videos (channel_id)`,
+	`CREATE INDEX ts_idx ON videos USING GIN (tsv)`,
+	`CREATE INDEX title_trgm_idx ON videos USING GIN (title gin_trgm_ops)`,
+	`CREATE INDEX description_trgm_idx ON videos USING GIN (description gin_trgm_ops)`,
+	`CREATE INDEX idx_videos_published_at ON videos (published_at)`,
+}
+
+// Partition converts the videos table of a migrated postgres database into one partitioned
+// by the year videos were published in, with a partition for every year from that of the
+// oldest video through the year through, and a default partition for any later. Videos are
+// then only kept unique by their (video_id, published_at). Once the table is partitioned,
+// Partition only adds the partitions missing through the year through, and so is to be run
+// again before the last year is out: the default partition must hold no videos of a year to
+// add its partition. Returns the partitions added.
+//
+// The conversion copies every video in a single transaction, holding up writes until it is
+// done.
+func Partition(db *gorm.DB, through int) ([]string, error) {
+	if db.Dialector.Name() != "postgres" {
+		return nil, fmt.Errorf("only postgres databases can be partitioned")
+	}
+	pending, err := Pending(db)
+	if err != nil {
+		return nil, err
+	}
+	if len(pending) > 0 {
+		return nil, fmt.Errorf("%d pending migrations, apply them first", len(pending))
+	}
+
+	var added []string
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("SELECT pg_advisory_xact_lock(?)", lockID).Error; err != nil {
+			return err
+		}
+
+		var partitioned bool
+		err := tx.Raw("SELECT EXISTS (SELECT 1 FROM pg_partitioned_table " +
+			"WHERE partrelid = 'videos'::regclass)").Scan(&partitioned).Error
+		if err != nil {
+			return err
+		}
+
+		from := through
+		if !partitioned {
+			if from, err = repartitionVideos(tx, through); err != nil {
+				return err
+			}
+			if err := tx.Exec("CREATE TABLE videos_default PARTITION OF videos DEFAULT").
+				Error; err != nil {
+				return err
+			}
+			added = append(added, "videos_default")
+		}
+
+		for year := from; year <= through; year++ {
+			name := fmt.Sprintf("videos_%d", year)
+			var exists bool
+			if err := tx.Raw("SELECT to_regclass(?) IS NOT NULL", name).Scan(&exists).
+				Error; err != nil {
+				return err
+			}
+			if exists {
+				continue
+			}
+			err := tx.Exec(fmt.Sprintf("CREATE TABLE %s PARTITION OF videos "+
+				"FOR VALUES FROM ('%d-01-01 00:00:00+00') TO ('%d-01-01 00:00:00+00')",
+				name, year, year+1)).Error
+			if err != nil {
+				return fmt.Errorf("partition %s: %w", name, err)
+			}
+			added = append(added, name)
+		}
+
+		if !partitioned {
+			return copyVideos(tx)
+		}
+		return nil
+	})
+	return added, err
+}
+
+// repartitionVideos moves the unpartitioned videos table aside as videos_unpartitioned, and
+// creates the partitioned videos table in its place, returning the year of the oldest video,
+// or through if there are none.
+func repartitionVideos(tx *gorm.DB, through int) (int, error) {
+	var unpublished int64
+	err := tx.Table("videos").Where("published_at IS NULL").Count(&unpublished).Error
+	if err != nil {
+		return 0, err
+	}
+	if unpublished > 0 {
+		return 0, fmt.Errorf("%d videos have no published_at to partition them by", unpublished)
+	}
+
+	var oldest sql.NullInt64
+	err = tx.Raw("SELECT extract(year FROM min(published_at) AT TIME ZONE 'UTC')::bigint " +
+		"FROM videos").Scan(&oldest).Error
+	if err != nil {
+		return 0, err
+	}
+	from := through
+	if oldest.Valid && int(oldest.Int64) < from {
+		from = int(oldest.Int64)
+	}
+
+	// The IDs of videos keep counting up from where they were
+	var sequence sql.NullString
+	if err := tx.Raw("SELECT pg_get_serial_sequence('videos', 'id')").Scan(&sequence).
+		Error; err != nil {
+		return 0, err
+	}
+	if !sequence.Valid {
+		return 0, errors.New("videos.id has no sequence to keep")
+	}
+
+	stmts := []string{
+		"ALTER TABLE videos RENAME TO videos_unpartitioned",
+		partitionedVideos,
+		fmt.Sprintf("ALTER SEQUENCE %s OWNED BY videos.id", sequence.String),
+	}
+	for _, stmt := range stmts {
+		if err := tx.Exec(stmt).Error; err != nil {
+			return 0, err
+		}
+	}
+	return from, nil
+}
+
+// copyVideos copies the videos of videos_unpartitioned into the partitioned videos table,
+// drops it, and only then keys and indexes the videos table, as the names of its keys and
+// indexes are those of videos_unpartitioned.
+func copyVideos(tx *gorm.DB) error {
+	var columns []string
+	err := tx.Raw("SELECT column_name FROM information_schema.columns " +
+		"WHERE table_schema = current_schema() AND table_name = 'videos_unpartitioned' " +
+		"AND is_generated = 'NEVER' ORDER BY ordinal_position").Scan(&columns).Error
+	if err != nil {
+		return err
+	}
+	list := strings.Join(columns, ", ")
+
+	stmts := []string{
+		fmt.Sprintf("INSERT INTO videos (%s) SELECT %s FROM videos_unpartitioned", list, list),
+		"DROP TABLE videos_unpartitioned",
+	}
+	for _, stmt := range append(stmts, partitionedVideosKeys...) {
+		if err := tx.Exec(stmt).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
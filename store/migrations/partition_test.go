@@ -0,0 +1,40 @@
+package migrations_test
+
+import (
+	"github.com/ditsuke/youtube-focus/config"
+	"github.com/ditsuke/youtube-focus/store/migrations"
+	"gorm.io/gorm"
+	"strings"
+	"testing"
+)
+
+// newTestDB returns an empty in-memory SQLite database.
+func newTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := config.Config{DBDriver: config.DriverSQLite, SQLitePath: ":memory:"}.GetDB()
+	if err != nil {
+		t.Fatalf("open the database: %v", err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatalf("open the database: %v", err)
+	}
+	// The in-memory database is dropped with its last connection, once the test is over
+	t.Cleanup(func() { _ = sqlDB.Close() })
+	return db
+}
+
+func TestPartitionRefusesSQLite(t *testing.T) {
+	db := newTestDB(t)
+	if _, err := migrations.Up(db); err != nil {
+		t.Fatalf("Up: %v", err)
+	}
+
+	added, err := migrations.Partition(db, 2022)
+	if err == nil || !strings.Contains(err.Error(), "only postgres") || len(added) != 0 {
+		t.Errorf("Partition on SQLite = %v, %v, want it refused", added, err)
+	}
+	if !db.Migrator().HasTable("videos") {
+		t.Errorf("Partition on SQLite dropped the videos table")
+	}
+}
@@ -0,0 +1,26 @@
+CREATE TABLE IF NOT EXISTS videos (
+    id                     bigserial PRIMARY KEY,
+    created_at             timestamptz,
+    updated_at             timestamptz,
+    deleted_at             timestamptz,
+    video_id               text NOT NULL UNIQUE,
+    title                  text,
+    description            text,
+    published_at           timestamptz,
+    thumbnail_url          text,
+    channel_id             text,
+    channel_title          text,
+    live_broadcast_content text,
+    duration_seconds       bigint,
+    view_count             bigint,
+    like_count             bigint,
+    comment_count          bigint,
+    default_language       text,
+    category_id            text,
+    -- tsv backs full-text search
+    tsv                    tsvector GENERATED ALWAYS AS (to_tsvector('english', title)) STORED
+);
+
+CREATE INDEX IF NOT EXISTS idx_videos_deleted_at ON videos (deleted_at);
+CREATE INDEX IF NOT EXISTS idx_videos_channel_id ON videos (channel_id);
+CREATE INDEX IF NOT EXISTS ts_idx ON videos USING GIN (tsv);
@@ -0,0 +1,3 @@
+-- The pg_trgm extension is left installed, as other databases of the cluster may use it
+DROP INDEX IF EXISTS description_trgm_idx;
+DROP INDEX IF EXISTS title_trgm_idx;
@@ -0,0 +1,5 @@
+-- Trigram indexes back fuzzy and ILIKE searches
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+CREATE INDEX IF NOT EXISTS title_trgm_idx ON videos USING GIN (title gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS description_trgm_idx ON videos USING GIN (description gin_trgm_ops);
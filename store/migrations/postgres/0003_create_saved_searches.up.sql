@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS saved_searches (
+    id              bigserial PRIMARY KEY,
+    created_at      timestamptz,
+    updated_at      timestamptz,
+    deleted_at      timestamptz,
+    name            text,
+    query           text NOT NULL,
+    mode            text,
+    -- filter is a store.Filter, as JSON
+    filter          text,
+    checked_at      timestamptz,
+    prev_checked_at timestamptz
+);
+
+CREATE INDEX IF NOT EXISTS idx_saved_searches_deleted_at ON saved_searches (deleted_at);
@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS audit_entries (
+    id           bigserial PRIMARY KEY,
+    created_at   timestamptz,
+    actor_key_id bigint,
+    actor        text,
+    action       text,
+    target       text,
+    detail       text,
+    status       bigint
+);
@@ -0,0 +1,31 @@
+-- retention_days overrides how long the videos matching a saved search are kept, if set
+ALTER TABLE saved_searches ADD COLUMN retention_days integer;
+
+-- Pruning finds videos past their retention by publish time
+CREATE INDEX IF NOT EXISTS idx_videos_published_at ON videos (published_at);
+
+-- videos_archive keeps pruned videos, as they were when archived
+CREATE TABLE IF NOT EXISTS videos_archive (
+    id                     bigint PRIMARY KEY,
+    created_at             timestamptz,
+    updated_at             timestamptz,
+    deleted_at             timestamptz,
+    video_id               text NOT NULL,
+    title                  text,
+    description            text,
+    published_at           timestamptz,
+    thumbnail_url          text,
+    channel_id             text,
+    channel_title          text,
+    live_broadcast_content text,
+    duration_seconds       bigint,
+    view_count             bigint,
+    like_count             bigint,
+    comment_count          bigint,
+    default_language       text,
+    category_id            text,
+    removal_status         text,
+    archived_at            timestamptz NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_videos_archive_video_id ON videos_archive (video_id);
@@ -0,0 +1,3 @@
+-- Which of the columns were added, rather than already there, is not recorded, so they are
+-- all left in place
+SELECT 1;
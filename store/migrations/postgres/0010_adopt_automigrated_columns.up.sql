@@ -0,0 +1,53 @@
+-- Tables created by AutoMigrate, and adopted by the first migrations, may lack columns added
+-- to the models since. They are added here, as tables created by those migrations have them.
+ALTER TABLE videos
+    ADD COLUMN IF NOT EXISTS created_at             timestamptz,
+    ADD COLUMN IF NOT EXISTS updated_at             timestamptz,
+    ADD COLUMN IF NOT EXISTS deleted_at             timestamptz,
+    ADD COLUMN IF NOT EXISTS video_id               text NOT NULL UNIQUE,
+    ADD COLUMN IF NOT EXISTS title                  text,
+    ADD COLUMN IF NOT EXISTS description            text,
+    ADD COLUMN IF NOT EXISTS published_at           timestamptz,
+    ADD COLUMN IF NOT EXISTS thumbnail_url          text,
+    ADD COLUMN IF NOT EXISTS channel_id             text,
+    ADD COLUMN IF NOT EXISTS channel_title          text,
+    ADD COLUMN IF NOT EXISTS live_broadcast_content text,
+    ADD COLUMN IF NOT EXISTS duration_seconds       bigint,
+    ADD COLUMN IF NOT EXISTS view_count             bigint,
+    ADD COLUMN IF NOT EXISTS like_count             bigint,
+    ADD COLUMN IF NOT EXISTS comment_count          bigint,
+    ADD COLUMN IF NOT EXISTS default_language       text,
+    ADD COLUMN IF NOT EXISTS category_id            text,
+    ADD COLUMN IF NOT EXISTS tsv                    tsvector
+        GENERATED ALWAYS AS (to_tsvector('english', title)) STORED;
+
+ALTER TABLE saved_searches
+    ADD COLUMN IF NOT EXISTS created_at      timestamptz,
+    ADD COLUMN IF NOT EXISTS updated_at      timestamptz,
+    ADD COLUMN IF NOT EXISTS deleted_at      timestamptz,
+    ADD COLUMN IF NOT EXISTS name            text,
+    ADD COLUMN IF NOT EXISTS query           text NOT NULL,
+    ADD COLUMN IF NOT EXISTS mode            text,
+    ADD COLUMN IF NOT EXISTS filter          text,
+    ADD COLUMN IF NOT EXISTS checked_at      timestamptz,
+    ADD COLUMN IF NOT EXISTS prev_checked_at timestamptz;
+
+ALTER TABLE api_keys
+    ADD COLUMN IF NOT EXISTS created_at timestamptz,
+    ADD COLUMN IF NOT EXISTS updated_at timestamptz,
+    ADD COLUMN IF NOT EXISTS deleted_at timestamptz,
+    ADD COLUMN IF NOT EXISTS name       text,
+    ADD COLUMN IF NOT EXISTS prefix     text,
+    ADD COLUMN IF NOT EXISTS hash       text NOT NULL,
+    ADD COLUMN IF NOT EXISTS rate_limit bigint,
+    ADD COLUMN IF NOT EXISTS role       text NOT NULL DEFAULT 'reader',
+    ADD COLUMN IF NOT EXISTS revoked_at timestamptz;
+
+ALTER TABLE audit_entries
+    ADD COLUMN IF NOT EXISTS created_at   timestamptz,
+    ADD COLUMN IF NOT EXISTS actor_key_id bigint,
+    ADD COLUMN IF NOT EXISTS actor        text,
+    ADD COLUMN IF NOT EXISTS action       text,
+    ADD COLUMN IF NOT EXISTS target       text,
+    ADD COLUMN IF NOT EXISTS detail       text,
+    ADD COLUMN IF NOT EXISTS status       bigint;
@@ -0,0 +1,23 @@
+CREATE TABLE IF NOT EXISTS videos (
+    id                     integer PRIMARY KEY AUTOINCREMENT,
+    created_at             datetime,
+    updated_at             datetime,
+    deleted_at             datetime,
+    video_id               text NOT NULL UNIQUE,
+    title                  text,
+    description            text,
+    published_at           datetime,
+    thumbnail_url          text,
+    channel_id             text,
+    channel_title          text,
+    live_broadcast_content text,
+    duration_seconds       integer,
+    view_count             integer,
+    like_count             integer,
+    comment_count          integer,
+    default_language       text,
+    category_id            text
+);
+
+CREATE INDEX IF NOT EXISTS idx_videos_deleted_at ON videos (deleted_at);
+CREATE INDEX IF NOT EXISTS idx_videos_channel_id ON videos (channel_id);
@@ -0,0 +1,24 @@
+-- videos_fts indexes the titles of videos for natural-language search, as the tsv column of
+-- postgres does. Triggers keep it in step with the videos table.
+CREATE VIRTUAL TABLE IF NOT EXISTS videos_fts USING fts5(
+    title,
+    content = 'videos',
+    content_rowid = 'id',
+    tokenize = 'porter unicode61'
+);
+
+CREATE TRIGGER IF NOT EXISTS videos_fts_insert AFTER INSERT ON videos BEGIN
+    INSERT INTO videos_fts (rowid, title) VALUES (new.id, new.title);
+END;
+
+CREATE TRIGGER IF NOT EXISTS videos_fts_delete AFTER DELETE ON videos BEGIN
+    INSERT INTO videos_fts (videos_fts, rowid, title) VALUES ('delete', old.id, old.title);
+END;
+
+CREATE TRIGGER IF NOT EXISTS videos_fts_update AFTER UPDATE OF title ON videos BEGIN
+    INSERT INTO videos_fts (videos_fts, rowid, title) VALUES ('delete', old.id, old.title);
+    INSERT INTO videos_fts (rowid, title) VALUES (new.id, new.title);
+END;
+
+-- Index any videos stored before the index was created
+INSERT INTO videos_fts (videos_fts) VALUES ('rebuild');
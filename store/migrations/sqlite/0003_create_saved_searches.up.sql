@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS saved_searches (
+    id              integer PRIMARY KEY AUTOINCREMENT,
+    created_at      datetime,
+    updated_at      datetime,
+    deleted_at      datetime,
+    name            text,
+    query           text NOT NULL,
+    mode            text,
+    -- filter is a store.Filter, as JSON
+    filter          text,
+    checked_at      datetime,
+    prev_checked_at datetime
+);
+
+CREATE INDEX IF NOT EXISTS idx_saved_searches_deleted_at ON saved_searches (deleted_at);
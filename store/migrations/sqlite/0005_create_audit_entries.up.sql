@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS audit_entries (
+    id           integer PRIMARY KEY AUTOINCREMENT,
+    created_at   datetime,
+    actor_key_id integer,
+    actor        text,
+    action       text,
+    target       text,
+    detail       text,
+    status       integer
+);
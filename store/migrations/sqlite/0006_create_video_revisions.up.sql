@@ -0,0 +1,12 @@
+-- video_revisions records the prior versions of the content of videos, as they are edited
+CREATE TABLE IF NOT EXISTS video_revisions (
+    id            integer PRIMARY KEY AUTOINCREMENT,
+    video_id      text NOT NULL,
+    title         text,
+    description   text,
+    thumbnail_url text,
+    seen_at       datetime NOT NULL,
+    replaced_at   datetime NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_video_revisions_video_id ON video_revisions (video_id);
@@ -0,0 +1,2 @@
+-- removal_status records why a video was found removed from YouTube, when deleted_at was set
+ALTER TABLE videos ADD COLUMN removal_status text;
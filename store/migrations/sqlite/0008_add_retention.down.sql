@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS videos_archive;
+DROP INDEX IF EXISTS idx_videos_published_at;
+ALTER TABLE saved_searches DROP COLUMN retention_days;
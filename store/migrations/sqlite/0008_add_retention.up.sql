@@ -0,0 +1,31 @@
+-- retention_days overrides how long the videos matching a saved search are kept, if set
+ALTER TABLE saved_searches ADD COLUMN retention_days integer;
+
+-- Pruning finds videos past their retention by publish time
+CREATE INDEX IF NOT EXISTS idx_videos_published_at ON videos (published_at);
+
+-- videos_archive keeps pruned videos, as they were when archived
+CREATE TABLE IF NOT EXISTS videos_archive (
+    id                     integer PRIMARY KEY,
+    created_at             datetime,
+    updated_at             datetime,
+    deleted_at             datetime,
+    video_id               text NOT NULL,
+    title                  text,
+    description            text,
+    published_at           datetime,
+    thumbnail_url          text,
+    channel_id             text,
+    channel_title          text,
+    live_broadcast_content text,
+    duration_seconds       integer,
+    view_count             integer,
+    like_count             integer,
+    comment_count          integer,
+    default_language       text,
+    category_id            text,
+    removal_status         text,
+    archived_at            datetime NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_videos_archive_video_id ON videos_archive (video_id);
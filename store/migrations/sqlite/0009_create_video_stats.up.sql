@@ -0,0 +1,11 @@
+-- video_stats records the statistics of videos over time, as they change
+CREATE TABLE IF NOT EXISTS video_stats (
+    id            integer PRIMARY KEY AUTOINCREMENT,
+    video_id      text NOT NULL,
+    view_count    bigint,
+    like_count    bigint,
+    comment_count bigint,
+    recorded_at   datetime NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_video_stats_video_id ON video_stats (video_id);
@@ -0,0 +1,2 @@
+-- The up migration changes nothing
+SELECT 1;
@@ -0,0 +1,17 @@
+-- Tables created by AutoMigrate, and adopted by the first migrations, may lack columns added
+-- to the models since. SQLite cannot add columns only if they are missing, so this fails on
+-- tables lacking any, rather than leave them behind the schema.
+SELECT created_at, updated_at, deleted_at, video_id, title, description, published_at,
+       thumbnail_url, channel_id, channel_title, live_broadcast_content, duration_seconds,
+       view_count, like_count, comment_count, default_language, category_id
+FROM videos LIMIT 0;
+
+SELECT created_at, updated_at, deleted_at, name, query, mode, filter, checked_at,
+       prev_checked_at
+FROM saved_searches LIMIT 0;
+
+SELECT created_at, updated_at, deleted_at, name, prefix, hash, rate_limit, role, revoked_at
+FROM api_keys LIMIT 0;
+
+SELECT created_at, actor_key_id, actor, action, target, detail, status
+FROM audit_entries LIMIT 0;
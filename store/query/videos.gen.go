@@ -0,0 +1,392 @@
+// Code generated by gorm.io/gen. DO NOT EDIT.
+// Code generated by gorm.io/gen. DO NOT EDIT.
+// Code generated by gorm.io/gen. DO NOT EDIT.
+
+package query
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+
+	"gorm.io/gen"
+	"gorm.io/gen/field"
+
+	"gorm.io/plugin/dbresolver"
+
+	"github.com/ditsuke/youtube-focus/internal/yt"
+)
+
+func newVideo(db *gorm.DB) video {
+	_video := video{}
+
+	_video.videoDo.UseDB(db)
+	_video.videoDo.UseModel(&yt.Video{})
+
+	tableName := _video.videoDo.TableName()
+	_video.ALL = field.NewAsterisk(tableName)
+	_video.ID = field.NewUint(tableName, "id")
+	_video.CreatedAt = field.NewTime(tableName, "created_at")
+	_video.UpdatedAt = field.NewTime(tableName, "updated_at")
+	_video.DeletedAt = field.NewField(tableName, "deleted_at")
+	_video.RemovalStatus = field.NewString(tableName, "removal_status")
+	_video.VideoId = field.NewString(tableName, "video_id")
+	_video.Title = field.NewString(tableName, "title")
+	_video.Description = field.NewString(tableName, "description")
+	_video.PublishedAt = field.NewTime(tableName, "published_at")
+	_video.ThumbnailUrl = field.NewString(tableName, "thumbnail_url")
+	_video.ChannelId = field.NewString(tableName, "channel_id")
+	_video.ChannelTitle = field.NewString(tableName, "channel_title")
+	_video.LiveBroadcastContent = field.NewString(tableName, "live_broadcast_content")
+	_video.DurationSeconds = field.NewInt64(tableName, "duration_seconds")
+	_video.ViewCount = field.NewInt64(tableName, "view_count")
+	_video.LikeCount = field.NewInt64(tableName, "like_count")
+	_video.CommentCount = field.NewInt64(tableName, "comment_count")
+	_video.DefaultLanguage = field.NewString(tableName, "default_language")
+	_video.CategoryId = field.NewString(tableName, "category_id")
+
+	_video.fillFieldMap()
+
+	return _video
+}
+
+type video struct {
+	videoDo videoDo
+
+	ALL                  field.Asterisk
+	ID                   field.Uint
+	CreatedAt            field.Time
+	UpdatedAt            field.Time
+	DeletedAt            field.Field
+	RemovalStatus        field.String
+	VideoId              field.String
+	Title                field.String
+	Description          field.String
+	PublishedAt          field.Time
+	ThumbnailUrl         field.String
+	ChannelId            field.String
+	ChannelTitle         field.String
+	LiveBroadcastContent field.String
+	DurationSeconds      field.Int64
+	ViewCount            field.Int64
+	LikeCount            field.Int64
+	CommentCount         field.Int64
+	DefaultLanguage      field.String
+	CategoryId           field.String
+
+	fieldMap map[string]field.Expr
+}
+
+func (v video) Table(newTableName string) *video {
+	v.videoDo.UseTable(newTableName)
+	return v.updateTableName(newTableName)
+}
+
+func (v video) As(alias string) *video {
+	v.videoDo.DO = *(v.videoDo.As(alias).(*gen.DO))
+	return v.updateTableName(alias)
+}
+
+func (v *video) updateTableName(table string) *video {
+	v.ALL = field.NewAsterisk(table)
+	v.ID = field.NewUint(table, "id")
+	v.CreatedAt = field.NewTime(table, "created_at")
+	v.UpdatedAt = field.NewTime(table, "updated_at")
+	v.DeletedAt = field.NewField(table, "deleted_at")
+	v.RemovalStatus = field.NewString(table, "removal_status")
+	v.VideoId = field.NewString(table, "video_id")
+	v.Title = field.NewString(table, "title")
+	v.Description = field.NewString(table, "description")
+	v.PublishedAt = field.NewTime(table, "published_at")
+	v.ThumbnailUrl = field.NewString(table, "thumbnail_url")
+	v.ChannelId = field.NewString(table, "channel_id")
+	v.ChannelTitle = field.NewString(table, "channel_title")
+	v.LiveBroadcastContent = field.NewString(table, "live_broadcast_content")
+	v.DurationSeconds = field.NewInt64(table, "duration_seconds")
+	v.ViewCount = field.NewInt64(table, "view_count")
+	v.LikeCount = field.NewInt64(table, "like_count")
+	v.CommentCount = field.NewInt64(table, "comment_count")
+	v.DefaultLanguage = field.NewString(table, "default_language")
+	v.CategoryId = field.NewString(table, "category_id")
+
+	v.fillFieldMap()
+
+	return v
+}
+
+func (v *video) WithContext(ctx context.Context) *videoDo { return v.videoDo.WithContext(ctx) }
+
+func (v video) TableName() string { return v.videoDo.TableName() }
+
+func (v video) Alias() string { return v.videoDo.Alias() }
+
+func (v *video) GetFieldByName(fieldName string) (field.OrderExpr, bool) {
+	_f, ok := v.fieldMap[fieldName]
+	if !ok || _f == nil {
+		return nil, false
+	}
+	_oe, ok := _f.(field.OrderExpr)
+	return _oe, ok
+}
+
+func (v *video) fillFieldMap() {
+	v.fieldMap = make(map[string]field.Expr, 19)
+	v.fieldMap["id"] = v.ID
+	v.fieldMap["created_at"] = v.CreatedAt
+	v.fieldMap["updated_at"] = v.UpdatedAt
+	v.fieldMap["deleted_at"] = v.DeletedAt
+	v.fieldMap["removal_status"] = v.RemovalStatus
+	v.fieldMap["video_id"] = v.VideoId
+	v.fieldMap["title"] = v.Title
+	v.fieldMap["description"] = v.Description
+	v.fieldMap["published_at"] = v.PublishedAt
+	v.fieldMap["thumbnail_url"] = v.ThumbnailUrl
+	v.fieldMap["channel_id"] = v.ChannelId
+	v.fieldMap["channel_title"] = v.ChannelTitle
+	v.fieldMap["live_broadcast_content"] = v.LiveBroadcastContent
+	v.fieldMap["duration_seconds"] = v.DurationSeconds
+	v.fieldMap["view_count"] = v.ViewCount
+	v.fieldMap["like_count"] = v.LikeCount
+	v.fieldMap["comment_count"] = v.CommentCount
+	v.fieldMap["default_language"] = v.DefaultLanguage
+	v.fieldMap["category_id"] = v.CategoryId
+}
+
+func (v video) clone(db *gorm.DB) video {
+	v.videoDo.ReplaceDB(db)
+	return v
+}
+
+type videoDo struct{ gen.DO }
+
+func (v videoDo) Debug() *videoDo {
+	return v.withDO(v.DO.Debug())
+}
+
+func (v videoDo) WithContext(ctx context.Context) *videoDo {
+	return v.withDO(v.DO.WithContext(ctx))
+}
+
+func (v videoDo) ReadDB() *videoDo {
+	return v.Clauses(dbresolver.Read)
+}
+
+func (v videoDo) WriteDB() *videoDo {
+	return v.Clauses(dbresolver.Write)
+}
+
+func (v videoDo) Clauses(conds ...clause.Expression) *videoDo {
+	return v.withDO(v.DO.Clauses(conds...))
+}
+
+func (v videoDo) Returning(value interface{}, columns ...string) *videoDo {
+	return v.withDO(v.DO.Returning(value, columns...))
+}
+
+func (v videoDo) Not(conds ...gen.Condition) *videoDo {
+	return v.withDO(v.DO.Not(conds...))
+}
+
+func (v videoDo) Or(conds ...gen.Condition) *videoDo {
+	return v.withDO(v.DO.Or(conds...))
+}
+
+func (v videoDo) Select(conds ...field.Expr) *videoDo {
+	return v.withDO(v.DO.Select(conds...))
+}
+
+func (v videoDo) Where(conds ...gen.Condition) *videoDo {
+	return v.withDO(v.DO.Where(conds...))
+}
+
+func (v videoDo) Exists(subquery interface{ UnderlyingDB() *gorm.DB }) *videoDo {
+	return v.Where(field.CompareSubQuery(field.ExistsOp, nil, subquery.UnderlyingDB()))
+}
+
+func (v videoDo) Order(conds ...field.Expr) *videoDo {
+	return v.withDO(v.DO.Order(conds...))
+}
+
+func (v videoDo) Distinct(cols ...field.Expr) *videoDo {
+	return v.withDO(v.DO.Distinct(cols...))
+}
+
+func (v videoDo) Omit(cols ...field.Expr) *videoDo {
+	return v.withDO(v.DO.Omit(cols...))
+}
+
+func (v videoDo) Join(table schema.Tabler, on ...field.Expr) *videoDo {
+	return v.withDO(v.DO.Join(table, on...))
+}
+
+func (v videoDo) LeftJoin(table schema.Tabler, on ...field.Expr) *videoDo {
+	return v.withDO(v.DO.LeftJoin(table, on...))
+}
+
+func (v videoDo) RightJoin(table schema.Tabler, on ...field.Expr) *videoDo {
+	return v.withDO(v.DO.RightJoin(table, on...))
+}
+
+func (v videoDo) Group(cols ...field.Expr) *videoDo {
+	return v.withDO(v.DO.Group(cols...))
+}
+
+func (v videoDo) Having(conds ...gen.Condition) *videoDo {
+	return v.withDO(v.DO.Having(conds...))
+}
+
+func (v videoDo) Limit(limit int) *videoDo {
+	return v.withDO(v.DO.Limit(limit))
+}
+
+func (v videoDo) Offset(offset int) *videoDo {
+	return v.withDO(v.DO.Offset(offset))
+}
+
+func (v videoDo) Scopes(funcs ...func(gen.Dao) gen.Dao) *videoDo {
+	return v.withDO(v.DO.Scopes(funcs...))
+}
+
+func (v videoDo) Unscoped() *videoDo {
+	return v.withDO(v.DO.Unscoped())
+}
+
+func (v videoDo) Create(values ...*yt.Video) error {
+	if len(values) == 0 {
+		return nil
+	}
+	return v.DO.Create(values)
+}
+
+func (v videoDo) CreateInBatches(values []*yt.Video, batchSize int) error {
+	return v.DO.CreateInBatches(values, batchSize)
+}
+
+// Save : !!! underlying implementation is different with GORM
+// The method is equivalent to executing the statement: db.Clauses(clause.OnConflict{UpdateAll: true}).Create(values)
+func (v videoDo) Save(values ...*yt.Video) error {
+	if len(values) == 0 {
+		return nil
+	}
+	return v.DO.Save(values)
+}
+
+func (v videoDo) First() (*yt.Video, error) {
+	if result, err := v.DO.First(); err != nil {
+		return nil, err
+	} else {
+		return result.(*yt.Video), nil
+	}
+}
+
+func (v videoDo) Take() (*yt.Video, error) {
+	if result, err := v.DO.Take(); err != nil {
+		return nil, err
+	} else {
+		return result.(*yt.Video), nil
+	}
+}
+
+func (v videoDo) Last() (*yt.Video, error) {
+	if result, err := v.DO.Last(); err != nil {
+		return nil, err
+	} else {
+		return result.(*yt.Video), nil
+	}
+}
+
+func (v videoDo) Find() ([]*yt.Video, error) {
+	result, err := v.DO.Find()
+	return result.([]*yt.Video), err
+}
+
+func (v videoDo) FindInBatch(batchSize int, fc func(tx gen.Dao, batch int) error) (results []*yt.Video, err error) {
+	buf := make([]*yt.Video, 0, batchSize)
+	err = v.DO.FindInBatches(&buf, batchSize, func(tx gen.Dao, batch int) error {
+		defer func() { results = append(results, buf...) }()
+		return fc(tx, batch)
+	})
+	return results, err
+}
+
+func (v videoDo) FindInBatches(result *[]*yt.Video, batchSize int, fc func(tx gen.Dao, batch int) error) error {
+	return v.DO.FindInBatches(result, batchSize, fc)
+}
+
+func (v videoDo) Attrs(attrs ...field.AssignExpr) *videoDo {
+	return v.withDO(v.DO.Attrs(attrs...))
+}
+
+func (v videoDo) Assign(attrs ...field.AssignExpr) *videoDo {
+	return v.withDO(v.DO.Assign(attrs...))
+}
+
+func (v videoDo) Joins(fields ...field.RelationField) *videoDo {
+	for _, _f := range fields {
+		v = *v.withDO(v.DO.Joins(_f))
+	}
+	return &v
+}
+
+func (v videoDo) Preload(fields ...field.RelationField) *videoDo {
+	for _, _f := range fields {
+		v = *v.withDO(v.DO.Preload(_f))
+	}
+	return &v
+}
+
+func (v videoDo) FirstOrInit() (*yt.Video, error) {
+	if result, err := v.DO.FirstOrInit(); err != nil {
+		return nil, err
+	} else {
+		return result.(*yt.Video), nil
+	}
+}
+
+func (v videoDo) FirstOrCreate() (*yt.Video, error) {
+	if result, err := v.DO.FirstOrCreate(); err != nil {
+		return nil, err
+	} else {
+		return result.(*yt.Video), nil
+	}
+}
+
+func (v videoDo) FindByPage(offset int, limit int) (result []*yt.Video, count int64, err error) {
+	result, err = v.Offset(offset).Limit(limit).Find()
+	if err != nil {
+		return
+	}
+
+	if size := len(result); 0 < limit && 0 < size && size < limit {
+		count = int64(size + offset)
+		return
+	}
+
+	count, err = v.Offset(-1).Limit(-1).Count()
+	return
+}
+
+func (v videoDo) ScanByPage(result interface{}, offset int, limit int) (count int64, err error) {
+	count, err = v.Count()
+	if err != nil {
+		return
+	}
+
+	err = v.Offset(offset).Limit(limit).Scan(result)
+	return
+}
+
+func (v videoDo) Scan(result interface{}) (err error) {
+	return v.DO.Scan(result)
+}
+
+func (v videoDo) Delete(models ...*yt.Video) (result gen.ResultInfo, err error) {
+	return v.DO.Delete(models)
+}
+
+func (v *videoDo) withDO(do gen.Dao) *videoDo {
+	v.DO = *do.(*gen.DO)
+	return v
+}
@@ -0,0 +1,64 @@
+package store
+
+import (
+	"github.com/ditsuke/youtube-focus/internal/yt"
+	"gorm.io/gorm"
+)
+
+// AvailableIDs returns the YouTube IDs of up to limit videos not known to be removed from
+// YouTube, stored after the video of ID after, along with the ID of the last of them. Passing
+// that ID back in pages through the store, which starts over from an ID of zero.
+func (v *VideoMetaStore) AvailableIDs(after uint, limit int) ([]string, uint, error) {
+	var videos []yt.Video
+	err := v.DB.
+		Select("id", "video_id").
+		Where("id > ?", after).
+		Order("id").
+		Limit(limit).
+		Find(&videos).Error
+	if err != nil {
+		return nil, 0, wrapErr(err)
+	}
+
+	ids := make([]string, len(videos))
+	for i := range videos {
+		ids[i] = videos[i].VideoId
+	}
+	if len(videos) == 0 {
+		return ids, 0, nil
+	}
+	return ids, videos[len(videos)-1].ID, nil
+}
+
+// MarkRemoved records videos as removed from YouTube, mapping their YouTube IDs to one of the
+// yt.Removal* statuses, and returns the number of videos marked. Removed videos are
+// soft-deleted at the time they are marked, and those already removed are left as they are.
+func (v *VideoMetaStore) MarkRemoved(statuses map[string]string) (int64, error) {
+	byStatus := make(map[string][]string)
+	for id, status := range statuses {
+		byStatus[status] = append(byStatus[status], id)
+	}
+
+	var marked int64
+	err := v.DB.Transaction(func(tx *gorm.DB) error {
+		now := tx.NowFunc()
+		for status, ids := range byStatus {
+			// The content of removed videos is as it was, so updated_at is left alone
+			result := tx.Model(&yt.Video{}).
+				Where("video_id IN ?", ids).
+				UpdateColumns(map[string]interface{}{
+					"removal_status": status,
+					"deleted_at":     now,
+				})
+			if result.Error != nil {
+				return result.Error
+			}
+			marked += result.RowsAffected
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, wrapErr(err)
+	}
+	return marked, nil
+}
@@ -0,0 +1,203 @@
+package store
+
+import (
+	"errors"
+	"github.com/ditsuke/youtube-focus/internal/yt"
+	"gorm.io/gorm"
+	"time"
+)
+
+// RetentionPolicy decides how long videos are kept, by the time they were published. Videos
+// matching saved searches with a RetentionDays of their own are kept for the longest of those
+// instead.
+type RetentionPolicy struct {
+	// Days is the number of days videos are kept for, forever if zero.
+	Days int
+	// Archive moves pruned videos to the videos_archive table, rather than deleting them.
+	Archive bool
+}
+
+// retention returns how long the policy keeps videos matching a search, zero for forever.
+func (p RetentionPolicy) retention(search *SavedSearch) time.Duration {
+	days := p.Days
+	if search != nil && search.RetentionDays > 0 {
+		days = search.RetentionDays
+	}
+	return time.Duration(days) * 24 * time.Hour
+}
+
+// PruneReport describes the videos a Prune removed, or would have removed in a dry run.
+type PruneReport struct {
+	DryRun   bool
+	Archived bool
+	// Videos is the number of videos pruned, published between Oldest and Newest.
+	Videos int64
+	Oldest time.Time
+	Newest time.Time
+}
+
+// archivedColumns are the columns of videos kept in videos_archive.
+const archivedColumns = "id, created_at, updated_at, deleted_at, video_id, title, description, " +
+	"published_at, thumbnail_url, channel_id, channel_title, live_broadcast_content, " +
+	"duration_seconds, view_count, like_count, comment_count, default_language, category_id, " +
+	"removal_status"
+
+// Prune removes the videos kept past their retention under a policy, whether removed from
+// YouTube or not, batchSize at a time. Each batch is removed in a transaction of its own, so
+// that pruning never holds locks for long. A dry run only reports what would be removed.
+func (v *VideoMetaStore) Prune(policy RetentionPolicy, batchSize int, dryRun bool,
+) (PruneReport, error) {
+	report := PruneReport{DryRun: dryRun, Archived: policy.Archive}
+
+	var searches []SavedSearch
+	if err := v.DB.Find(&searches).Error; err != nil {
+		return report, wrapErr(err)
+	}
+
+	// No video is pruned before the shortest retention of any, which may be forever
+	shortest := policy.retention(nil)
+	for i := range searches {
+		r := policy.retention(&searches[i])
+		if r > 0 && (shortest == 0 || r < shortest) {
+			shortest = r
+		}
+	}
+	if shortest == 0 {
+		return report, nil
+	}
+
+	now := v.DB.NowFunc()
+	var cursor uint
+	for {
+		var batch []yt.Video
+		err := v.DB.Unscoped().
+			Select("id", "video_id", "published_at").
+			Where("published_at < ? AND id > ?", now.Add(-shortest).UTC(), cursor).
+			Order("id").
+			Limit(batchSize).
+			Find(&batch).Error
+		if err != nil {
+			return report, wrapErr(err)
+		}
+		if len(batch) == 0 {
+			return report, nil
+		}
+		cursor = batch[len(batch)-1].ID
+
+		expired, err := v.expired(policy, searches, batch, now)
+		if err != nil {
+			return report, err
+		}
+		if len(expired) == 0 {
+			continue
+		}
+		if !dryRun {
+			if err := v.remove(expired, policy.Archive, now); err != nil {
+				return report, err
+			}
+		}
+
+		report.Videos += int64(len(expired))
+		for _, video := range expired {
+			if report.Oldest.IsZero() || video.PublishedAt.Before(report.Oldest) {
+				report.Oldest = video.PublishedAt
+			}
+			if video.PublishedAt.After(report.Newest) {
+				report.Newest = video.PublishedAt
+			}
+		}
+	}
+}
+
+// expired returns the videos of a batch kept past their retention, which is the longest of
+// those of the searches they match, or that of the policy if they match none. Searches that
+// cannot be run, eg: for no words, are logged and match none.
+func (v *VideoMetaStore) expired(policy RetentionPolicy, searches []SavedSearch,
+	batch []yt.Video, now time.Time,
+) ([]yt.Video, error) {
+	ids := make([]uint, len(batch))
+	for i := range batch {
+		ids[i] = batch[i].ID
+	}
+
+	retentions := make(map[uint]time.Duration, len(batch))
+	matched := make(map[uint]bool, len(batch))
+	for i := range searches {
+		r := policy.retention(&searches[i])
+		matching, err := v.matching(searches[i], ids)
+		if errors.Is(err, ErrInvalidQuery) {
+			// A search that cannot be run matches nothing, rather than holding up pruning
+			v.Logger.Warn().Err(err).Uint("saved_search", searches[i].ID).
+				Msg("skipping saved search in pruning")
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		for _, id := range matching {
+			prev, ok := retentions[id]
+			switch {
+			case !ok:
+				retentions[id] = r
+			case prev == 0 || r == 0:
+				retentions[id] = 0
+			case r > prev:
+				retentions[id] = r
+			}
+			matched[id] = true
+		}
+	}
+
+	var expired []yt.Video
+	for _, video := range batch {
+		r := policy.retention(nil)
+		if matched[video.ID] {
+			r = retentions[video.ID]
+		}
+		if r > 0 && video.PublishedAt.Before(now.Add(-r)) {
+			expired = append(expired, video)
+		}
+	}
+	return expired, nil
+}
+
+// matching returns the IDs of the videos among ids that a saved search matches, regardless
+// of when they were first seen, or whether they were removed from YouTube.
+func (v *VideoMetaStore) matching(search SavedSearch, ids []uint) ([]uint, error) {
+	var matching []uint
+	err := v.withMatching(search, func(_, matches *gorm.DB) error {
+		return matches.Where("id IN ?", ids).Pluck("id", &matching).Error
+	})
+	return matching, wrapErr(err)
+}
+
+// remove deletes videos, along with the revisions of their content and the history of their
+// statistics unless they are archived.
+func (v *VideoMetaStore) remove(videos []yt.Video, archive bool, now time.Time) error {
+	ids := make([]uint, len(videos))
+	videoIDs := make([]string, len(videos))
+	for i := range videos {
+		ids[i], videoIDs[i] = videos[i].ID, videos[i].VideoId
+	}
+
+	err := v.DB.Transaction(func(tx *gorm.DB) error {
+		if archive {
+			err := tx.Exec("INSERT INTO videos_archive ("+archivedColumns+", archived_at) "+
+				"SELECT "+archivedColumns+", ? FROM videos WHERE id IN ?", now, ids).Error
+			if err != nil {
+				return err
+			}
+		} else {
+			err := tx.Where("video_id IN ?", videoIDs).Delete(&VideoRevision{}).Error
+			if err != nil {
+				return err
+			}
+			err = tx.Where("video_id IN ?", videoIDs).Delete(&VideoStats{}).Error
+			if err != nil {
+				return err
+			}
+		}
+		return tx.Unscoped().Where("id IN ?", ids).Delete(&yt.Video{}).Error
+	})
+	return wrapErr(err)
+}
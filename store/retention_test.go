@@ -0,0 +1,223 @@
+package store_test
+
+import (
+	"fmt"
+	"github.com/ditsuke/youtube-focus/internal/yt"
+	"github.com/ditsuke/youtube-focus/store"
+	"gorm.io/gorm"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+)
+
+// day is the unit of retention.
+const day = 24 * time.Hour
+
+// saveAged stores videos published the given number of days ago, by their IDs, titled after
+// their IDs.
+func saveAged(t *testing.T, videos store.VideoStore, ages map[string]int) {
+	t.Helper()
+	now := time.Now()
+	var records []yt.Video
+	for id, age := range ages {
+		records = append(records, yt.Video{
+			VideoId:     id,
+			Title:       id,
+			PublishedAt: now.Add(-time.Duration(age) * day),
+			ViewCount:   100,
+		})
+	}
+	if _, err := videos.Save(records); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+}
+
+// storedIDs returns the sorted YouTube IDs of the videos in a table.
+func storedIDs(t *testing.T, db *gorm.DB, table string) []string {
+	t.Helper()
+	ids := []string{}
+	if err := db.Table(table).Order("video_id").Pluck("video_id", &ids).Error; err != nil {
+		t.Fatalf("list %s: %v", table, err)
+	}
+	return ids
+}
+
+func countRows(t *testing.T, db *gorm.DB, model interface{}) int64 {
+	t.Helper()
+	var n int64
+	if err := db.Model(model).Count(&n).Error; err != nil {
+		t.Fatalf("count: %v", err)
+	}
+	return n
+}
+
+func TestPruneSavedSearchRetention(t *testing.T) {
+	db := newTestDB(t)
+	videos := &store.VideoMetaStore{DB: db}
+	saveAged(t, videos, map[string]int{
+		"cat old": 40, "cat ancient": 90, "dog old": 40, "bird recent": 10, "fish recent": 10,
+	})
+
+	searches := &store.SavedSearchStore{DB: db}
+	for _, s := range []store.SavedSearch{
+		// Keeps videos of cats longer than the policy does
+		{Name: "cats", Query: "cat", Mode: store.ModeLike, RetentionDays: 60},
+		// Keeps videos of birds shorter than the policy does
+		{Name: "birds", Query: "bird", Mode: store.ModeLike, RetentionDays: 5},
+		// Defers to the policy
+		{Name: "fish", Query: "fish", Mode: store.ModeLike},
+	} {
+		s := s
+		if err := searches.Create(&s); err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+	}
+
+	report, err := videos.Prune(store.RetentionPolicy{Days: 30}, 2, false)
+	if err != nil {
+		t.Fatalf("Prune: %v", err)
+	}
+	if report.Videos != 3 || report.DryRun || report.Archived {
+		t.Errorf("Prune = %+v, want 3 videos deleted", report)
+	}
+	want := []string{"cat old", "fish recent"}
+	if got := storedIDs(t, db, "videos"); !reflect.DeepEqual(got, want) {
+		t.Errorf("videos after Prune = %v, want %v", got, want)
+	}
+}
+
+func TestPruneLongestRetentionOfMatchingSearches(t *testing.T) {
+	db := newTestDB(t)
+	videos := &store.VideoMetaStore{DB: db}
+	saveAged(t, videos, map[string]int{"cat dog": 40, "dog": 40})
+
+	searches := &store.SavedSearchStore{DB: db}
+	for _, s := range []store.SavedSearch{
+		{Name: "cats", Query: "cat", Mode: store.ModeLike, RetentionDays: 60},
+		{Name: "dogs", Query: "dog", Mode: store.ModeLike, RetentionDays: 20},
+	} {
+		s := s
+		if err := searches.Create(&s); err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+	}
+
+	if _, err := videos.Prune(store.RetentionPolicy{}, 10, false); err != nil {
+		t.Fatalf("Prune: %v", err)
+	}
+	if got := storedIDs(t, db, "videos"); !reflect.DeepEqual(got, []string{"cat dog"}) {
+		t.Errorf("videos after Prune = %v, want [cat dog]", got)
+	}
+}
+
+func TestPruneForever(t *testing.T) {
+	db := newTestDB(t)
+	videos := &store.VideoMetaStore{DB: db}
+	saveAged(t, videos, map[string]int{"old": 4000})
+
+	report, err := videos.Prune(store.RetentionPolicy{}, 10, false)
+	if err != nil || report.Videos != 0 {
+		t.Errorf("Prune without retention = %+v, %v, want nothing pruned", report, err)
+	}
+	if got := storedIDs(t, db, "videos"); !reflect.DeepEqual(got, []string{"old"}) {
+		t.Errorf("videos after Prune = %v, want [old]", got)
+	}
+}
+
+func TestPruneModes(t *testing.T) {
+	for _, archive := range []bool{false, true} {
+		archive := archive
+		t.Run(fmt.Sprintf("Archive=%v", archive), func(t *testing.T) {
+			testPruneMode(t, archive)
+		})
+	}
+}
+
+func testPruneMode(t *testing.T, archive bool) {
+	db := newTestDB(t)
+	videos := &store.VideoMetaStore{DB: db}
+	saveAged(t, videos, map[string]int{"old": 40, "recent": 10})
+	// Retitle the old video, for the next save to revise it back, and record a revision
+	if err := db.Model(&yt.Video{}).Where("video_id = ?", "old").
+		Update("title", "retitled").Error; err != nil {
+		t.Fatalf("retitle: %v", err)
+	}
+	saveAged(t, videos, map[string]int{"old": 40})
+	if n := countRows(t, db, &store.VideoRevision{}); n != 1 {
+		t.Fatalf("%d revisions before Prune, want 1", n)
+	}
+
+	report, err := videos.Prune(store.RetentionPolicy{Days: 30, Archive: archive}, 10, false)
+	if err != nil {
+		t.Fatalf("Prune with Archive %v: %v", archive, err)
+	}
+	if report.Videos != 1 || report.Archived != archive {
+		t.Errorf("Prune with Archive %v = %+v, want 1 video pruned", archive, report)
+	}
+	if got := storedIDs(t, db, "videos"); !reflect.DeepEqual(got, []string{"recent"}) {
+		t.Errorf("videos after Prune with Archive %v = %v, want [recent]", archive, got)
+	}
+
+	archived := storedIDs(t, db, "videos_archive")
+	revisions := countRows(t, db, &store.VideoRevision{})
+	var stats []string
+	if err := db.Model(&store.VideoStats{}).Distinct().Pluck("video_id", &stats).
+		Error; err != nil {
+		t.Fatalf("list stats: %v", err)
+	}
+	sort.Strings(stats)
+	if archive {
+		// Archived videos keep their revisions and statistics
+		if !reflect.DeepEqual(archived, []string{"old"}) || revisions != 1 ||
+			!reflect.DeepEqual(stats, []string{"old", "recent"}) {
+			t.Errorf("archived %v, with %d revisions and stats of %v, want [old], 1 and "+
+				"[old recent]", archived, revisions, stats)
+		}
+		var title string
+		if err := db.Table("videos_archive").Select("title").Scan(&title).Error; err != nil ||
+			title != "old" {
+			t.Errorf("title of the archived video = %q, %v, want old", title, err)
+		}
+	} else if len(archived) != 0 || revisions != 0 ||
+		!reflect.DeepEqual(stats, []string{"recent"}) {
+		t.Errorf("archived %v, with %d revisions and stats of %v, want none, 0 and "+
+			"[recent]", archived, revisions, stats)
+	}
+}
+
+func TestPruneDryRun(t *testing.T) {
+	for _, archive := range []bool{false, true} {
+		archive := archive
+		t.Run(fmt.Sprintf("Archive=%v", archive), func(t *testing.T) {
+			testPruneDryRun(t, archive)
+		})
+	}
+}
+
+func testPruneDryRun(t *testing.T, archive bool) {
+	db := newTestDB(t)
+	videos := &store.VideoMetaStore{DB: db}
+	saveAged(t, videos, map[string]int{"old": 40, "older": 50, "recent": 10})
+
+	report, err := videos.Prune(store.RetentionPolicy{Days: 30, Archive: archive}, 1, true)
+	if err != nil {
+		t.Fatalf("Prune: %v", err)
+	}
+	if !report.DryRun || report.Videos != 2 ||
+		report.Oldest.After(time.Now().Add(-50*day)) ||
+		report.Newest.Before(time.Now().Add(-41*day)) {
+		t.Errorf("dry run = %+v, want 2 videos, published 50 to 40 days ago", report)
+	}
+
+	want := []string{"old", "older", "recent"}
+	if got := storedIDs(t, db, "videos"); !reflect.DeepEqual(got, want) {
+		t.Errorf("videos after a dry run = %v, want %v", got, want)
+	}
+	if got := storedIDs(t, db, "videos_archive"); len(got) != 0 {
+		t.Errorf("dry run archived %v", got)
+	}
+	if n := countRows(t, db, &store.VideoStats{}); n != 3 {
+		t.Errorf("%d stats after a dry run, want 3", n)
+	}
+}
@@ -0,0 +1,58 @@
+package store
+
+import (
+	"github.com/ditsuke/youtube-focus/internal/yt"
+	"time"
+)
+
+// VideoRevision is a prior version of the content of a video, as it was before an edit.
+type VideoRevision struct {
+	ID           uint `gorm:"primarykey"`
+	VideoId      string
+	Title        string
+	Description  string
+	ThumbnailUrl string
+	// SeenAt is when this version was first seen, and ReplacedAt when it was edited.
+	SeenAt     time.Time
+	ReplacedAt time.Time
+}
+
+// isEdited reports whether the content of a video differs from that of a stored version, as
+// tracked by revisions.
+func isEdited(stored, v yt.Video) bool {
+	return stored.Title != v.Title ||
+		stored.Description != v.Description ||
+		stored.ThumbnailUrl != v.ThumbnailUrl
+}
+
+// hasNewStatistics reports whether the statistics of a video differ from those of a stored
+// version. Videos whose details could not be looked up have no statistics to go by.
+func hasNewStatistics(stored, v yt.Video) bool {
+	if v.ViewCount == 0 && v.LikeCount == 0 && v.CommentCount == 0 {
+		return false
+	}
+	return stored.ViewCount != v.ViewCount ||
+		stored.LikeCount != v.LikeCount ||
+		stored.CommentCount != v.CommentCount
+}
+
+// revisionOf returns the revision recording the content of a stored video, replaced at some
+// time.
+func revisionOf(stored yt.Video, replacedAt time.Time) VideoRevision {
+	return VideoRevision{
+		VideoId:      stored.VideoId,
+		Title:        stored.Title,
+		Description:  stored.Description,
+		ThumbnailUrl: stored.ThumbnailUrl,
+		SeenAt:       stored.UpdatedAt,
+		ReplacedAt:   replacedAt,
+	}
+}
+
+// Revisions returns the prior versions of a video, by its YouTube ID, oldest first. Videos
+// that were never edited have none.
+func (v *VideoMetaStore) Revisions(videoID string) ([]VideoRevision, error) {
+	revisions := []VideoRevision{}
+	err := v.DB.Where("video_id = ?", videoID).Order("replaced_at, id").Find(&revisions).Error
+	return revisions, wrapErr(err)
+}
@@ -0,0 +1,31 @@
+package store
+
+// Role is the level of access granted to an API client. Each role includes the access of the
+// roles below it.
+type Role string
+
+const (
+	// RoleReader can query videos and saved searches.
+	RoleReader Role = "reader"
+	// RoleEditor can also create and delete saved searches.
+	RoleEditor Role = "editor"
+	// RoleAdmin can also manage API keys and read the audit log.
+	RoleAdmin Role = "admin"
+)
+
+var roleLevels = map[Role]int{
+	RoleReader: 1,
+	RoleEditor: 2,
+	RoleAdmin:  3,
+}
+
+// IsValid reports whether r is a known role.
+func (r Role) IsValid() bool {
+	_, ok := roleLevels[r]
+	return ok
+}
+
+// Includes reports whether r grants the access of other.
+func (r Role) Includes(other Role) bool {
+	return r.IsValid() && roleLevels[r] >= roleLevels[other]
+}
@@ -0,0 +1,193 @@
+package store
+
+import (
+	"errors"
+	"github.com/ditsuke/youtube-focus/internal/yt"
+	"github.com/rs/zerolog"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"time"
+)
+
+// SearchMode is the kind of search a SavedSearch runs.
+type SearchMode string
+
+const (
+	// ModeLike runs searches with VideoMetaStore.Search.
+	ModeLike SearchMode = "like"
+	// ModeFuzzy runs searches with VideoMetaStore.FuzzySearch.
+	ModeFuzzy SearchMode = "fuzzy"
+	// ModeNatural runs searches with VideoMetaStore.NaturalSearch.
+	ModeNatural SearchMode = "natural"
+)
+
+// IsValid reports whether m is a supported search mode.
+func (m SearchMode) IsValid() bool {
+	return m == ModeLike || m == ModeFuzzy || m == ModeNatural
+}
+
+// SavedSearch is a search query, with filters, saved so that it can be checked for new
+// results later.
+type SavedSearch struct {
+	gorm.Model
+	Name   string
+	Query  string `gorm:"not null"`
+	Mode   SearchMode
+	Filter Filter
+	// RetentionDays is the number of days the videos it matches are kept for, overriding
+	// the RetentionPolicy of the store if set. See VideoMetaStore.Prune.
+	RetentionDays int
+
+	// CheckedAt is the read marker: videos first seen up to this time have been checked.
+	CheckedAt time.Time
+	// PrevCheckedAt is the read marker before the last check, kept so that the results of
+	// the last check can be paged through.
+	PrevCheckedAt time.Time
+}
+
+// SavedSearchStore is the storage for saved searches and their read markers.
+type SavedSearchStore struct {
+	Logger zerolog.Logger
+	DB     *gorm.DB
+}
+
+// Create saves a new search. Its read marker starts at the time of creation, so that only
+// videos seen from then on are new to it.
+func (s *SavedSearchStore) Create(search *SavedSearch) error {
+	now := s.DB.NowFunc()
+	search.CheckedAt, search.PrevCheckedAt = now, now
+	return wrapErr(s.DB.Create(search).Error)
+}
+
+// List all saved searches, oldest first.
+func (s *SavedSearchStore) List() ([]SavedSearch, error) {
+	var searches []SavedSearch
+	err := s.DB.Order("id").Find(&searches).Error
+	return searches, wrapErr(err)
+}
+
+// Get a saved search by ID. Returns ErrNotFound if there is no such search.
+func (s *SavedSearchStore) Get(id uint) (SavedSearch, error) {
+	var search SavedSearch
+	err := s.DB.First(&search, id).Error
+	return search, wrapErr(err)
+}
+
+// Delete a saved search by ID. Returns ErrNotFound if there is no such search.
+func (s *SavedSearchStore) Delete(id uint) error {
+	result := s.DB.Delete(&SavedSearch{}, id)
+	if result.Error == nil && result.RowsAffected == 0 {
+		return wrapErr(gorm.ErrRecordNotFound)
+	}
+	return wrapErr(result.Error)
+}
+
+// Check moves the read marker of a saved search up to now, and calls fn with the updated
+// search, eg: to run it for its new results. The marker is left where it was if fn fails, so
+// that no results are skipped, and concurrent checks wait for each other. Returns the updated
+// search, or ErrNotFound if there is no such search.
+func (s *SavedSearchStore) Check(id uint, fn func(checked SavedSearch) error,
+) (SavedSearch, error) {
+	var search SavedSearch
+	err := s.DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&search).
+			Clauses(clause.Returning{}).
+			Where("id = ?", id).
+			Updates(map[string]interface{}{
+				"prev_checked_at": gorm.Expr("checked_at"),
+				"checked_at":      tx.NowFunc(),
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return fn(search)
+	})
+	return search, wrapErr(err)
+}
+
+// SavedSearchResults runs a saved search for the videos first seen between its last two
+// checks, published before publishedBefore, latest first. Pagination follows that of Retrieve
+// in natural mode, which NaturalSearch does not page, and that of the search's SearchMode in
+// others.
+func (v *VideoMetaStore) SavedSearchResults(search SavedSearch, publishedBefore time.Time,
+	limit int,
+) ([]yt.Video, error) {
+	return savedSearchResults(v, search, publishedBefore, limit)
+}
+
+// savedSearchResults runs a saved search against any store, as SavedSearchResults does.
+func savedSearchResults(st VideoStore, search SavedSearch, publishedBefore time.Time,
+	limit int,
+) ([]yt.Video, error) {
+	f := search.Filter
+	f.FirstSeenAfter, f.FirstSeenBefore = search.PrevCheckedAt, search.CheckedAt
+	if search.Mode == ModeNatural &&
+		(f.PublishedBefore.IsZero() || publishedBefore.Before(f.PublishedBefore)) {
+		f.PublishedBefore = publishedBefore
+	}
+	st = st.WithFilter(f)
+
+	switch search.Mode {
+	case ModeNatural:
+		return st.NaturalSearch(search.Query, limit)
+	case ModeFuzzy:
+		return st.FuzzySearch(search.Query, publishedBefore, limit)
+	default:
+		return st.Search(search.Query, publishedBefore, limit)
+	}
+}
+
+// MatchingSearches returns the saved searches matching videos, by their IDs in the store,
+// regardless of when the videos were first seen. Videos matching none are left out, as are
+// saved searches that cannot be run.
+func (v *VideoMetaStore) MatchingSearches(ids []uint) (map[uint][]SavedSearch, error) {
+	var searches []SavedSearch
+	if err := v.DB.Order("id").Find(&searches).Error; err != nil {
+		return nil, wrapErr(err)
+	}
+
+	matches := make(map[uint][]SavedSearch, len(ids))
+	for _, search := range searches {
+		matching, err := v.matching(search, ids)
+		if errors.Is(err, ErrInvalidQuery) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		for _, id := range matching {
+			matches[id] = append(matches[id], search)
+		}
+	}
+	return matches, nil
+}
+
+// withMatching calls fn with a query for the videos a saved search matches, regardless of when
+// they were first seen, or whether they were removed from YouTube, along with the database or
+// transaction to run it and any query it is a part of in. Fails with ErrInvalidQuery if the
+// search cannot be run.
+func (v *VideoMetaStore) withMatching(search SavedSearch, fn func(tx, matches *gorm.DB) error,
+) error {
+	f := search.Filter
+	f.Removed = RemovedInclude
+	b := v.backend()
+
+	switch search.Mode {
+	case ModeNatural:
+		q, err := v.withFilter(f).naturalSearchQuery(search.Query)
+		if err != nil {
+			return err
+		}
+		return fn(v.DB, q.Model(&yt.Video{}))
+	case ModeFuzzy:
+		return b.withFuzzyThreshold(v.DB, v.fuzzyThreshold(), func(tx *gorm.DB) error {
+			return fn(tx, tx.Model(&yt.Video{}).Scopes(f.scope, b.fuzzyMatch(search.Query)))
+		})
+	default:
+		return fn(v.DB, v.withFilter(f).query().Model(&yt.Video{}).
+			Scopes(b.contains(search.Query)))
+	}
+}
@@ -0,0 +1,44 @@
+package store
+
+import (
+	"github.com/ditsuke/youtube-focus/internal/yt"
+	"time"
+)
+
+// VideoStats records the statistics of a video as they were at some time.
+type VideoStats struct {
+	ID           uint `gorm:"primarykey"`
+	VideoId      string
+	ViewCount    int64
+	LikeCount    int64
+	CommentCount int64
+	RecordedAt   time.Time
+}
+
+// statsOf returns the record of the statistics of a video, as they were at some time.
+func statsOf(v yt.Video, recordedAt time.Time) VideoStats {
+	return VideoStats{
+		VideoId:      v.VideoId,
+		ViewCount:    v.ViewCount,
+		LikeCount:    v.LikeCount,
+		CommentCount: v.CommentCount,
+		RecordedAt:   recordedAt,
+	}
+}
+
+// StatsHistory returns the statistics recorded for videos, by their YouTube IDs, oldest first.
+// Statistics are recorded as videos are first stored, and whenever they change after. Videos
+// with none recorded are left out.
+func (v *VideoMetaStore) StatsHistory(videoIDs []string) (map[string][]VideoStats, error) {
+	var records []VideoStats
+	err := v.DB.Where("video_id IN ?", videoIDs).Order("recorded_at, id").Find(&records).Error
+	if err != nil {
+		return nil, wrapErr(err)
+	}
+
+	history := make(map[string][]VideoStats, len(videoIDs))
+	for _, r := range records {
+		history[r.VideoId] = append(history[r.VideoId], r)
+	}
+	return history, nil
+}
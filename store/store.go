@@ -1,9 +1,11 @@
 package store
 
 import (
+	"context"
 	"fmt"
 	"github.com/ditsuke/youtube-focus/internal/interfaces"
 	"github.com/ditsuke/youtube-focus/internal/yt"
+	"github.com/ditsuke/youtube-focus/store/query"
 	"github.com/rs/zerolog"
 	"gorm.io/gorm"
 	"gorm.io/gorm/clause"
@@ -16,85 +18,385 @@ import (
 type VideoMetaStore struct {
 	Logger zerolog.Logger
 	DB     *gorm.DB
+
+	// FuzzyThreshold is the minimum trigram word-similarity (0-1] between a query and a
+	// title or description for FuzzySearch to consider it a match.
+	FuzzyThreshold float64
+
+	// OnEdit, if set, is called with the stored videos whose content a Save updated.
+	OnEdit func(edited []yt.Video)
+
+	filter Filter
+}
+
+// VideoStore is a store of videos the API can serve, extending interfaces.Store with the
+// queries it makes. It is implemented by VideoMetaStore, and by MemoryStore without a
+// database; see the VideoMetaStore methods for the semantics of each.
+type VideoStore interface {
+	interfaces.Store[yt.Video, time.Time]
+
+	// WithFilter returns a copy of the store whose queries only match videos passing the
+	// filter.
+	WithFilter(filter Filter) VideoStore
+
+	NaturalSearch(query string, limit int) ([]yt.Video, error)
+	NaturalSearchFacets(query string, facets []Facet, limit int) (map[Facet][]FacetCount, error)
+	FuzzySearch(query string, publishedBefore time.Time, limit int) ([]yt.Video, error)
+	Suggest(query string, limit int) ([]string, error)
+	SavedSearchResults(search SavedSearch, publishedBefore time.Time, limit int,
+	) ([]yt.Video, error)
+
+	Get(videoID string) (yt.Video, error)
+	Lookup(videoIDs []string) ([]yt.Video, error)
+	Revisions(videoID string) ([]VideoRevision, error)
+	StatsHistory(videoIDs []string) (map[string][]VideoStats, error)
+	MatchingSearches(ids []uint) (map[uint][]SavedSearch, error)
+
+	Channels(channelIDs []string) ([]Channel, error)
+	ChannelVideos(channelIDs []string, publishedBefore time.Time, limit int,
+	) (map[string][]yt.Video, error)
+
+	Walk(fn func(*yt.Video) error) error
+	WalkAfter(id uint, fn func(*yt.Video) error) error
+}
+
+// ManagedVideoStore is a VideoStore that the background services keep: fetching videos into
+// it, refreshing and verifying those stored, and pruning those past their retention. Dumps
+// are loaded into it too.
+type ManagedVideoStore interface {
+	VideoStore
+
+	Refresh(records []yt.Video) error
+	Import(videos []yt.Video) (int64, error)
+	AvailableIDs(after uint, limit int) ([]string, uint, error)
+	MarkRemoved(statuses map[string]string) (int64, error)
+	Prune(policy RetentionPolicy, batchSize int, dryRun bool) (PruneReport, error)
 }
 
 // interface compliance constraint for VideoMetaStore
-var _ interfaces.Store[yt.Video, time.Time] = &VideoMetaStore{}
+var _ ManagedVideoStore = &VideoMetaStore{}
 
 const OrderReverseChrono = "published_at DESC"
 
-// Save records to the video store.
-func (v *VideoMetaStore) Save(records []yt.Video) {
-	v.DB.Clauses(clause.OnConflict{DoNothing: true}).Create(records)
+// FuzzyThresholdDefault is the FuzzyThreshold used when none is configured.
+const FuzzyThresholdDefault = 0.5
+
+// WithFilter returns a copy of the store whose queries only match videos passing the filter.
+func (v *VideoMetaStore) WithFilter(filter Filter) VideoStore {
+	return v.withFilter(filter)
+}
+
+// withFilter is WithFilter, returning the store itself.
+func (v *VideoMetaStore) withFilter(filter Filter) *VideoMetaStore {
+	filtered := *v
+	filtered.filter = filter
+	return &filtered
+}
+
+// Save records to the video store, returning those that were not already stored, with their
+// IDs and the time they were first seen stamped. Stored videos whose title, description or
+// thumbnail changed are updated, their prior version kept as a VideoRevision, and passed to
+// OnEdit. Their statistics are updated too, in place, as they change all the time, and kept
+// as VideoStats, as are those of new videos.
+func (v *VideoMetaStore) Save(records []yt.Video) ([]yt.Video, error) {
+	return v.save(records, true)
+}
+
+// Refresh updates the stored videos among records as Save does, eg: to their current versions
+// on YouTube, but stores none that are not already.
+func (v *VideoMetaStore) Refresh(records []yt.Video) error {
+	_, err := v.save(records, false)
+	return err
+}
+
+// save saves records, inserting those new to the store if insert is set.
+func (v *VideoMetaStore) save(records []yt.Video, insert bool) ([]yt.Video, error) {
+	if len(records) == 0 {
+		return nil, nil
+	}
+
+	ids := make([]string, len(records))
+	for i := range records {
+		ids[i] = records[i].VideoId
+	}
+
+	var saved, edited []yt.Video
+	err := v.DB.Transaction(func(tx *gorm.DB) error {
+		var stored []yt.Video
+		// Deleted videos are known too, and stay deleted
+		if err := tx.Unscoped().Where("video_id IN ?", ids).Find(&stored).Error; err != nil {
+			return err
+		}
+
+		known := make(map[string]*yt.Video, len(stored)+len(records))
+		for i := range stored {
+			known[stored[i].VideoId] = &stored[i]
+		}
+		now := tx.NowFunc()
+		var revisions []VideoRevision
+		var stats []VideoStats
+		for i := range records {
+			record := records[i]
+			prev, ok := known[record.VideoId]
+			if !ok && !insert {
+				continue
+			}
+			if !ok {
+				// Batches may repeat a video
+				known[record.VideoId] = &record
+				record.PublishedAt = record.PublishedAt.UTC()
+				saved = append(saved, record)
+				if hasNewStatistics(yt.Video{}, record) {
+					stats = append(stats, statsOf(record, now))
+				}
+				continue
+			}
+			// New videos repeated in a batch count once, and removed videos stay as they were
+			if prev.ID == 0 || prev.DeletedAt.Valid {
+				continue
+			}
+			if hasNewStatistics(*prev, record) {
+				// Columns are updated as they are, so that updated_at keeps to edits
+				err := tx.Model(prev).UpdateColumns(map[string]interface{}{
+					"view_count":    record.ViewCount,
+					"like_count":    record.LikeCount,
+					"comment_count": record.CommentCount,
+				}).Error
+				if err != nil {
+					return err
+				}
+				stats = append(stats, statsOf(record, now))
+			}
+			if !isEdited(*prev, record) {
+				continue
+			}
+
+			revisions = append(revisions, revisionOf(*prev, now))
+			err := tx.Model(prev).Updates(map[string]interface{}{
+				"title":         record.Title,
+				"description":   record.Description,
+				"thumbnail_url": record.ThumbnailUrl,
+				"updated_at":    now,
+			}).Error
+			if err != nil {
+				return err
+			}
+			edited = append(edited, *prev)
+		}
+
+		if len(revisions) > 0 {
+			if err := tx.Create(revisions).Error; err != nil {
+				return err
+			}
+		}
+		if len(stats) > 0 {
+			if err := tx.Create(stats).Error; err != nil {
+				return err
+			}
+		}
+		if len(saved) == 0 {
+			return nil
+		}
+		return tx.Clauses(clause.OnConflict{DoNothing: true}).Create(saved).Error
+	})
+
+	if err != nil {
+		return nil, wrapErr(err)
+	}
+	if len(edited) > 0 && v.OnEdit != nil {
+		v.OnEdit(edited)
+	}
+	return saved, nil
+}
+
+// importBatchSize is the number of videos inserted per statement by Import, keeping statements
+// within the limits databases set on their number of parameters.
+const importBatchSize = 500
+
+// Import stores videos as they are, such as those of another store, along with the times they
+// were first seen, edited and removed, and returns the number stored. Videos already stored
+// are skipped, so imports can safely be repeated. IDs are assigned anew.
+func (v *VideoMetaStore) Import(videos []yt.Video) (int64, error) {
+	if len(videos) == 0 {
+		return 0, nil
+	}
+
+	records := make([]yt.Video, len(videos))
+	for i, video := range videos {
+		video.ID = 0
+		video.PublishedAt = video.PublishedAt.UTC()
+		video.CreatedAt = video.CreatedAt.UTC()
+		video.UpdatedAt = video.UpdatedAt.UTC()
+		video.DeletedAt.Time = video.DeletedAt.Time.UTC()
+		records[i] = video
+	}
+
+	// Conflicts are left untargeted, as partitioned videos are unique by their
+	// (video_id, published_at) rather than their video_id
+	result := v.DB.
+		Clauses(clause.OnConflict{DoNothing: true}).
+		CreateInBatches(&records, importBatchSize)
+	return result.RowsAffected, wrapErr(result.Error)
 }
 
 // Retrieve a maximum of limit videos published after some time.Time in reverse-chronological
 // order (ie: sorted by latest)
 // The publishedBefore param can be used for pagination -- by using the published_at
 // attribute of the last record in a result, get the next batch.
-func (v *VideoMetaStore) Retrieve(publishedBefore time.Time, limit int) []yt.Video {
-	videos := new([]yt.Video)
-	result := v.DB.
+func (v *VideoMetaStore) Retrieve(publishedBefore time.Time, limit int) ([]yt.Video, error) {
+	videos := []yt.Video{}
+	err := v.query().
 		Order(OrderReverseChrono).
 		Limit(limit).
-		Find(videos, "published_at < ?", publishedBefore)
-
-	if result.Error != nil {
-		fmt.Println("error: ", result.Error)
-	}
-
-	if videos == nil {
-		return []yt.Video{}
-	}
-
-	return *videos
+		Find(&videos, "published_at < ?", publishedBefore.UTC()).Error
+	return videos, wrapErr(err)
 }
 
 // Search videos in the store by title and description. Retrieves a maximum of limit videos
 // published before some time.Time, sorted by latest first (reverse chronological)
 // The publishedBefore param can be used for pagination -- by using the published_at
 // attribute of the last record in a result, get the next batch.
-func (v *VideoMetaStore) Search(query string, publishedBefore time.Time, limit int) []yt.Video {
-	videos := new([]yt.Video)
-	result := v.DB.
+func (v *VideoMetaStore) Search(query string, publishedBefore time.Time, limit int,
+) ([]yt.Video, error) {
+	videos := []yt.Video{}
+	err := v.query().
+		Scopes(v.backend().contains(query)).
 		Order(OrderReverseChrono).
 		Limit(limit).
-		Where(v.DB.Where("LOWER(title) LIKE LOWER(?)",
-			"%"+query+"%").Or("LOWER(description) LIKE LOWER(?)", "%"+query+"%")).
-		Where("published_at <= ?", publishedBefore).
-		Find(videos)
-
-	// @todo could use an error return
-	if result.Error != nil {
-		v.Logger.Error().Err(result.Error).Msg("video query")
-	}
-
-	if videos == nil {
-		return []yt.Video{}
-	}
-
-	return *videos
+		Where("published_at <= ?", publishedBefore.UTC()).
+		Find(&videos).Error
+	return videos, wrapErr(err)
 }
 
 // NaturalSearch searches videos with a special natural-language aware operation, retrieving
 // a maximum of limit videos. This method does not support pagination at the moment.
-func (v *VideoMetaStore) NaturalSearch(query string, limit int) []yt.Video {
-	nlQuery := strings.Join(strings.Split(query, " "), "|")
+// Returns ErrInvalidQuery if the query has no words.
+func (v *VideoMetaStore) NaturalSearch(query string, limit int) ([]yt.Video, error) {
+	q, err := v.naturalSearchQuery(query)
+	if err != nil {
+		return nil, err
+	}
 
-	videos := new([]yt.Video)
-	result := v.DB.
+	videos := []yt.Video{}
+	err = q.
 		Order(OrderReverseChrono).
 		Limit(limit).
-		Where("tsv @@ to_tsquery('english', ?)", nlQuery).
-		Find(videos)
+		Find(&videos).Error
+	return videos, wrapErr(err)
+}
+
+// FuzzySearch is like Search, but typo-tolerant: videos match when the trigram word-similarity
+// of the query to their title or description is at least FuzzyThreshold. On SQLite, which
+// lacks trigram matching, videos match when they contain every word of the query instead.
+func (v *VideoMetaStore) FuzzySearch(query string, publishedBefore time.Time, limit int,
+) ([]yt.Video, error) {
+	b := v.backend()
+	videos := []yt.Video{}
+	err := b.withFuzzyThreshold(v.DB, v.fuzzyThreshold(), func(tx *gorm.DB) error {
+		return tx.
+			Scopes(v.filter.scope, b.fuzzyMatch(query)).
+			Order(OrderReverseChrono).
+			Limit(limit).
+			Where("published_at <= ?", publishedBefore.UTC()).
+			Find(&videos).Error
+	})
+	return videos, wrapErr(err)
+}
+
+// Suggest returns a maximum of limit distinct titles closest to the query, best match first.
+// It is meant for "did you mean" hints when a search comes up empty, and so matches more
+// loosely than FuzzySearch.
+func (v *VideoMetaStore) Suggest(query string, limit int) ([]string, error) {
+	b := v.backend()
+	titles := make([]string, 0, limit)
+	if len(strings.Fields(query)) == 0 {
+		return titles, nil
+	}
+
+	err := b.withFuzzyThreshold(v.DB, v.fuzzyThreshold()/2, func(tx *gorm.DB) error {
+		return tx.
+			Model(&yt.Video{}).
+			Scopes(v.filter.scope, b.suggestions(query)).
+			Group("videos.title").
+			Limit(limit).
+			Pluck("videos.title", &titles).Error
+	})
+	return titles, wrapErr(err)
+}
+
+// query starts a query on the store, with its filter applied.
+func (v *VideoMetaStore) query() *gorm.DB {
+	return v.DB.Scopes(v.filter.scope)
+}
+
+// naturalSearchQuery starts a query for videos matching a natural-language search, failing
+// with ErrInvalidQuery if it has no words.
+func (v *VideoMetaStore) naturalSearchQuery(query string) (*gorm.DB, error) {
+	words := strings.Fields(query)
+	if len(words) == 0 {
+		return nil, fmt.Errorf("%w: no words to search for", ErrInvalidQuery)
+	}
+	return v.query().Scopes(v.backend().naturalMatch(words)), nil
+}
+
+func (v *VideoMetaStore) fuzzyThreshold() float64 {
+	if v.FuzzyThreshold <= 0 || v.FuzzyThreshold > 1 {
+		return FuzzyThresholdDefault
+	}
+	return v.FuzzyThreshold
+}
+
+// Get a video by its YouTube ID, even if it was removed from YouTube.
+// Returns ErrNotFound if there is no such video.
+func (v *VideoMetaStore) Get(videoID string) (yt.Video, error) {
+	q := query.Use(v.DB).Video
+	video, err := q.WithContext(context.Background()).Unscoped().Where(q.VideoId.Eq(videoID)).Take()
+	if err != nil {
+		return yt.Video{}, wrapErr(err)
+	}
+	return *video, nil
+}
+
+// Lookup videos by their YouTube IDs, in no particular order, including those removed from
+// YouTube. IDs of videos not in the store are ignored.
+func (v *VideoMetaStore) Lookup(videoIDs []string) ([]yt.Video, error) {
+	q := query.Use(v.DB).Video
+	found, err := q.WithContext(context.Background()).Unscoped().Where(q.VideoId.In(videoIDs...)).Find()
+	if err != nil {
+		return nil, wrapErr(err)
+	}
+
+	videos := make([]yt.Video, len(found))
+	for i := range found {
+		videos[i] = *found[i]
+	}
+	return videos, nil
+}
+
+// Walk calls fn with each video in the store, in the order they were stored, streaming them
+// from the database with a cursor. Walking stops at the first error returned by fn.
+func (v *VideoMetaStore) Walk(fn func(*yt.Video) error) error {
+	return v.WalkAfter(0, fn)
+}
 
-	if result.Error != nil {
-		v.Logger.Error().Err(result.Error).Msg("natural language query for videos")
+// WalkAfter is like Walk, but starts after the video of ID id, eg: to resume a walk that
+// stopped there.
+func (v *VideoMetaStore) WalkAfter(id uint, fn func(*yt.Video) error) error {
+	rows, err := v.query().Model(&yt.Video{}).Where("id > ?", id).Order("id").Rows()
+	if err != nil {
+		return wrapErr(err)
 	}
+	defer rows.Close()
 
-	if videos == nil {
-		return []yt.Video{}
+	for rows.Next() {
+		var video yt.Video
+		if err := v.DB.ScanRows(rows, &video); err != nil {
+			return wrapErr(err)
+		}
+		if err := fn(&video); err != nil {
+			return err
+		}
 	}
 
-	return *videos
+	return wrapErr(rows.Err())
 }
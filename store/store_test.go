@@ -0,0 +1,38 @@
+package store_test
+
+import (
+	"github.com/ditsuke/youtube-focus/config"
+	"github.com/ditsuke/youtube-focus/store"
+	"github.com/ditsuke/youtube-focus/store/migrations"
+	"github.com/ditsuke/youtube-focus/store/storetest"
+	"gorm.io/gorm"
+	"testing"
+)
+
+// newTestDB returns an empty, migrated, in-memory SQLite database.
+func newTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	cfg := config.Config{DBDriver: config.DriverSQLite, SQLitePath: ":memory:"}
+	db, err := cfg.GetDB()
+	if err != nil {
+		t.Fatalf("open the database: %v", err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatalf("open the database: %v", err)
+	}
+	// The in-memory database is shared by the connections of the process, and dropped with
+	// the last of them, so that every test starts from an empty one
+	t.Cleanup(func() { _ = sqlDB.Close() })
+
+	if _, err := migrations.Up(db); err != nil {
+		t.Fatalf("migrate the database: %v", err)
+	}
+	return db
+}
+
+func TestVideoMetaStoreSQLite(t *testing.T) {
+	storetest.Run(t, func(t *testing.T) storetest.Store {
+		return &store.VideoMetaStore{DB: newTestDB(t)}
+	})
+}
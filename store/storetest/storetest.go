@@ -0,0 +1,759 @@
+// Package storetest implements a conformance suite for video stores, the implementations of
+// store.ManagedVideoStore such as store.VideoMetaStore and store.MemoryStore. Every store
+// should pass it, so that code written against one behaves the same against another:
+//
+//	func TestMemoryStore(t *testing.T) {
+//		storetest.Run(t, func(t *testing.T) storetest.Store {
+//			return store.NewMemoryStore()
+//		})
+//	}
+package storetest
+
+import (
+	"errors"
+	"fmt"
+	"github.com/ditsuke/youtube-focus/internal/yt"
+	"github.com/ditsuke/youtube-focus/store"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+)
+
+// Store is a video store under test.
+type Store = store.ManagedVideoStore
+
+// NewStore returns an empty store for a test.
+type NewStore func(t *testing.T) Store
+
+// Run runs the conformance suite, each test against its own store from newStore.
+func Run(t *testing.T, newStore NewStore) {
+	tests := []struct {
+		name string
+		test func(t *testing.T, s Store)
+	}{
+		{"SaveReturnsNew", testSaveReturnsNew},
+		{"SaveRepeatedInBatch", testSaveRepeatedInBatch},
+		{"SaveNothing", testSaveNothing},
+		{"SaveUpdatesEdits", testSaveUpdatesEdits},
+		{"RetrieveLatestFirst", testRetrieveLatestFirst},
+		{"RetrievePages", testRetrievePages},
+		{"RetrieveNothing", testRetrieveNothing},
+		{"SearchTitleAndDescription", testSearchTitleAndDescription},
+		{"SearchIgnoresCase", testSearchIgnoresCase},
+		{"SearchIncludesMarker", testSearchIncludesMarker},
+		{"SearchNothing", testSearchNothing},
+		{"FilterRetrieve", testFilterRetrieve},
+		{"NaturalSearchAnyWord", testNaturalSearchAnyWord},
+		{"NaturalSearchNoWords", testNaturalSearchNoWords},
+		{"NaturalSearchFacets", testNaturalSearchFacets},
+		{"FuzzySearchExactWords", testFuzzySearchExactWords},
+		{"Suggest", testSuggest},
+		{"SavedSearchResults", testSavedSearchResults},
+		{"GetAndLookup", testGetAndLookup},
+		{"RevisionsOfEdits", testRevisionsOfEdits},
+		{"StatsHistory", testStatsHistory},
+		{"Channels", testChannels},
+		{"Walk", testWalk},
+		{"RefreshStoresNothingNew", testRefreshStoresNothingNew},
+		{"Import", testImport},
+		{"MarkRemoved", testMarkRemoved},
+		{"AvailableIDs", testAvailableIDs},
+		{"Prune", testPrune},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			tt.test(t, newStore(t))
+		})
+	}
+}
+
+// epoch is when the latest of the videos made by videos was published. Times are whole
+// seconds in UTC, which every database keeps as they are.
+var epoch = time.Date(2022, time.September, 1, 12, 0, 0, 0, time.UTC)
+
+// videos returns n videos, video0 to video<n-1>, published a minute apart from epoch, latest
+// first.
+func videos(n int) []yt.Video {
+	videos := make([]yt.Video, n)
+	for i := range videos {
+		videos[i] = yt.Video{
+			VideoId:     fmt.Sprintf("video%d", i),
+			Title:       fmt.Sprintf("Video %d", i),
+			Description: fmt.Sprintf("The video numbered %d", i),
+			PublishedAt: epoch.Add(-time.Duration(i) * time.Minute),
+			ChannelId:   "channel",
+		}
+	}
+	return videos
+}
+
+// save saves records, failing the test if that fails.
+func save(t *testing.T, s Store, records []yt.Video) []yt.Video {
+	t.Helper()
+	saved, err := s.Save(records)
+	if err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	return saved
+}
+
+// videoIDs returns the YouTube IDs of videos, in order.
+func videoIDs(videos []yt.Video) []string {
+	ids := make([]string, len(videos))
+	for i, v := range videos {
+		ids[i] = v.VideoId
+	}
+	return ids
+}
+
+func expectIDs(t *testing.T, what string, got []yt.Video, want ...string) {
+	t.Helper()
+	if want == nil {
+		want = []string{}
+	}
+	if ids := videoIDs(got); !reflect.DeepEqual(ids, want) {
+		t.Errorf("%s = %v, want %v", what, ids, want)
+	}
+}
+
+func testSaveReturnsNew(t *testing.T, s Store) {
+	saved := save(t, s, videos(3))
+	expectIDs(t, "Save", saved, "video0", "video1", "video2")
+
+	seen := map[uint]bool{}
+	for _, v := range saved {
+		if v.ID == 0 || seen[v.ID] {
+			t.Errorf("Save: %s has the ID %d, want a distinct non-zero ID", v.VideoId, v.ID)
+		}
+		seen[v.ID] = true
+		if v.CreatedAt.IsZero() {
+			t.Errorf("Save: %s has no first seen time", v.VideoId)
+		}
+	}
+
+	saved = save(t, s, videos(4)[2:])
+	expectIDs(t, "Save of a known and a new video", saved, "video3")
+}
+
+func testSaveRepeatedInBatch(t *testing.T, s Store) {
+	v := videos(2)
+	saved := save(t, s, []yt.Video{v[0], v[1], v[0]})
+	expectIDs(t, "Save", saved, "video0", "video1")
+
+	stored, err := s.Retrieve(epoch.Add(time.Second), 10)
+	if err != nil {
+		t.Fatalf("Retrieve: %v", err)
+	}
+	expectIDs(t, "Retrieve", stored, "video0", "video1")
+}
+
+func testSaveNothing(t *testing.T, s Store) {
+	if saved := save(t, s, nil); len(saved) > 0 {
+		t.Errorf("Save(nil) = %v, want nothing", videoIDs(saved))
+	}
+}
+
+func testSaveUpdatesEdits(t *testing.T, s Store) {
+	save(t, s, videos(2))
+
+	edited := videos(2)
+	edited[1].Title = "Video 1, retitled"
+	edited[1].ThumbnailUrl = "https://i.ytimg.com/vi/video1/hq720.jpg"
+	if saved := save(t, s, edited); len(saved) > 0 {
+		t.Errorf("Save of edited videos = %v, want nothing new", videoIDs(saved))
+	}
+
+	got, err := s.Search("retitled", epoch, 10)
+	if err != nil {
+		t.Fatalf("Search: %v", err)
+	}
+	expectIDs(t, "Search for the edited title", got, "video1")
+	if len(got) == 1 && got[0].ThumbnailUrl != edited[1].ThumbnailUrl {
+		t.Errorf("thumbnail of the edited video = %q, want %q",
+			got[0].ThumbnailUrl, edited[1].ThumbnailUrl)
+	}
+}
+
+func testRetrieveLatestFirst(t *testing.T, s Store) {
+	v := videos(3)
+	// Saved out of order
+	save(t, s, []yt.Video{v[1], v[2], v[0]})
+
+	got, err := s.Retrieve(epoch.Add(time.Second), 10)
+	if err != nil {
+		t.Fatalf("Retrieve: %v", err)
+	}
+	expectIDs(t, "Retrieve", got, "video0", "video1", "video2")
+}
+
+func testRetrievePages(t *testing.T, s Store) {
+	save(t, s, videos(5))
+
+	// Markers are exclusive
+	marker := epoch
+	var pages [][]string
+	for {
+		page, err := s.Retrieve(marker, 2)
+		if err != nil {
+			t.Fatalf("Retrieve: %v", err)
+		}
+		if len(page) == 0 {
+			break
+		}
+		if len(pages) == 5 {
+			t.Fatalf("Retrieve: paging does not end")
+		}
+		pages = append(pages, videoIDs(page))
+		marker = page[len(page)-1].PublishedAt
+	}
+
+	want := [][]string{{"video1", "video2"}, {"video3", "video4"}}
+	if !reflect.DeepEqual(pages, want) {
+		t.Errorf("pages of Retrieve = %v, want %v", pages, want)
+	}
+}
+
+func testRetrieveNothing(t *testing.T, s Store) {
+	save(t, s, videos(2))
+
+	got, err := s.Retrieve(epoch.Add(-time.Hour), 10)
+	if err != nil {
+		t.Fatalf("Retrieve: %v", err)
+	}
+	if got == nil || len(got) > 0 {
+		t.Errorf("Retrieve before every video = %#v, want an empty slice", got)
+	}
+}
+
+func testSearchTitleAndDescription(t *testing.T, s Store) {
+	v := videos(3)
+	v[0].Title = "A cat video"
+	v[1].Description = "Some cats, and a dog"
+	save(t, s, v)
+
+	got, err := s.Search("cat", epoch, 10)
+	if err != nil {
+		t.Fatalf("Search: %v", err)
+	}
+	expectIDs(t, `Search("cat")`, got, "video0", "video1")
+
+	got, err = s.Search("cat", epoch, 1)
+	if err != nil {
+		t.Fatalf("Search: %v", err)
+	}
+	expectIDs(t, `Search("cat") with a limit of 1`, got, "video0")
+}
+
+func testSearchIgnoresCase(t *testing.T, s Store) {
+	v := videos(2)
+	v[1].Title = "LOUD NEWS"
+	save(t, s, v)
+
+	got, err := s.Search("Loud News", epoch, 10)
+	if err != nil {
+		t.Fatalf("Search: %v", err)
+	}
+	expectIDs(t, `Search("Loud News")`, got, "video1")
+}
+
+func testSearchIncludesMarker(t *testing.T, s Store) {
+	save(t, s, videos(3))
+
+	// Unlike those of Retrieve, markers are inclusive
+	got, err := s.Search("video", epoch.Add(-time.Minute), 10)
+	if err != nil {
+		t.Fatalf("Search: %v", err)
+	}
+	expectIDs(t, "Search", got, "video1", "video2")
+}
+
+func testSearchNothing(t *testing.T, s Store) {
+	save(t, s, videos(2))
+
+	got, err := s.Search("nothing like it", epoch, 10)
+	if err != nil {
+		t.Fatalf("Search: %v", err)
+	}
+	if got == nil || len(got) > 0 {
+		t.Errorf("Search for nothing = %#v, want an empty slice", got)
+	}
+}
+
+func testFilterRetrieve(t *testing.T, s Store) {
+	v := videos(3)
+	v[1].ChannelId = "other"
+	save(t, s, v)
+
+	got, err := s.WithFilter(store.Filter{ChannelId: "other"}).Retrieve(epoch.Add(time.Second), 10)
+	if err != nil {
+		t.Fatalf("Retrieve: %v", err)
+	}
+	expectIDs(t, "Retrieve of a channel", got, "video1")
+}
+
+func testNaturalSearchAnyWord(t *testing.T, s Store) {
+	v := videos(3)
+	v[0].Title = "Cat toys"
+	v[1].Title = "A dog park"
+	save(t, s, v)
+
+	got, err := s.NaturalSearch("cat dog", 10)
+	if err != nil {
+		t.Fatalf("NaturalSearch: %v", err)
+	}
+	expectIDs(t, `NaturalSearch("cat dog")`, got, "video0", "video1")
+
+	got, err = s.NaturalSearch("cat dog", 1)
+	if err != nil {
+		t.Fatalf("NaturalSearch: %v", err)
+	}
+	expectIDs(t, `NaturalSearch("cat dog") with a limit of 1`, got, "video0")
+}
+
+func testNaturalSearchNoWords(t *testing.T, s Store) {
+	save(t, s, videos(1))
+
+	if _, err := s.NaturalSearch("  ", 10); !errors.Is(err, store.ErrInvalidQuery) {
+		t.Errorf("NaturalSearch for no words: %v, want ErrInvalidQuery", err)
+	}
+	_, err := s.NaturalSearchFacets("", []store.Facet{store.FacetChannel}, 10)
+	if !errors.Is(err, store.ErrInvalidQuery) {
+		t.Errorf("NaturalSearchFacets for no words: %v, want ErrInvalidQuery", err)
+	}
+}
+
+func testNaturalSearchFacets(t *testing.T, s Store) {
+	v := videos(4)
+	for i := range v {
+		v[i].ChannelId, v[i].ChannelTitle = "a", "Channel A"
+	}
+	v[2].ChannelId, v[2].ChannelTitle = "b", "Channel B"
+	v[1].DurationSeconds = 60
+	v[2].DurationSeconds = 600
+	// Matched by no search
+	v[3].Title = "Other"
+	save(t, s, v)
+
+	got, err := s.NaturalSearchFacets("video",
+		[]store.Facet{store.FacetChannel, store.FacetDuration, store.FacetWatch}, 2)
+	if err != nil {
+		t.Fatalf("NaturalSearchFacets: %v", err)
+	}
+	want := map[store.Facet][]store.FacetCount{
+		store.FacetChannel: {
+			{Value: "a", Label: "Channel A", Count: 2},
+			{Value: "b", Label: "Channel B", Count: 1},
+		},
+		// Limited to the first 2 of 3 values, ties ordered by value
+		store.FacetDuration: {
+			{Value: "medium", Count: 1},
+			{Value: "short", Count: 1},
+		},
+		store.FacetWatch: {},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("NaturalSearchFacets = %+v, want %+v", got, want)
+	}
+}
+
+func testFuzzySearchExactWords(t *testing.T, s Store) {
+	v := videos(3)
+	v[0].Title = "Cat toys"
+	v[1].Title = "Dog toys"
+	save(t, s, v)
+
+	got, err := s.FuzzySearch("cat toys", epoch, 10)
+	if err != nil {
+		t.Fatalf("FuzzySearch: %v", err)
+	}
+	expectIDs(t, `FuzzySearch("cat toys")`, got, "video0")
+}
+
+func testSuggest(t *testing.T, s Store) {
+	v := videos(4)
+	v[0].Title = "Cat toys"
+	v[1].Title = "Cat food"
+	v[2].Title = "Cat toys"
+	v[3].Title = "Dog park"
+	save(t, s, v)
+
+	got, err := s.Suggest("cat", 10)
+	if err != nil {
+		t.Fatalf("Suggest: %v", err)
+	}
+	// In no particular order
+	sort.Strings(got)
+	if want := []string{"Cat food", "Cat toys"}; !reflect.DeepEqual(got, want) {
+		t.Errorf(`Suggest("cat") = %q, want %q`, got, want)
+	}
+
+	got, err = s.Suggest(" ", 10)
+	if err != nil {
+		t.Fatalf("Suggest: %v", err)
+	}
+	if got == nil || len(got) > 0 {
+		t.Errorf("Suggest for no words = %#v, want an empty slice", got)
+	}
+}
+
+func testSavedSearchResults(t *testing.T, s Store) {
+	v := videos(4)
+	v[0].Title = "Cat toys"
+	v[2].Title = "Cat food"
+	v[3].Title = "Cat bowls"
+	save(t, s, v)
+
+	search := store.SavedSearch{
+		Query:     "cat",
+		CheckedAt: time.Now().Add(time.Hour),
+	}
+	for _, mode := range []store.SearchMode{store.ModeLike, store.ModeNatural} {
+		search.Mode = mode
+
+		// Paged as Retrieve in natural mode, and Search in others
+		marker := epoch.Add(-2 * time.Minute)
+		want := []string{"video2", "video3"}
+		if mode == store.ModeNatural {
+			want = want[1:]
+		}
+		got, err := s.SavedSearchResults(search, marker, 10)
+		if err != nil {
+			t.Fatalf("SavedSearchResults in %s mode: %v", mode, err)
+		}
+		expectIDs(t, fmt.Sprintf("SavedSearchResults in %s mode", mode), got, want...)
+	}
+
+	// Videos first seen before the last check are not new
+	search.PrevCheckedAt = search.CheckedAt
+	got, err := s.SavedSearchResults(search, epoch, 10)
+	if err != nil {
+		t.Fatalf("SavedSearchResults: %v", err)
+	}
+	expectIDs(t, "SavedSearchResults before the last check", got)
+}
+
+func testGetAndLookup(t *testing.T, s Store) {
+	save(t, s, videos(3))
+
+	video, err := s.Get("video1")
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if video.VideoId != "video1" || video.Title != "Video 1" {
+		t.Errorf(`Get("video1") = %s titled %q`, video.VideoId, video.Title)
+	}
+	if _, err := s.Get("missing"); !errors.Is(err, store.ErrNotFound) {
+		t.Errorf(`Get("missing"): %v, want ErrNotFound`, err)
+	}
+
+	got, err := s.Lookup([]string{"video2", "missing", "video0"})
+	if err != nil {
+		t.Fatalf("Lookup: %v", err)
+	}
+	// In no particular order
+	ids := videoIDs(got)
+	sort.Strings(ids)
+	if want := []string{"video0", "video2"}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("Lookup = %v, want %v", ids, want)
+	}
+}
+
+func testRevisionsOfEdits(t *testing.T, s Store) {
+	save(t, s, videos(2))
+	edited := videos(2)
+	edited[1].Title = "Video 1, retitled"
+	save(t, s, edited)
+	edited[1].Title = "Video 1, retitled again"
+	save(t, s, edited)
+
+	revisions, err := s.Revisions("video1")
+	if err != nil {
+		t.Fatalf("Revisions: %v", err)
+	}
+	var titles []string
+	for _, r := range revisions {
+		titles = append(titles, r.Title)
+	}
+	if want := []string{"Video 1", "Video 1, retitled"}; !reflect.DeepEqual(titles, want) {
+		t.Errorf("titles of the revisions = %q, want %q", titles, want)
+	}
+
+	revisions, err = s.Revisions("video0")
+	if err != nil {
+		t.Fatalf("Revisions: %v", err)
+	}
+	if revisions == nil || len(revisions) > 0 {
+		t.Errorf("Revisions of an unedited video = %#v, want an empty slice", revisions)
+	}
+}
+
+func testStatsHistory(t *testing.T, s Store) {
+	v := videos(2)
+	v[0].ViewCount = 10
+	save(t, s, v)
+	v[0].ViewCount = 20
+	save(t, s, v)
+	// Unchanged
+	save(t, s, v)
+
+	history, err := s.StatsHistory([]string{"video0", "video1"})
+	if err != nil {
+		t.Fatalf("StatsHistory: %v", err)
+	}
+	if _, ok := history["video1"]; ok {
+		t.Errorf("StatsHistory has %v for a video without statistics", history["video1"])
+	}
+	var views []int64
+	for _, s := range history["video0"] {
+		views = append(views, s.ViewCount)
+	}
+	if want := []int64{10, 20}; !reflect.DeepEqual(views, want) {
+		t.Errorf("views in the history = %v, want %v", views, want)
+	}
+}
+
+func testChannels(t *testing.T, s Store) {
+	v := videos(3)
+	v[0].ChannelId, v[0].ChannelTitle = "a", "Channel A, renamed"
+	v[1].ChannelId, v[1].ChannelTitle = "b", "Channel B"
+	v[2].ChannelId, v[2].ChannelTitle = "a", "Channel A"
+	save(t, s, v)
+
+	channels, err := s.Channels([]string{"a", "missing"})
+	if err != nil {
+		t.Fatalf("Channels: %v", err)
+	}
+	// Titled as in their latest videos
+	if want := []store.Channel{{ID: "a", Title: "Channel A, renamed"}}; !reflect.DeepEqual(
+		channels, want) {
+		t.Errorf("Channels = %+v, want %+v", channels, want)
+	}
+
+	byChannel, err := s.ChannelVideos([]string{"a", "b", "missing"}, epoch, 1)
+	if err != nil {
+		t.Fatalf("ChannelVideos: %v", err)
+	}
+	got := map[string][]string{}
+	for id, videos := range byChannel {
+		got[id] = videoIDs(videos)
+	}
+	// Markers are exclusive
+	if want := map[string][]string{"a": {"video2"}, "b": {"video1"}}; !reflect.DeepEqual(
+		got, want) {
+		t.Errorf("ChannelVideos = %v, want %v", got, want)
+	}
+}
+
+func testWalk(t *testing.T, s Store) {
+	v := videos(3)
+	// Saved out of order
+	saved := save(t, s, []yt.Video{v[1], v[2], v[0]})
+
+	var walked []yt.Video
+	err := s.Walk(func(v *yt.Video) error {
+		walked = append(walked, *v)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Walk: %v", err)
+	}
+	expectIDs(t, "Walk", walked, "video1", "video2", "video0")
+
+	walked = nil
+	err = s.WalkAfter(saved[0].ID, func(v *yt.Video) error {
+		walked = append(walked, *v)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("WalkAfter: %v", err)
+	}
+	expectIDs(t, "WalkAfter the first", walked, "video2", "video0")
+
+	stop := errors.New("stop")
+	walked = nil
+	err = s.Walk(func(v *yt.Video) error {
+		walked = append(walked, *v)
+		return stop
+	})
+	if !errors.Is(err, stop) {
+		t.Errorf("Walk: %v, want the error of fn", err)
+	}
+	expectIDs(t, "Walk stopped at the first", walked, "video1")
+}
+
+func testRefreshStoresNothingNew(t *testing.T, s Store) {
+	save(t, s, videos(1))
+	v := videos(2)
+	v[0].Title = "Video 0, retitled"
+	if err := s.Refresh(v); err != nil {
+		t.Fatalf("Refresh: %v", err)
+	}
+
+	got, err := s.Lookup([]string{"video0", "video1"})
+	if err != nil {
+		t.Fatalf("Lookup: %v", err)
+	}
+	expectIDs(t, "Lookup after Refresh", got, "video0")
+	if len(got) == 1 && got[0].Title != v[0].Title {
+		t.Errorf("title of the refreshed video = %q, want %q", got[0].Title, v[0].Title)
+	}
+}
+
+func testImport(t *testing.T, s Store) {
+	v := videos(2)
+	for i := range v {
+		v[i].ID = 100 + uint(i)
+		v[i].CreatedAt = epoch.Add(time.Hour)
+		v[i].UpdatedAt = epoch.Add(2 * time.Hour)
+	}
+	v[1].RemovalStatus = yt.RemovalPrivate
+	v[1].DeletedAt.Time, v[1].DeletedAt.Valid = epoch.Add(3*time.Hour), true
+
+	n, err := s.Import(v)
+	if err != nil || n != 2 {
+		t.Fatalf("Import = %d, %v, want 2", n, err)
+	}
+	// Repeated imports skip the videos already stored
+	if n, err := s.Import(v); err != nil || n != 0 {
+		t.Errorf("repeated Import = %d, %v, want 0", n, err)
+	}
+
+	imported, err := s.Get("video0")
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if !imported.CreatedAt.Equal(v[0].CreatedAt) || !imported.UpdatedAt.Equal(v[0].UpdatedAt) {
+		t.Errorf("imported video first seen at %s, updated at %s, want %s and %s",
+			imported.CreatedAt, imported.UpdatedAt, v[0].CreatedAt, v[0].UpdatedAt)
+	}
+	removed, err := s.Get("video1")
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if !removed.DeletedAt.Valid || !removed.DeletedAt.Time.Equal(v[1].DeletedAt.Time) ||
+		removed.RemovalStatus != yt.RemovalPrivate {
+		t.Errorf("imported removed video = %+v, want it removed as it was", removed)
+	}
+
+	got, err := s.Retrieve(epoch.Add(time.Second), 10)
+	if err != nil {
+		t.Fatalf("Retrieve: %v", err)
+	}
+	expectIDs(t, "Retrieve of imported videos", got, "video0")
+}
+
+func testMarkRemoved(t *testing.T, s Store) {
+	save(t, s, videos(2))
+
+	marked, err := s.MarkRemoved(map[string]string{
+		"video0": yt.RemovalDeleted, "missing": yt.RemovalDeleted,
+	})
+	if err != nil || marked != 1 {
+		t.Fatalf("MarkRemoved = %d, %v, want 1", marked, err)
+	}
+	// Removed videos are left as they are
+	marked, err = s.MarkRemoved(map[string]string{"video0": yt.RemovalPrivate})
+	if err != nil || marked != 0 {
+		t.Errorf("MarkRemoved of a removed video = %d, %v, want 0", marked, err)
+	}
+
+	removed, err := s.Get("video0")
+	if err != nil {
+		t.Fatalf("Get of a removed video: %v", err)
+	}
+	if !removed.DeletedAt.Valid || removed.RemovalStatus != yt.RemovalDeleted {
+		t.Errorf("removed video = %+v, want it deleted", removed)
+	}
+
+	got, err := s.Retrieve(epoch.Add(time.Second), 10)
+	if err != nil {
+		t.Fatalf("Retrieve: %v", err)
+	}
+	expectIDs(t, "Retrieve", got, "video1")
+	got, err = s.WithFilter(store.Filter{Removed: store.RemovedOnly}).
+		Retrieve(epoch.Add(time.Second), 10)
+	if err != nil {
+		t.Fatalf("Retrieve: %v", err)
+	}
+	expectIDs(t, "Retrieve of removed videos", got, "video0")
+}
+
+func testAvailableIDs(t *testing.T, s Store) {
+	save(t, s, videos(3))
+	if _, err := s.MarkRemoved(map[string]string{"video1": yt.RemovalDeleted}); err != nil {
+		t.Fatalf("MarkRemoved: %v", err)
+	}
+
+	ids, last, err := s.AvailableIDs(0, 1)
+	if err != nil || !reflect.DeepEqual(ids, []string{"video0"}) {
+		t.Fatalf("AvailableIDs of the first page = %v, %v, want [video0]", ids, err)
+	}
+	ids, last, err = s.AvailableIDs(last, 10)
+	if err != nil || !reflect.DeepEqual(ids, []string{"video2"}) {
+		t.Fatalf("AvailableIDs of the second page = %v, %v, want [video2]", ids, err)
+	}
+	ids, last, err = s.AvailableIDs(last, 10)
+	if err != nil || len(ids) != 0 || last != 0 {
+		t.Errorf("AvailableIDs past the last = %v, %d, %v, want none, and to start over",
+			ids, last, err)
+	}
+}
+
+func testPrune(t *testing.T, s Store) {
+	day := 24 * time.Hour
+	now := time.Now().UTC().Truncate(time.Second)
+	v := videos(3)
+	v[0].PublishedAt = now.Add(-10 * day)
+	v[1].PublishedAt = now.Add(-40 * day)
+	v[2].PublishedAt = now.Add(-50 * day)
+	for i := range v {
+		v[i].ViewCount = 10
+	}
+	save(t, s, v)
+	// Removed videos are pruned all the same
+	if _, err := s.MarkRemoved(map[string]string{"video2": yt.RemovalDeleted}); err != nil {
+		t.Fatalf("MarkRemoved: %v", err)
+	}
+	remaining := func() []string {
+		t.Helper()
+		got, err := s.Lookup([]string{"video0", "video1", "video2"})
+		if err != nil {
+			t.Fatalf("Lookup: %v", err)
+		}
+		ids := videoIDs(got)
+		sort.Strings(ids)
+		return ids
+	}
+
+	report, err := s.Prune(store.RetentionPolicy{}, 1, false)
+	if err != nil || report.Videos != 0 {
+		t.Errorf("Prune keeping videos forever = %+v, %v, want nothing pruned", report, err)
+	}
+
+	report, err = s.Prune(store.RetentionPolicy{Days: 30}, 1, true)
+	if err != nil {
+		t.Fatalf("Prune: %v", err)
+	}
+	if !report.DryRun || report.Videos != 2 || !report.Oldest.Equal(v[2].PublishedAt) ||
+		!report.Newest.Equal(v[1].PublishedAt) {
+		t.Errorf("dry run = %+v, want 2 videos, published from %s to %s", report,
+			v[2].PublishedAt, v[1].PublishedAt)
+	}
+	if want := []string{"video0", "video1", "video2"}; !reflect.DeepEqual(remaining(), want) {
+		t.Errorf("videos after a dry run = %v, want %v", remaining(), want)
+	}
+
+	report, err = s.Prune(store.RetentionPolicy{Days: 30}, 1, false)
+	if err != nil || report.DryRun || report.Videos != 2 {
+		t.Errorf("Prune = %+v, %v, want 2 videos pruned", report, err)
+	}
+	if want := []string{"video0"}; !reflect.DeepEqual(remaining(), want) {
+		t.Errorf("videos after Prune = %v, want %v", remaining(), want)
+	}
+	history, err := s.StatsHistory([]string{"video1"})
+	if err != nil || len(history) != 0 {
+		t.Errorf("StatsHistory of a deleted video = %v, %v, want none", history, err)
+	}
+}